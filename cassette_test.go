@@ -0,0 +1,185 @@
+package agentgo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/cassette"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cassetteClient 创建经由磁带收发请求的客户端
+func cassetteClient(c *cassette.Cassette, baseURL string) openai.Client {
+	return openai.NewClient(
+		option.WithAPIKey("test-key"),
+		option.WithBaseURL(baseURL),
+		option.WithMaxRetries(0),
+		option.WithHTTPClient(c.HTTPClient()),
+	)
+}
+
+// offlineURL 是回放时使用的地址，任何真实请求都会失败
+const offlineURL = "http://127.0.0.1:1"
+
+func TestCassette_RecordAndReplayRun(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassettes", "calc.json")
+	newAgent := func(client openai.Client) *agent.Agent {
+		return agent.New("calc").
+			WithModel("test-model").
+			WithClient(client).
+			WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	}
+
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_1", name: "calculator", arguments: `{"operation":"add","a":1,"b":1}`},
+		}},
+		scriptedReply{text: "1 + 1 = 2"},
+	)
+	rec, err := cassette.Open(path, cassette.ModeRecord)
+	require.NoError(t, err)
+	recorded, err := runner.Run(ctx, newAgent(cassetteClient(rec, server.URL)), "1 + 1?")
+	require.NoError(t, err)
+	require.NoError(t, rec.Save())
+	assert.Equal(t, 2, server.requestCount())
+
+	// 回放时不访问网络，结果与录制时一致
+	replay, err := cassette.Open(path, cassette.ModeReplay)
+	require.NoError(t, err)
+	replayed, err := runner.Run(ctx, newAgent(cassetteClient(replay, offlineURL)), "1 + 1?")
+	require.NoError(t, err)
+	assert.Equal(t, recorded.FinalOutput, replayed.FinalOutput)
+	assert.Equal(t, recorded.Usage, replayed.Usage)
+	assert.Empty(t, replay.Unmatched())
+	assert.Equal(t, 2, server.requestCount())
+
+	// 请求不同则明确失败
+	replay, err = cassette.Open(path, cassette.ModeReplay)
+	require.NoError(t, err)
+	_, err = runner.Run(ctx, newAgent(cassetteClient(replay, offlineURL)), "2 + 2?")
+	assert.True(t, errors.Is(err, cassette.ErrNoMatch))
+	require.Len(t, replay.Unmatched(), 1)
+	assert.Contains(t, replay.Unmatched()[0], "2 + 2?")
+}
+
+func TestCassette_ReplayStreamedRun(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "stream.json")
+	server := newFakeLLMServer(t, scriptedReply{text: "streamed answer"})
+
+	run := func(client openai.Client) string {
+		a := agent.New("assistant").WithModel("test-model").WithClient(client)
+		stream := runner.RunStreamed(ctx, a, "hi")
+		var text strings.Builder
+		for ev := range stream.Events() {
+			if d, ok := ev.(runner.TextDeltaEvent); ok {
+				text.WriteString(d.Delta)
+			}
+		}
+		_, err := stream.Wait()
+		require.NoError(t, err)
+		return text.String()
+	}
+
+	rec, err := cassette.Open(path, cassette.ModeRecord)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed answer", run(cassetteClient(rec, server.URL)))
+	require.NoError(t, rec.Save())
+
+	replay, err := cassette.Open(path, cassette.ModeReplay)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed answer", run(cassetteClient(replay, offlineURL)))
+}
+
+func TestCassette_NormalizesRequestBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "http.json")
+	server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+
+	rec, err := cassette.Open(path, cassette.ModeRecord)
+	require.NoError(t, err)
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/chat/completions", strings.NewReader(`{"model":"m","stream":false}`))
+	resp, err := rec.HTTPClient().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.NoError(t, rec.Save())
+
+	// 键顺序与空白不同的请求体视为同一请求，每条记录只回放一次
+	replay, err := cassette.Open(path, cassette.ModeReplay)
+	require.NoError(t, err)
+	req, _ = http.NewRequest(http.MethodPost, offlineURL+"/chat/completions", strings.NewReader("{\n  \"stream\": false,\n  \"model\": \"m\"\n}"))
+	resp, err = replay.HTTPClient().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, _ = http.NewRequest(http.MethodPost, offlineURL+"/chat/completions", strings.NewReader(`{"model":"m","stream":false}`))
+	_, err = replay.HTTPClient().Do(req)
+	assert.True(t, errors.Is(err, cassette.ErrNoMatch))
+}
+
+func TestCassette_MCPServer(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "mcp.json")
+	live := &MockMCPServer{
+		name:  "files",
+		tools: []*mcp.Tool{{Name: "read_file", Description: "Read a file."}},
+		callToolFunc: func(_ context.Context, name string, args map[string]any) (*mcp.CallToolResult, error) {
+			if args["path"] == "missing.txt" {
+				return nil, errors.New("file not found")
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "hello from " + args["path"].(string)}}}, nil
+		},
+	}
+
+	rec, err := cassette.Open(path, cassette.ModeRecord)
+	require.NoError(t, err)
+	server := rec.WrapMCPServer(live)
+	tools, err := server.ListTools(ctx, agent.New("reader"))
+	require.NoError(t, err)
+	result, err := server.CallTool(ctx, "read_file", map[string]any{"path": "a.txt"})
+	require.NoError(t, err)
+	_, err = server.CallTool(ctx, "read_file", map[string]any{"path": "missing.txt"})
+	require.Error(t, err)
+	require.NoError(t, rec.Save())
+
+	// 回放时不调用真实服务
+	offline := &MockMCPServer{
+		name: "files",
+		callToolFunc: func(context.Context, string, map[string]any) (*mcp.CallToolResult, error) {
+			t.Fatal("replay must not call the server")
+			return nil, nil
+		},
+	}
+	replay, err := cassette.Open(path, cassette.ModeReplay)
+	require.NoError(t, err)
+	server = replay.WrapMCPServer(offline)
+	require.NoError(t, server.Connect(ctx))
+
+	replayedTools, err := server.ListTools(ctx, agent.New("reader"))
+	require.NoError(t, err)
+	require.Len(t, replayedTools, 1)
+	assert.Equal(t, tools[0].Name, replayedTools[0].Name)
+
+	replayedResult, err := server.CallTool(ctx, "read_file", map[string]any{"path": "a.txt"})
+	require.NoError(t, err)
+	assert.Equal(t, result.Content[0].(*mcp.TextContent).Text, replayedResult.Content[0].(*mcp.TextContent).Text)
+
+	_, err = server.CallTool(ctx, "read_file", map[string]any{"path": "missing.txt"})
+	assert.EqualError(t, err, "file not found")
+
+	_, err = server.CallTool(ctx, "read_file", map[string]any{"path": "b.txt"})
+	assert.True(t, errors.Is(err, cassette.ErrNoMatch))
+}
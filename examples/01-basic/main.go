@@ -50,10 +50,10 @@ func main() {
 	fmt.Println("=== Agent 回复 ===")
 	fmt.Println(result.FinalOutput)
 
-	// 6. 可选：查看 token 使用情况
-	if len(result.RawResponses) > 0 && result.RawResponses[0].Usage != nil {
-		usage := result.RawResponses[0].Usage
+	// 6. 可选：查看整个运行的 token 使用情况
+	if usage := result.Usage; usage.Requests > 0 {
 		fmt.Printf("\n=== Token 使用 ===\n")
+		fmt.Printf("请求次数: %d\n", usage.Requests)
 		fmt.Printf("输入 tokens: %d\n", usage.InputTokens)
 		fmt.Printf("输出 tokens: %d\n", usage.OutputTokens)
 		fmt.Printf("总计 tokens: %d\n", usage.TotalTokens)
@@ -272,6 +272,15 @@ func main() {
 		log.Fatalf("执行失败: %v", err)
 	}
 
+	// 写文件和执行命令需要用户确认，确认后继续运行
+	for len(result.Interruptions) > 0 {
+		approvals := askApprovals(reader, result.Interruptions)
+		result, err = runner.Resume(ctx, result.State, approvals)
+		if err != nil {
+			log.Fatalf("执行失败: %v", err)
+		}
+	}
+
 	// 输出结果
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	fmt.Println("最终输出:")
@@ -285,6 +294,27 @@ func main() {
 	fmt.Printf("  - 总响应数: %d\n", len(result.RawResponses))
 }
 
+// askApprovals 逐个询问用户是否允许等待审批的工具调用
+func askApprovals(reader *bufio.Reader, items []agentgo.ToolApprovalItem) []agentgo.ToolApproval {
+	approvals := make([]agentgo.ToolApproval, 0, len(items))
+	for _, item := range items {
+		fmt.Printf("\n[%s] 请求调用 %s，参数: %s\n", item.AgentName, item.ToolName, item.Arguments)
+		fmt.Print("是否允许？(y/N，拒绝时可输入原因): ")
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes") {
+			approvals = append(approvals, agentgo.Approve(item.CallID))
+			continue
+		}
+		reason := answer
+		if strings.EqualFold(reason, "n") || strings.EqualFold(reason, "no") {
+			reason = ""
+		}
+		approvals = append(approvals, agentgo.Reject(item.CallID, reason))
+	}
+	return approvals
+}
+
 // countToolCalls 统计工具调用次数
 // 每个工具调用通常产生2个 item（调用+结果），所以除以2
 func countToolCalls(result *agentgo.RunResult) int {
@@ -52,8 +52,9 @@ func (et *ExecTools) isCommandAllowed(command string) bool {
 // CreateExecCommandTool 创建执行命令工具
 func (et *ExecTools) CreateExecCommandTool() tool.FunctionTool {
 	return tool.FunctionTool{
-		Name:        "exec_command",
-		Description: fmt.Sprintf("在项目目录中执行系统命令。允许的命令: %s", strings.Join(et.AllowedCommands, ", ")),
+		Name:          "exec_command",
+		Description:   fmt.Sprintf("在项目目录中执行系统命令。允许的命令: %s", strings.Join(et.AllowedCommands, ", ")),
+		NeedsApproval: true, // 执行前需要用户确认
 		ParamsJSONSchema: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
@@ -94,8 +94,9 @@ func (ft *FileTools) CreateReadFileTool() tool.FunctionTool {
 // CreateWriteFileTool 创建写入文件工具
 func (ft *FileTools) CreateWriteFileTool() tool.FunctionTool {
 	return tool.FunctionTool{
-		Name:        "write_file",
-		Description: "写入内容到指定文件。如果文件不存在则创建，如果目录不存在则自动创建目录。",
+		Name:          "write_file",
+		Description:   "写入内容到指定文件。如果文件不存在则创建，如果目录不存在则自动创建目录。",
+		NeedsApproval: true, // 执行前需要用户确认
 		ParamsJSONSchema: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
@@ -0,0 +1,142 @@
+package agentgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeModel_ToolLoop(t *testing.T) {
+	m := fakemodel.New(
+		fakemodel.Output(
+			fakemodel.Message("Let me add them."),
+			fakemodel.ToolCall("call_1", "calculator", `{"operation":"add","a":1,"b":1}`),
+		).WithUsage(model.Usage{Requests: 1, InputTokens: 10, OutputTokens: 5, TotalTokens: 15}),
+		fakemodel.Text("1 + 1 = 2").Expecting(func(req model.Request) error {
+			if out, ok := fakemodel.ToolOutput(req, "call_1"); !ok || out != "2" {
+				return errors.New("calculator output missing")
+			}
+			return nil
+		}),
+	)
+	a := agent.New("calc").
+		WithInstructions("You add numbers.").
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: m}}
+
+	result, err := r.Run(context.Background(), a, "1 + 1?")
+	require.NoError(t, err)
+	assert.Equal(t, "1 + 1 = 2", result.FinalOutput)
+	assert.Zero(t, m.Remaining())
+	assert.Equal(t, uint64(2), result.Usage.Requests)
+	assert.Equal(t, uint64(15), result.Usage.TotalTokens)
+
+	requests := m.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "You add numbers.", requests[0].Instructions)
+	assert.Equal(t, []string{"calculator"}, fakemodel.ToolNames(requests[0]))
+	assert.Len(t, requests[1].Input, 4)
+}
+
+func TestFakeModel_Handoff(t *testing.T) {
+	triageModel := fakemodel.New(fakemodel.Output(fakemodel.Handoff("call_transfer", "billing")))
+	billingModel := fakemodel.New(fakemodel.Text("Your invoice is on its way."))
+
+	billing := agent.New("billing").WithModel("billing-model")
+	triage := agent.New("triage").
+		WithModel("triage-model").
+		AddHandoff(agent.HandoffTo(billing))
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: fakemodel.Provider{
+		"triage-model":  triageModel,
+		"billing-model": billingModel,
+	}}}
+
+	result, err := r.Run(context.Background(), triage, "I need my invoice")
+	require.NoError(t, err)
+	assert.Equal(t, billing, result.LastAgent)
+	assert.Equal(t, "Your invoice is on its way.", result.FinalOutput)
+
+	assert.Equal(t, []string{"transfer_to_billing"}, fakemodel.ToolNames(triageModel.Requests()[0]))
+	out, ok := fakemodel.ToolOutput(billingModel.Requests()[0], "call_transfer")
+	require.True(t, ok)
+	assert.Contains(t, out, "billing")
+}
+
+func TestFakeModel_Guardrails(t *testing.T) {
+	blockSecrets := agent.NewInputGuardrail("no_secrets", func(_ context.Context, _ types.AgentLike, input types.Input) (agent.GuardrailFunctionOutput, error) {
+		return agent.GuardrailFunctionOutput{TripwireTriggered: input == types.InputString("password?")}, nil
+	})
+	blockShouting := agent.NewOutputGuardrail("no_shouting", func(_ context.Context, _ types.AgentLike, output any) (agent.GuardrailFunctionOutput, error) {
+		return agent.GuardrailFunctionOutput{TripwireTriggered: output == "HELLO"}, nil
+	})
+
+	// 输入护栏触发时模型不会被调用
+	m := fakemodel.New()
+	a := agent.New("assistant").WithInputGuardrails([]agent.InputGuardrail{blockSecrets})
+	_, err := runner.Runner{Config: runner.RunConfig{ModelProvider: m}}.Run(context.Background(), a, "password?")
+	var tripped *runner.GuardrailTripwireTriggeredError
+	require.True(t, errors.As(err, &tripped))
+	assert.True(t, tripped.IsInput)
+	assert.Empty(t, m.Requests())
+
+	m = fakemodel.New(fakemodel.Text("HELLO"))
+	a = agent.New("assistant").WithOutputGuardrails([]agent.OutputGuardrail{blockShouting})
+	_, err = runner.Runner{Config: runner.RunConfig{ModelProvider: m}}.Run(context.Background(), a, "hi")
+	require.True(t, errors.As(err, &tripped))
+	assert.False(t, tripped.IsInput)
+}
+
+func TestFakeModel_Errors(t *testing.T) {
+	boom := errors.New("boom")
+	m := fakemodel.New(fakemodel.Error(boom))
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: m}}
+
+	_, err := r.Run(context.Background(), agent.New("assistant"), "hi")
+	assert.True(t, errors.Is(err, boom))
+
+	// 脚本用完后的调用返回 ErrScriptExhausted
+	_, err = r.Run(context.Background(), agent.New("assistant"), "hi")
+	assert.True(t, errors.Is(err, fakemodel.ErrScriptExhausted))
+
+	m.Add(fakemodel.Text("unreachable").Expecting(func(req model.Request) error {
+		return errors.New("wrong instructions")
+	}))
+	_, err = r.Run(context.Background(), agent.New("assistant"), "hi")
+	assert.ErrorContains(t, err, "wrong instructions")
+}
+
+func TestFakeModel_Streamed(t *testing.T) {
+	m := fakemodel.New(
+		fakemodel.Output(fakemodel.ToolCall("call_1", "calculator", `{"operation":"add","a":2,"b":3}`)),
+		fakemodel.Text("5"),
+	)
+	a := agent.New("calc").WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: m}}
+
+	stream := r.RunStreamed(context.Background(), a, "2 + 3?")
+	var started []string
+	var text string
+	for ev := range stream.Events() {
+		switch e := ev.(type) {
+		case runner.ToolCallStartedEvent:
+			started = append(started, e.Name)
+		case runner.TextDeltaEvent:
+			text += e.Delta
+		}
+	}
+	result, err := stream.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, "5", result.FinalOutput)
+	assert.Equal(t, []string{"calculator"}, started)
+	assert.Equal(t, "5", text)
+}
@@ -0,0 +1,323 @@
+// Package reflectschema derives JSON schemas from Go types.
+//
+// Property names follow `json` struct tags. A field is required unless it is a
+// pointer or tagged omitempty/omitzero; `jsonschema:"required"` forces it.
+// Further keywords come from the `jsonschema` tag, e.g.
+//
+//	Unit string `json:"unit" jsonschema:"description=Temperature unit,enum=celsius|fahrenheit"`
+//
+// Supported keywords: description, enum (values separated by |), format,
+// pattern, minimum, maximum, minLength, maxLength, required.
+package reflectschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tagKeys are the keywords recognized in `jsonschema` tags.
+var tagKeys = []string{"description", "enum", "format", "pattern", "minimum", "maximum", "minLength", "maxLength", "required"}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// For returns the JSON schema of T. See Generate.
+func For[T any](strict bool) (map[string]any, error) {
+	return Generate(reflect.TypeFor[T](), strict)
+}
+
+// Generate returns the JSON schema of t.
+//
+// In strict mode every property is listed as required and optional fields
+// accept null instead, as OpenAI strict schemas demand. Maps are rejected in
+// strict mode, since strict objects cannot have additional properties.
+func Generate(t reflect.Type, strict bool) (map[string]any, error) {
+	g := generator{strict: strict, visiting: map[reflect.Type]bool{}}
+	return g.schema(t, nil)
+}
+
+type generator struct {
+	strict   bool
+	visiting map[reflect.Type]bool
+}
+
+func (g generator) schema(t reflect.Type, path []string) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case rawMessageType:
+		return map[string]any{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes []byte as base64.
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := g.schema(t.Elem(), append(slices.Clone(path), "items"))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: map key must be a string, got %s", pathString(path), t.Key())
+		}
+		if g.strict {
+			// Strict schemas require additionalProperties to be false.
+			return nil, fmt.Errorf("%s: map %s is not supported in strict mode, use a struct or a slice instead", pathString(path), t)
+		}
+		values, err := g.schema(t.Elem(), append(slices.Clone(path), "additionalProperties"))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return g.object(t, path)
+	default:
+		return nil, fmt.Errorf("%s: unsupported type %s", pathString(path), t)
+	}
+}
+
+func (g generator) object(t reflect.Type, path []string) (map[string]any, error) {
+	if g.visiting[t] {
+		return nil, fmt.Errorf("%s: recursive type %s is not supported", pathString(path), t)
+	}
+	if reflect.PointerTo(t).Implements(jsonMarshalerType) {
+		return nil, fmt.Errorf("%s: type %s has a custom JSON encoding", pathString(path), t)
+	}
+	g.visiting[t] = true
+	defer delete(g.visiting, t)
+
+	properties := map[string]any{}
+	required := []string{}
+	if err := g.fields(t, path, properties, &required); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+// fields adds the properties of t's fields, flattening embedded structs like encoding/json.
+func (g generator) fields(t reflect.Type, path []string, properties map[string]any, required *[]string) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		jsonTag := f.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(jsonTag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := g.fields(ft, path, properties, required); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		fieldPath := append(slices.Clone(path), name)
+		prop, err := g.schema(f.Type, fieldPath)
+		if err != nil {
+			return err
+		}
+		tags, err := parseTag(f.Tag.Get("jsonschema"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", pathString(fieldPath), err)
+		}
+		isRequired, err := applyTags(prop, f.Type, tags)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pathString(fieldPath), err)
+		}
+
+		optional := f.Type.Kind() == reflect.Pointer || hasOption(opts, "omitempty") || hasOption(opts, "omitzero")
+		if isRequired {
+			optional = false
+		}
+		switch {
+		case !optional:
+			*required = append(*required, name)
+		case g.strict:
+			prop = nullable(prop)
+			*required = append(*required, name)
+		}
+		properties[name] = prop
+	}
+	return nil
+}
+
+// applyTags adds the keywords of a `jsonschema` tag to prop and reports
+// whether the tag marks the field as required.
+func applyTags(prop map[string]any, t reflect.Type, tags map[string]string) (bool, error) {
+	for key, value := range tags {
+		switch key {
+		case "description", "format", "pattern":
+			prop[key] = value
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s %q: %w", key, value, err)
+			}
+			prop[key] = n
+		case "minLength", "maxLength":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s %q: %w", key, value, err)
+			}
+			prop[key] = n
+		case "enum":
+			values, err := enumValues(t, strings.Split(value, "|"))
+			if err != nil {
+				return false, err
+			}
+			prop["enum"] = values
+		}
+	}
+	_, required := tags["required"]
+	return required, nil
+}
+
+// enumValues converts enum tag values to the field's JSON type.
+func enumValues(t reflect.Type, raw []string) ([]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	values := make([]any, 0, len(raw))
+	for _, v := range raw {
+		switch t.Kind() {
+		case reflect.String:
+			values = append(values, v)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid enum value %q: %w", v, err)
+			}
+			values = append(values, n)
+		case reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid enum value %q: %w", v, err)
+			}
+			values = append(values, n)
+		default:
+			return nil, fmt.Errorf("enum is not supported for %s", t)
+		}
+	}
+	return values, nil
+}
+
+// parseTag splits a `jsonschema` tag into keywords. Commas only separate
+// keywords when followed by a known key, so descriptions may contain commas.
+func parseTag(tag string) (map[string]string, error) {
+	tags := map[string]string{}
+	if tag == "" {
+		return tags, nil
+	}
+
+	var parts []string
+	start := 0
+	for i := 0; i < len(tag); i++ {
+		if tag[i] == ',' && startsWithKey(tag[i+1:]) {
+			parts = append(parts, tag[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, tag[start:])
+
+	for _, part := range parts {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if !isKey(key) {
+			return nil, fmt.Errorf("unknown jsonschema tag key %q", key)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+func startsWithKey(s string) bool {
+	s = strings.TrimSpace(s)
+	for _, k := range tagKeys {
+		if s == k || strings.HasPrefix(s, k+"=") || strings.HasPrefix(s, k+",") {
+			return true
+		}
+	}
+	return false
+}
+
+func isKey(key string) bool {
+	for _, k := range tagKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOption(opts, option string) bool {
+	for opt := range strings.SplitSeq(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable allows null in addition to the values prop accepts.
+func nullable(prop map[string]any) map[string]any {
+	description, ok := prop["description"]
+	if ok {
+		delete(prop, "description")
+	}
+	out := map[string]any{"anyOf": []any{prop, map[string]any{"type": "null"}}}
+	if ok {
+		out["description"] = description
+	}
+	return out
+}
+
+func pathString(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	return strings.Join(path, ".")
+}
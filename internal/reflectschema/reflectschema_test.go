@@ -0,0 +1,109 @@
+package reflectschema_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/internal/reflectschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `json:"city" jsonschema:"description=City name, e.g. Paris"`
+}
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type person struct {
+	base
+	Name     string    `json:"name" jsonschema:"description=Full name"`
+	Age      int       `json:"age,omitempty" jsonschema:"minimum=0,maximum=150"`
+	Role     string    `json:"role" jsonschema:"enum=admin|member"`
+	Level    int       `json:"level,omitempty" jsonschema:"enum=1|2|3,required"`
+	Address  *address  `json:"address"`
+	Tags     []string  `json:"tags"`
+	Born     time.Time `json:"born"`
+	Secret   string    `json:"-"`
+	internal string
+	Untagged bool
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	schema, err := reflectschema.For[person](false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	assert.ElementsMatch(t, []string{"id", "name", "role", "level", "tags", "born", "Untagged"}, schema["required"])
+
+	props := schema["properties"].(map[string]any)
+	assert.Len(t, props, 9)
+	assert.Equal(t, map[string]any{"type": "string"}, props["id"])
+	assert.Equal(t, map[string]any{"type": "string", "description": "Full name"}, props["name"])
+	assert.Equal(t, map[string]any{"type": "integer", "minimum": 0.0, "maximum": 150.0}, props["age"])
+	assert.Equal(t, map[string]any{"type": "string", "enum": []any{"admin", "member"}}, props["role"])
+	assert.Equal(t, map[string]any{"type": "integer", "enum": []any{int64(1), int64(2), int64(3)}}, props["level"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, props["tags"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, props["born"])
+	assert.Equal(t, map[string]any{"type": "boolean"}, props["Untagged"])
+
+	addr := props["address"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "description": "City name, e.g. Paris"},
+		addr["properties"].(map[string]any)["city"])
+}
+
+func TestGenerateStrict(t *testing.T) {
+	t.Parallel()
+	schema, err := reflectschema.For[person](true)
+	require.NoError(t, err)
+
+	// 严格模式下所有字段都必填，可选字段改为可为 null
+	assert.Len(t, schema["required"], 9)
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"anyOf": []any{
+		map[string]any{"type": "integer", "minimum": 0.0, "maximum": 150.0},
+		map[string]any{"type": "null"},
+	}}, props["age"])
+	assert.Contains(t, props["address"], "anyOf")
+	assert.Equal(t, "string", props["name"].(map[string]any)["type"])
+}
+
+type node struct {
+	Children []node `json:"children"`
+}
+
+func TestGenerateErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := reflectschema.For[node](false)
+	assert.ErrorContains(t, err, "recursive type")
+
+	_, err = reflectschema.For[map[int]string](false)
+	assert.ErrorContains(t, err, "map key must be a string")
+
+	_, err = reflectschema.For[struct {
+		C chan int `json:"c"`
+	}](false)
+	assert.ErrorContains(t, err, "c: unsupported type")
+
+	_, err = reflectschema.For[struct {
+		A string `json:"a" jsonschema:"title=x"`
+	}](false)
+	assert.ErrorContains(t, err, `unknown jsonschema tag key "title"`)
+
+	// 严格模式下 map 无法满足 additionalProperties: false
+	type labels struct {
+		Items []struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"items"`
+	}
+	schema, err := reflectschema.For[labels](false)
+	require.NoError(t, err)
+	assert.NotNil(t, schema)
+	_, err = reflectschema.For[labels](true)
+	assert.ErrorContains(t, err, "items.items.tags: map map[string]string is not supported in strict mode")
+}
@@ -0,0 +1,203 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/memory/longterm"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestVectorStore(t *testing.T, path string) *longterm.SQLiteVectorStore {
+	t.Helper()
+	store, err := longterm.NewSQLiteVectorStore(context.Background(), longterm.SQLiteVectorStoreConfig{DBPath: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func matchTexts(matches []longterm.Match) []string {
+	var texts []string
+	for _, m := range matches {
+		texts = append(texts, m.Text)
+	}
+	return texts
+}
+
+func TestHashEmbedder(t *testing.T) {
+	ctx := context.Background()
+	e := longterm.HashEmbedder{}
+	vectors, err := e.Embed(ctx, []string{"The user likes green tea", "the USER likes green tea!", "Deploys run on Fridays", ""})
+	require.NoError(t, err)
+	require.Len(t, vectors, 4)
+	assert.Len(t, vectors[0], longterm.DefaultHashDimensions)
+
+	// 相同词语得到相同向量，无共同词语时相似度低
+	assert.Equal(t, vectors[0], vectors[1])
+	assert.InDelta(t, 1, longterm.CosineSimilarity(vectors[0], vectors[1]), 1e-6)
+	assert.Less(t, longterm.CosineSimilarity(vectors[0], vectors[2]), float32(0.5))
+	assert.Zero(t, longterm.CosineSimilarity(vectors[0], vectors[3]))
+
+	again, err := longterm.HashEmbedder{}.Embed(ctx, []string{"The user likes green tea"})
+	require.NoError(t, err)
+	assert.Equal(t, vectors[0], again[0])
+}
+
+func TestLongTermMemory_RememberRecall(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "memories.db")
+	mem := longterm.New(longterm.HashEmbedder{}, newTestVectorStore(t, dbPath))
+
+	for _, fact := range []string{
+		"The user's favourite drink is green tea",
+		"The user deploys the billing service on Fridays",
+		"The user's cat is called Miso",
+	} {
+		_, err := mem.Remember(ctx, fact, map[string]string{"user": "alice"})
+		require.NoError(t, err)
+	}
+	_, err := mem.Remember(ctx, "The user's favourite drink is coffee", map[string]string{"user": "bob"})
+	require.NoError(t, err)
+
+	matches, err := mem.Recall(ctx, "what drink does the user like", longterm.SearchOptions{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Contains(t, matches[0].Text, "favourite drink")
+
+	// 按元数据过滤
+	matches, err = mem.Recall(ctx, "favourite drink", longterm.SearchOptions{Limit: 1, Filter: map[string]string{"user": "bob"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"The user's favourite drink is coffee"}, matchTexts(matches))
+
+	matches, err = mem.Recall(ctx, "cat called Miso", longterm.SearchOptions{Filter: map[string]string{"user": "alice"}})
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	assert.Equal(t, "The user's cat is called Miso", matches[0].Text)
+	assert.GreaterOrEqual(t, matches[0].Score, matches[1].Score)
+
+	matches, err = mem.Recall(ctx, "cat called Miso", longterm.SearchOptions{MinScore: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"The user's cat is called Miso"}, matchTexts(matches))
+
+	// 重复记住同一事实只保存一份
+	record, err := mem.Remember(ctx, "The user's cat is called Miso", map[string]string{"user": "alice"})
+	require.NoError(t, err)
+	store := newTestVectorStore(t, dbPath)
+	assert.Equal(t, 4, store.Len(), "records persisted and loaded on open")
+
+	require.NoError(t, mem.Forget(ctx, record.ID))
+	matches, err = mem.Recall(ctx, "cat called Miso", longterm.SearchOptions{MinScore: 0.5})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, 3, newTestVectorStore(t, dbPath).Len())
+}
+
+func TestSQLiteVectorStore_DimensionMismatch(t *testing.T) {
+	ctx := context.Background()
+	store := newTestVectorStore(t, "")
+	require.NoError(t, store.Upsert(ctx,
+		longterm.Record{ID: "old", Text: "a", Vector: []float32{1, 0}},
+		longterm.Record{ID: "new", Text: "b", Vector: []float32{1, 0, 0}},
+	))
+
+	// 维度不同的记录（例如换了嵌入模型之前写入的）被跳过，不影响其余结果
+	matches, err := store.Search(ctx, []float32{1, 0, 0}, longterm.SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, matchTexts(matches))
+}
+
+// vectorsEmbedder 无论输入多少文本都返回固定的向量
+type vectorsEmbedder [][]float32
+
+func (e vectorsEmbedder) Embed(context.Context, []string) ([][]float32, error) {
+	return e, nil
+}
+
+func TestLongTermMemory_EmbedderVectorCount(t *testing.T) {
+	ctx := context.Background()
+	for name, embedder := range map[string]vectorsEmbedder{
+		"none":  nil,
+		"two":   {{1, 0}, {0, 1}},
+		"empty": {{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mem := longterm.New(embedder, newTestVectorStore(t, ""))
+			_, err := mem.Remember(ctx, "fact", nil)
+			assert.ErrorContains(t, err, "embed memory: embedder returned")
+			_, err = mem.Recall(ctx, "fact", longterm.SearchOptions{})
+			assert.ErrorContains(t, err, "embed query: embedder returned")
+		})
+	}
+}
+
+func TestLongTermMemory_Tools(t *testing.T) {
+	ctx := context.Background()
+	mem := longterm.New(longterm.HashEmbedder{}, newTestVectorStore(t, ""))
+	tools := mem.Tools(longterm.ToolOptions{Scope: map[string]string{"user": "alice"}, Limit: 2})
+	a := agent.New("assistant").WithTools(tools)
+
+	// 第一次对话记住事实
+	m := fakemodel.New(
+		fakemodel.Output(fakemodel.ToolCall("call_1", "remember", `{"fact":"The user is allergic to peanuts"}`)),
+		fakemodel.Text("Noted."),
+	)
+	_, err := runner.Runner{Config: runner.RunConfig{ModelProvider: m}}.Run(ctx, a, "I'm allergic to peanuts.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remember", "recall"}, fakemodel.ToolNames(m.Requests()[0]))
+	output, ok := fakemodel.ToolOutput(m.Requests()[1], "call_1")
+	require.True(t, ok)
+	assert.Equal(t, "Remembered.", output)
+
+	// 新的对话中回忆事实
+	m = fakemodel.New(
+		fakemodel.Output(fakemodel.ToolCall("call_2", "recall", `{"query":"peanuts allergy"}`)),
+		fakemodel.Text("Skip the peanut sauce."),
+	)
+	_, err = runner.Runner{Config: runner.RunConfig{ModelProvider: m}}.Run(ctx, a, "Suggest a dinner.")
+	require.NoError(t, err)
+	output, ok = fakemodel.ToolOutput(m.Requests()[1], "call_2")
+	require.True(t, ok)
+	var facts []longterm.RecalledFact
+	require.NoError(t, json.Unmarshal([]byte(output), &facts))
+	require.Len(t, facts, 1)
+	assert.Equal(t, "The user is allergic to peanuts", facts[0].Fact)
+
+	// 其他用户的作用域看不到该事实
+	matches, err := mem.Recall(ctx, "peanuts", longterm.SearchOptions{Filter: map[string]string{"user": "bob"}})
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		// 故意打乱顺序，按 index 还原
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","model":"text-embedding-3-small","data":[
+			{"object":"embedding","index":1,"embedding":[0,1]},
+			{"object":"embedding","index":0,"embedding":[1,0]}
+		],"usage":{"prompt_tokens":2,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	client := openai.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test"), option.WithMaxRetries(0))
+	vectors, err := longterm.OpenAIEmbedder{Client: client, Dimensions: 2}.Embed(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	assert.Equal(t, string(longterm.DefaultEmbeddingModel), body["model"])
+	assert.Equal(t, []any{"a", "b"}, body["input"])
+	assert.Equal(t, float64(2), body["dimensions"])
+}
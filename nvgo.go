@@ -23,12 +23,19 @@
 package agentgo
 
 import (
+	"context"
+
 	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/checkpoint"
 	"github.com/chuanbosi666/agent_go/pkg/config"
+	"github.com/chuanbosi666/agent_go/pkg/history"
 	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/memory/longterm"
+	"github.com/chuanbosi666/agent_go/pkg/model"
 	"github.com/chuanbosi666/agent_go/pkg/pattern"
 	"github.com/chuanbosi666/agent_go/pkg/runner"
 	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/tracing"
 	"github.com/chuanbosi666/agent_go/pkg/types"
 )
 
@@ -125,11 +132,43 @@ func NewOutputGuardrail(name string, fn OutputGuardrailFunc) OutputGuardrail {
 // OutputGuardrailResult 是输出护栏的执行结果。
 type OutputGuardrailResult = agent.OutputGuardrailResult
 
+// ========== Handoffs ==========
+
+// Handoff 允许 Agent 将对话转交给另一个 Agent。
+type Handoff = agent.Handoff
+
+// HandoffInputData 是转交时传给输入过滤器的数据。
+type HandoffInputData = agent.HandoffInputData
+
+// HandoffInputFilter 在转交时裁剪接收方 Agent 看到的历史。
+type HandoffInputFilter = agent.HandoffInputFilter
+
+// HandoffTo 创建一个转交给指定 Agent 的 handoff。
+func HandoffTo(a *Agent) Handoff {
+	return agent.HandoffTo(a)
+}
+
+// HandoffToName 创建一个在运行时通过名称解析目标 Agent 的 handoff。
+func HandoffToName(name string) Handoff {
+	return agent.HandoffToName(name)
+}
+
+// RemoveToolHistory 是移除工具调用及其输出的 HandoffInputFilter。
+var RemoveToolHistory = agent.RemoveToolHistory
+
 // ========== OutputType ==========
 
 // OutputTypeInterface 定义期望的输出格式。
 type OutputTypeInterface = agent.OutputTypeInterface
 
+// TypedOutput 是由 Go 类型 T 推导 JSON Schema 的输出类型。
+type TypedOutput[T any] = agent.TypedOutput[T]
+
+// OutputTypeFor 根据 T 的结构体标签生成严格模式的输出类型。
+func OutputTypeFor[T any]() *TypedOutput[T] {
+	return agent.OutputTypeFor[T]()
+}
+
 // ========== MCP Config ==========
 
 // MCPConfig 提供 MCP 服务器配置。
@@ -149,6 +188,14 @@ type RunConfig = runner.RunConfig
 // Run 使用默认 Runner 执行 Agent。
 var Run = runner.Run
 
+// RunInputs 使用默认 Runner 以结构化输入项执行 Agent。
+var RunInputs = runner.RunInputs
+
+// RunTyped 执行 Agent 并将最终输出解析为 T。
+func RunTyped[T any](ctx context.Context, r Runner, startingAgent *Agent, input string) (T, error) {
+	return runner.RunTyped[T](ctx, r, startingAgent, input)
+}
+
 // RunResult 包含 Agent 执行的完整结果。
 type RunResult = runner.RunResult
 
@@ -158,24 +205,188 @@ type RunItem = runner.RunItem
 // WrapRunItem 将 ResponseInputItemUnionParam 包装为 RunItem。
 var WrapRunItem = runner.WrapRunItem
 
+// ItemsToChatMessages 将会话历史项转换为 Chat Completions 消息。
+var ItemsToChatMessages = runner.ItemsToChatMessages
+
+// ChatMessagesToItems 将 Chat Completions 消息转换为会话历史项。
+var ChatMessagesToItems = runner.ChatMessagesToItems
+
 // Output 代表 Agent 的最终输出。
 type Output = runner.Output
 
 // Usage 跟踪 LLM 请求的 token 消耗。
 type Usage = runner.Usage
 
+// RunUsage 汇总一次运行（含交接与嵌套 Agent 工具）的 token 消耗，并按 Agent 和模型分类。
+type RunUsage = runner.RunUsage
+
 // ModelResponse 包含单个 LLM 响应。
 type ModelResponse = runner.ModelResponse
 
 // MaxTurnsExceededError 在执行超过 MaxTurns 时返回。
 type MaxTurnsExceededError = runner.MaxTurnsExceededError
 
+// OutputValidationError 在最终输出未通过 OutputType 校验时返回。
+type OutputValidationError = runner.OutputValidationError
+
 // GuardrailTripwireTriggeredError 在护栏阻止执行时返回。
 type GuardrailTripwireTriggeredError = runner.GuardrailTripwireTriggeredError
 
 // DefaultMaxTurns 是默认的最大执行轮次。
 const DefaultMaxTurns = runner.DefaultMaxTurns
 
+// RetryPolicy 配置模型调用失败后的重试策略。
+type RetryPolicy = runner.RetryPolicy
+
+// IsRetryable 判断模型调用错误是否为可重试的临时错误。
+var IsRetryable = runner.IsRetryable
+
+// ========== History ==========
+
+// HistoryStrategy 裁剪每轮发送给模型的历史，会话中仍保存完整历史。
+type HistoryStrategy = history.Strategy
+
+// TokenCounter 估算历史项占用的 token 数。
+type TokenCounter = history.TokenCounter
+
+// ApproxTokenCounter 按字符数粗略估算 token 数。
+type ApproxTokenCounter = history.ApproxTokenCounter
+
+// SlidingWindow 保留 token 预算内最新的历史项。
+type SlidingWindow = history.SlidingWindow
+
+// KeepSystemAndLastN 保留所有 system 消息和最近的 N 项。
+type KeepSystemAndLastN = history.KeepSystemAndLastN
+
+// DropToolOutputsFirst 优先省略较早的工具结果，仍超出预算时再丢弃最早的历史项。
+type DropToolOutputsFirst = history.DropToolOutputsFirst
+
+// ========== Model ==========
+
+// Model 是 Runner 调用的语言模型接口，可接入任意模型服务。
+type Model = model.Model
+
+// ModelProvider 按名称解析模型，通过 RunConfig.ModelProvider 使用。
+type ModelProvider = model.Provider
+
+// ModelRequest 是发送给模型的一次请求。
+type ModelRequest = model.Request
+
+// ModelStreamEvent 是模型流式响应中的事件。
+type ModelStreamEvent = model.StreamEvent
+
+// OpenAIProvider 基于同一个 OpenAI 客户端提供模型。
+type OpenAIProvider = model.OpenAIProvider
+
+// NewOpenAIResponsesModel 创建通过 Responses API 调用的模型。
+var NewOpenAIResponsesModel = model.NewOpenAIResponsesModel
+
+// NewOpenAIChatCompletionsModel 创建通过 Chat Completions API 调用的模型。
+var NewOpenAIChatCompletionsModel = model.NewOpenAIChatCompletionsModel
+
+// ========== Streaming ==========
+
+// RunStreamed 使用默认 Runner 以流式方式执行 Agent。
+var RunStreamed = runner.RunStreamed
+
+// RunResultStreaming 是流式执行中的运行，可读取事件并等待最终结果。
+type RunResultStreaming = runner.RunResultStreaming
+
+// StreamEvent 是流式执行过程中产生的事件。
+type StreamEvent = runner.StreamEvent
+
+// TextDeltaEvent 携带模型生成的一段文本增量。
+type TextDeltaEvent = runner.TextDeltaEvent
+
+// ToolCallStartedEvent 在模型开始调用工具时产生。
+type ToolCallStartedEvent = runner.ToolCallStartedEvent
+
+// ToolCallArgumentsDeltaEvent 携带工具调用参数的一段增量。
+type ToolCallArgumentsDeltaEvent = runner.ToolCallArgumentsDeltaEvent
+
+// ToolOutputEvent 在工具执行完成后产生。
+type ToolOutputEvent = runner.ToolOutputEvent
+
+// AgentSwitchedEvent 在运行开始及当前 Agent 切换时产生。
+type AgentSwitchedEvent = runner.AgentSwitchedEvent
+
+// TurnFinishedEvent 在每一轮模型调用结束后产生。
+type TurnFinishedEvent = runner.TurnFinishedEvent
+
+// FinalResultEvent 是成功运行的最后一个事件。
+type FinalResultEvent = runner.FinalResultEvent
+
+// ========== Approval ==========
+
+// ToolApprovalItem 是等待审批的工具调用。
+type ToolApprovalItem = runner.ToolApprovalItem
+
+// ToolApproval 是对一次工具调用的审批决定。
+type ToolApproval = runner.ToolApproval
+
+// Approve 批准指定 ID 的工具调用。
+var Approve = runner.Approve
+
+// Reject 拒绝指定 ID 的工具调用，模型会看到拒绝原因。
+var Reject = runner.Reject
+
+// RunState 是暂停运行的可序列化状态。
+type RunState = runner.RunState
+
+// LoadRunState 从 JSON 恢复 RunState。
+var LoadRunState = runner.LoadRunState
+
+// Resume 使用默认 Runner 继续等待审批的运行。
+var Resume = runner.Resume
+
+// InterruptedError 表示作为工具调用的 Agent 因等待审批而暂停。
+type InterruptedError = runner.InterruptedError
+
+// ========== Checkpoint ==========
+
+// CheckpointStore 按运行 ID 保存运行状态，用于中断后恢复。
+type CheckpointStore = checkpoint.Store
+
+// NewFileCheckpointStore 创建按文件保存检查点的存储。
+var NewFileCheckpointStore = checkpoint.NewFileStore
+
+// SQLiteCheckpointStoreConfig 配置 SQLite 检查点存储。
+type SQLiteCheckpointStoreConfig = checkpoint.SQLiteStoreConfig
+
+// NewSQLiteCheckpointStore 创建基于 SQLite 的检查点存储。
+var NewSQLiteCheckpointStore = checkpoint.NewSQLiteStore
+
+// LoadCheckpoint 从检查点存储加载运行状态。
+var LoadCheckpoint = runner.LoadCheckpoint
+
+// ResumeFromState 使用默认 Runner 从运行状态继续中断的运行。
+var ResumeFromState = runner.ResumeFromState
+
+// ========== Hooks ==========
+
+// RunHooks 接收一次运行中所有 Agent 的生命周期回调。
+type RunHooks = runner.RunHooks
+
+// NoOpRunHooks 是空实现，嵌入后只需实现关心的回调。
+type NoOpRunHooks = runner.NoOpRunHooks
+
+// AgentHooks 接收单个 Agent 的生命周期回调。
+type AgentHooks = agent.AgentHooks
+
+// NoOpAgentHooks 是空实现，嵌入后只需实现关心的回调。
+type NoOpAgentHooks = agent.NoOpAgentHooks
+
+// ========== Tracing ==========
+
+// TraceProcessor 接收运行过程中开始与结束的 trace 和 span。
+type TraceProcessor = tracing.TraceProcessor
+
+// AddTraceProcessor 向默认的 trace provider 添加处理器。
+var AddTraceProcessor = tracing.AddTraceProcessor
+
+// SetTraceProcessors 替换默认 trace provider 的全部处理器。
+var SetTraceProcessors = tracing.SetTraceProcessors
+
 // ========== Tool ==========
 
 // Tool 定义 Agent 可调用的工具接口。
@@ -193,6 +404,11 @@ var DefaultToolErrorFunction = tool.DefaultToolErrorFunction
 // FunctionToolEnabler 定义工具是否启用的检查接口。
 type FunctionToolEnabler = tool.FunctionToolEnabler
 
+// NewFunctionTool 根据类型化的 Go 函数创建工具，参数 Schema 由 Args 结构体自动生成。
+func NewFunctionTool[Args any, Out any](name, description string, fn func(ctx context.Context, args Args) (Out, error)) FunctionTool {
+	return tool.NewFunctionTool(name, description, fn)
+}
+
 // ========== Tool Router ==========
 
 // ToolRouter 动态选择相关工具。
@@ -296,6 +512,9 @@ var LoadOrCreate = config.LoadOrCreate
 // Session 管理对话历史。
 type Session = memory.Session
 
+// ItemReplacer 是可一次性整体替换对话项的会话。
+type ItemReplacer = memory.ItemReplacer
+
 // SQLiteSessionConfig 配置 SQLite 会话。
 type SQLiteSessionConfig = memory.SQLiteSessionConfig
 
@@ -305,6 +524,92 @@ type SQLiteSession = memory.SQLiteSession
 // NewSQLiteSession 创建新的 SQLite 会话。
 var NewSQLiteSession = memory.NewSQLiteSession
 
+// PostgresSessionConfig 配置 Postgres 会话。
+type PostgresSessionConfig = memory.PostgresSessionConfig
+
+// PostgresSession 是基于 Postgres 的会话实现，可在多个服务实例间共享。
+type PostgresSession = memory.PostgresSession
+
+// NewPostgresSession 创建新的 Postgres 会话。
+var NewPostgresSession = memory.NewPostgresSession
+
+// MigratePostgres 将 Postgres 中的会话表结构升级到最新版本。
+var MigratePostgres = memory.MigratePostgres
+
+// RedisSessionConfig 配置 Redis 会话。
+type RedisSessionConfig = memory.RedisSessionConfig
+
+// RedisSession 是基于 Redis 列表的会话实现，支持过期时间与长度上限。
+type RedisSession = memory.RedisSession
+
+// NewRedisSession 创建新的 Redis 会话。
+var NewRedisSession = memory.NewRedisSession
+
+// SessionStoreConfig 配置会话管理器。
+type SessionStoreConfig = memory.SessionStoreConfig
+
+// SessionStore 管理 SQLite 中的全部会话：列出、复制、重命名、元数据与过期清理。
+type SessionStore = memory.SessionStore
+
+// SessionInfo 描述一个已保存的会话。
+type SessionInfo = memory.SessionInfo
+
+// ListSessionsOptions 配置会话列表的分页。
+type ListSessionsOptions = memory.ListSessionsOptions
+
+// SweeperOptions 配置过期会话的后台清理。
+type SweeperOptions = memory.SweeperOptions
+
+// NewSessionStore 创建新的会话管理器。
+var NewSessionStore = memory.NewSessionStore
+
+// Summarizer 把对话历史压缩为摘要文本。
+type Summarizer = memory.Summarizer
+
+// SummarizingSessionConfig 配置摘要会话。
+type SummarizingSessionConfig = memory.SummarizingSessionConfig
+
+// SummarizingSession 在历史过长时用摘要替换最早的对话项。
+type SummarizingSession = memory.SummarizingSession
+
+// NewSummarizingSession 用摘要压缩包装一个会话。
+var NewSummarizingSession = memory.NewSummarizingSession
+
+// AgentSummarizer 运行一个 Agent 生成会话摘要。
+type AgentSummarizer = pattern.AgentSummarizer
+
+// DefaultSummarizerInstructions 是摘要 Agent 的默认指令。
+const DefaultSummarizerInstructions = pattern.DefaultSummarizerInstructions
+
+// ========== Long-term Memory ==========
+
+// LongTermMemory 按语义记住并回忆跨会话的事实。
+type LongTermMemory = longterm.Memory
+
+// NewLongTermMemory 创建长期记忆。
+var NewLongTermMemory = longterm.New
+
+// Embedder 将文本转换为向量。
+type Embedder = longterm.Embedder
+
+// OpenAIEmbedder 使用 OpenAI embeddings API 生成向量。
+type OpenAIEmbedder = longterm.OpenAIEmbedder
+
+// HashEmbedder 是确定性的本地向量生成器，适用于测试。
+type HashEmbedder = longterm.HashEmbedder
+
+// VectorStore 存储向量并按相似度检索。
+type VectorStore = longterm.VectorStore
+
+// SQLiteVectorStoreConfig 配置 SQLite 向量存储。
+type SQLiteVectorStoreConfig = longterm.SQLiteVectorStoreConfig
+
+// SQLiteVectorStore 是持久化到 SQLite 的向量存储。
+type SQLiteVectorStore = longterm.SQLiteVectorStore
+
+// NewSQLiteVectorStore 创建新的 SQLite 向量存储。
+var NewSQLiteVectorStore = longterm.NewSQLiteVectorStore
+
 var (
 	DefaultConfig = config.DefaultConfig
 	LoadWithEnv   = config.LoadWithEnv
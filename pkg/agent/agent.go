@@ -56,6 +56,15 @@ type Agent struct {
 
 	// OutputType describes the expected output format (defaults to plain text).
 	OutputType OutputTypeInterface
+
+	// Handoffs lists the agents this agent can transfer the conversation to.
+	Handoffs []Handoff
+
+	// HandoffDescription tells other agents when to hand off to this agent (optional).
+	HandoffDescription string
+
+	// Hooks receives lifecycle callbacks for this agent (optional).
+	Hooks AgentHooks
 }
 
 // Implement types.AgentLike interface
@@ -168,3 +177,27 @@ func (a *Agent) AddTools(tools []tool.FunctionTool) *Agent {
 	a.Tools = append(a.Tools, tools...)
 	return a
 }
+
+// WithHandoffs sets the handoffs.
+func (a *Agent) WithHandoffs(handoffs []Handoff) *Agent {
+	a.Handoffs = handoffs
+	return a
+}
+
+// AddHandoff appends a handoff.
+func (a *Agent) AddHandoff(h Handoff) *Agent {
+	a.Handoffs = append(a.Handoffs, h)
+	return a
+}
+
+// WithHandoffDescription sets the description shown to agents handing off to this one.
+func (a *Agent) WithHandoffDescription(desc string) *Agent {
+	a.HandoffDescription = desc
+	return a
+}
+
+// WithHooks sets the lifecycle hooks.
+func (a *Agent) WithHooks(hooks AgentHooks) *Agent {
+	a.Hooks = hooks
+	return a
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// HandoffInputData is passed to a HandoffInputFilter when the conversation is transferred.
+type HandoffInputData struct {
+	// From is the agent that requested the handoff.
+	From *Agent
+	// To is the agent receiving the conversation.
+	To *Agent
+	// History is the conversation up to and including the turn that requested the handoff.
+	History []responses.ResponseInputItemUnionParam
+}
+
+// HandoffInputFilter rewrites the history the receiving agent sees.
+// It does not change what is stored in the session.
+type HandoffInputFilter func(ctx context.Context, data HandoffInputData) ([]responses.ResponseInputItemUnionParam, error)
+
+// Handoff lets an agent transfer the conversation to another agent.
+// The model sees it as a transfer_to_<agent> tool.
+type Handoff struct {
+	// Agent is the receiving agent.
+	Agent *Agent
+
+	// AgentName names the receiving agent when Agent is nil.
+	// It is resolved at run time through RunConfig.HandoffResolver.
+	AgentName string
+
+	// ToolName overrides the default transfer_to_<agent> tool name (optional).
+	ToolName string
+
+	// ToolDescription overrides the default tool description (optional).
+	ToolDescription string
+
+	// InputFilter trims the history passed to the receiving agent (optional).
+	InputFilter HandoffInputFilter
+}
+
+// HandoffTo creates a handoff to the given agent.
+func HandoffTo(a *Agent) Handoff {
+	return Handoff{Agent: a}
+}
+
+// HandoffToName creates a handoff to an agent resolved by name at run time.
+func HandoffToName(name string) Handoff {
+	return Handoff{AgentName: name}
+}
+
+// WithInputFilter returns a copy of the handoff using the given input filter.
+func (h Handoff) WithInputFilter(filter HandoffInputFilter) Handoff {
+	h.InputFilter = filter
+	return h
+}
+
+// TargetName returns the name of the receiving agent.
+func (h Handoff) TargetName() string {
+	if h.Agent != nil {
+		return h.Agent.Name
+	}
+	return h.AgentName
+}
+
+// GetToolName returns the name of the tool exposed to the model.
+func (h Handoff) GetToolName() string {
+	if h.ToolName != "" {
+		return h.ToolName
+	}
+	return HandoffToolName(h.TargetName())
+}
+
+// GetToolDescription returns the description of the tool exposed to the model.
+func (h Handoff) GetToolDescription() string {
+	if h.ToolDescription != "" {
+		return h.ToolDescription
+	}
+	desc := fmt.Sprintf("Handoff to the %s agent to handle the request.", h.TargetName())
+	if h.Agent != nil && h.Agent.HandoffDescription != "" {
+		desc += " " + h.Agent.HandoffDescription
+	}
+	return desc
+}
+
+// HandoffToolName returns the default tool name for a handoff to agentName,
+// e.g. "Billing Agent" becomes "transfer_to_billing_agent".
+func HandoffToolName(agentName string) string {
+	var b strings.Builder
+	b.WriteString("transfer_to_")
+	for _, r := range strings.ToLower(agentName) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// RemoveToolHistory is a HandoffInputFilter that drops function calls and their outputs,
+// leaving only the messages of the conversation.
+func RemoveToolHistory(_ context.Context, data HandoffInputData) ([]responses.ResponseInputItemUnionParam, error) {
+	filtered := make([]responses.ResponseInputItemUnionParam, 0, len(data.History))
+	for _, item := range data.History {
+		if item.OfFunctionCall != nil || item.OfFunctionCallOutput != nil {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered, nil
+}
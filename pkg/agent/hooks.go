@@ -0,0 +1,42 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// AgentHooks receives lifecycle callbacks for a single agent. Callbacks run
+// synchronously in the run loop, except the tool callbacks, which may be
+// called concurrently when a turn has several tool calls.
+//
+// Embed NoOpAgentHooks to implement only the callbacks you need.
+type AgentHooks interface {
+	// OnStart is called when the agent becomes the current agent of a run.
+	OnStart(ctx context.Context, a *Agent)
+	// OnEnd is called when the agent produces the final output.
+	OnEnd(ctx context.Context, a *Agent, output any)
+	// OnHandoff is called on the receiving agent when source hands off to it.
+	OnHandoff(ctx context.Context, a *Agent, source *Agent)
+	// OnLLMStart is called before each model request.
+	OnLLMStart(ctx context.Context, a *Agent, instructions string, input []responses.ResponseInputItemUnionParam)
+	// OnLLMEnd is called with the output of each successful model request.
+	OnLLMEnd(ctx context.Context, a *Agent, output []responses.ResponseOutputItemUnion)
+	// OnToolStart is called before a tool call is executed.
+	OnToolStart(ctx context.Context, a *Agent, call responses.ResponseFunctionToolCall)
+	// OnToolEnd is called with the output sent back to the model for a tool call.
+	OnToolEnd(ctx context.Context, a *Agent, call responses.ResponseFunctionToolCall, output string)
+}
+
+// NoOpAgentHooks implements AgentHooks with callbacks that do nothing.
+type NoOpAgentHooks struct{}
+
+func (NoOpAgentHooks) OnStart(context.Context, *Agent)           {}
+func (NoOpAgentHooks) OnEnd(context.Context, *Agent, any)        {}
+func (NoOpAgentHooks) OnHandoff(context.Context, *Agent, *Agent) {}
+func (NoOpAgentHooks) OnLLMStart(context.Context, *Agent, string, []responses.ResponseInputItemUnionParam) {
+}
+func (NoOpAgentHooks) OnLLMEnd(context.Context, *Agent, []responses.ResponseOutputItemUnion)   {}
+func (NoOpAgentHooks) OnToolStart(context.Context, *Agent, responses.ResponseFunctionToolCall) {}
+func (NoOpAgentHooks) OnToolEnd(context.Context, *Agent, responses.ResponseFunctionToolCall, string) {
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/chuanbosi666/agent_go/internal/reflectschema"
+	"github.com/chuanbosi666/agent_go/internal/strictschema"
+)
+
+// wrappedOutputKey holds non-object outputs, since the API requires an object at the schema root.
+const wrappedOutputKey = "response"
+
+var _ OutputTypeInterface = (*TypedOutput[any])(nil)
+
+// TypedOutput is an OutputTypeInterface whose schema is derived from the Go type T.
+// Create it with OutputTypeFor.
+type TypedOutput[T any] struct {
+	name    string
+	schema  map[string]any
+	err     error
+	wrapped bool
+	plain   bool
+}
+
+// OutputTypeFor returns an output type for T with a strict JSON schema derived
+// from its struct tags (see internal/reflectschema for the supported tags).
+// ValidateJSON decodes the model's output into a T. A string T means plain text.
+func OutputTypeFor[T any]() *TypedOutput[T] {
+	t := reflect.TypeFor[T]()
+	o := &TypedOutput[T]{name: t.Name()}
+	if t.Kind() == reflect.String {
+		o.plain = true
+		return o
+	}
+
+	schema, err := reflectschema.Generate(t, true)
+	if err != nil {
+		o.err = fmt.Errorf("generate schema for %s: %w", t, err)
+		return o
+	}
+	if schema["type"] != "object" || schema["properties"] == nil {
+		o.wrapped = true
+		schema = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{wrappedOutputKey: schema},
+		}
+	}
+	o.schema, o.err = strictschema.EnsureStrictJSONSchema(schema)
+	return o
+}
+
+// IsPlainText reports whether T is a string.
+func (o *TypedOutput[T]) IsPlainText() bool { return o.plain }
+
+// Name returns the name of T.
+func (o *TypedOutput[T]) Name() string { return o.name }
+
+// IsStrictJSONSchema always returns true.
+func (o *TypedOutput[T]) IsStrictJSONSchema() bool { return true }
+
+// JSONSchema returns the strict JSON schema of T.
+func (o *TypedOutput[T]) JSONSchema() (map[string]any, error) {
+	return o.schema, o.err
+}
+
+// ValidateJSON decodes jsonStr into a T.
+func (o *TypedOutput[T]) ValidateJSON(_ context.Context, jsonStr string) (any, error) {
+	var out T
+	if o.plain {
+		reflect.ValueOf(&out).Elem().SetString(jsonStr)
+		return out, nil
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	if o.wrapped {
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(jsonStr), &envelope); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		raw, ok := envelope[wrappedOutputKey]
+		if !ok {
+			return nil, fmt.Errorf("missing %q field", wrappedOutputKey)
+		}
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", o.name, err)
+		}
+		return out, nil
+	}
+
+	if err := json.Unmarshal([]byte(jsonStr), &out); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", o.name, err)
+	}
+	return out, nil
+}
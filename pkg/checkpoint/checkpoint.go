@@ -0,0 +1,29 @@
+// Package checkpoint stores snapshots of runs in progress so that a run
+// interrupted by a crash or restart can be resumed.
+//
+// A checkpoint is an opaque JSON document keyed by run ID; the runner writes
+// its RunState after every turn and deletes it when the run completes.
+package checkpoint
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNotFound indicates that no checkpoint exists for the run ID.
+	ErrNotFound = errors.New("checkpoint not found")
+	// ErrInvalidRunID indicates that the run ID is empty or unusable as a key.
+	ErrInvalidRunID = errors.New("invalid run ID")
+)
+
+// Store persists checkpoints by run ID. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Save stores data as the checkpoint of runID, replacing any previous one.
+	Save(ctx context.Context, runID string, data []byte) error
+	// Load returns the checkpoint of runID, or ErrNotFound.
+	Load(ctx context.Context, runID string) ([]byte, error)
+	// Delete removes the checkpoint of runID. Deleting a missing checkpoint is not an error.
+	Delete(ctx context.Context, runID string) error
+}
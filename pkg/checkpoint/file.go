@@ -0,0 +1,93 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var _ Store = (*FileStore)(nil)
+
+// FileStore keeps each checkpoint in its own JSON file, <dir>/<runID>.json.
+// Files are replaced atomically, so a crash during Save leaves the previous
+// checkpoint intact.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store in dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create checkpoint dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save writes data to a temporary file and renames it over the checkpoint.
+func (s *FileStore) Save(_ context.Context, runID string, data []byte) error {
+	path, err := s.path(runID)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, runID+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create checkpoint file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close checkpoint file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Load reads the checkpoint file of runID.
+func (s *FileStore) Load(_ context.Context, runID string) ([]byte, error) {
+	path, err := s.path(runID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes the checkpoint file of runID.
+func (s *FileStore) Delete(_ context.Context, runID string) error {
+	path, err := s.path(runID)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of runID, rejecting IDs that would escape the directory.
+func (s *FileStore) path(runID string) (string, error) {
+	if runID == "" || runID == "." || runID == ".." || strings.ContainsAny(runID, `/\`) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRunID, runID)
+	}
+	return filepath.Join(s.dir, runID+".json"), nil
+}
@@ -0,0 +1,126 @@
+package checkpoint
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// DefaultDBPath is the default path for the SQLite database, using in-memory storage.
+	DefaultDBPath = ":memory:"
+	// DefaultTable is the default table name for checkpoints.
+	DefaultTable = "agent_checkpoints"
+)
+
+// SQLiteStoreConfig holds configuration for creating a new SQLiteStore.
+type SQLiteStoreConfig struct {
+	// DBPath is the path to the SQLite database file; defaults to ":memory:" for in-memory storage.
+	DBPath string
+	// Table is the table name for checkpoints; defaults to "agent_checkpoints".
+	Table string
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// SQLiteStore keeps checkpoints in a SQLite table, one row per run.
+type SQLiteStore struct {
+	db    *sql.DB
+	table string
+	mu    sync.Mutex
+}
+
+// NewSQLiteStore opens the database and creates the checkpoint table if it does not exist.
+func NewSQLiteStore(ctx context.Context, config SQLiteStoreConfig) (*SQLiteStore, error) {
+	if config.DBPath == "" {
+		config.DBPath = DefaultDBPath
+	}
+	if config.Table == "" {
+		config.Table = DefaultTable
+	}
+
+	db, err := sql.Open("sqlite3", config.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("open checkpoint database: %w", err)
+	}
+	// An in-memory database exists per connection; keep a single one.
+	if config.DBPath == DefaultDBPath {
+		db.SetMaxOpenConns(1)
+	}
+
+	createTable := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			run_id TEXT PRIMARY KEY,
+			state TEXT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`, config.Table)
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create checkpoint table: %w", err)
+	}
+	return &SQLiteStore{db: db, table: config.Table}, nil
+}
+
+// Save inserts or replaces the checkpoint row of runID.
+func (s *SQLiteStore) Save(ctx context.Context, runID string, data []byte) error {
+	if runID == "" {
+		return ErrInvalidRunID
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (run_id, state, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (run_id) DO UPDATE SET state = excluded.state, updated_at = excluded.updated_at`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, runID, string(data), time.Now()); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Load returns the checkpoint row of runID.
+func (s *SQLiteStore) Load(ctx context.Context, runID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var data string
+	query := fmt.Sprintf(`SELECT state FROM %s WHERE run_id = ?`, s.table)
+	err := s.db.QueryRowContext(ctx, query, runID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+	return []byte(data), nil
+}
+
+// Delete removes the checkpoint row of runID.
+func (s *SQLiteStore) Delete(ctx context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE run_id = ?`, s.table)
+	if _, err := s.db.ExecContext(ctx, query, runID); err != nil {
+		return fmt.Errorf("delete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying database connection.
+func (s *SQLiteStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
@@ -17,5 +17,5 @@ type ModelConfig struct{
 
 	APIKey string `json:"apiKey"`
 
-	Model string `json: "model"`
+	Model string `json:"model"`
 }
\ No newline at end of file
@@ -0,0 +1,154 @@
+// Package history trims the conversation history sent to a model so that it
+// fits the model's context window.
+//
+// A Strategy returns the items to send for a turn; the session or run history
+// itself is left untouched. Strategies never separate a function call from its
+// output, which the APIs reject.
+package history
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// DefaultCharsPerToken is the ratio used by ApproxTokenCounter by default.
+const DefaultCharsPerToken = 4
+
+// TokenCounter estimates the number of tokens an item takes in a request.
+type TokenCounter interface {
+	CountTokens(item responses.ResponseInputItemUnionParam) int
+}
+
+// TokenCounterFunc adapts a function to TokenCounter.
+type TokenCounterFunc func(item responses.ResponseInputItemUnionParam) int
+
+// CountTokens implements TokenCounter.
+func (f TokenCounterFunc) CountTokens(item responses.ResponseInputItemUnionParam) int {
+	return f(item)
+}
+
+// ApproxTokenCounter estimates tokens from the length of an item's JSON
+// encoding, without a tokenizer. The zero value uses DefaultCharsPerToken.
+type ApproxTokenCounter struct {
+	CharsPerToken int
+}
+
+// CountTokens implements TokenCounter.
+func (c ApproxTokenCounter) CountTokens(item responses.ResponseInputItemUnionParam) int {
+	perToken := c.CharsPerToken
+	if perToken <= 0 {
+		perToken = DefaultCharsPerToken
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return 0
+	}
+	return (len(data) + perToken - 1) / perToken
+}
+
+// CountTokens returns the tokens of items as estimated by counter, or by
+// ApproxTokenCounter when counter is nil.
+func CountTokens(counter TokenCounter, items []responses.ResponseInputItemUnionParam) int {
+	if counter == nil {
+		counter = ApproxTokenCounter{}
+	}
+	total := 0
+	for _, item := range items {
+		total += counter.CountTokens(item)
+	}
+	return total
+}
+
+// Strategy selects the history items sent to the model for a turn.
+// Implementations must not modify items.
+type Strategy interface {
+	Trim(ctx context.Context, items []responses.ResponseInputItemUnionParam) ([]responses.ResponseInputItemUnionParam, error)
+}
+
+// StrategyFunc adapts a function to Strategy.
+type StrategyFunc func(ctx context.Context, items []responses.ResponseInputItemUnionParam) ([]responses.ResponseInputItemUnionParam, error)
+
+// Trim implements Strategy.
+func (f StrategyFunc) Trim(ctx context.Context, items []responses.ResponseInputItemUnionParam) ([]responses.ResponseInputItemUnionParam, error) {
+	return f(ctx, items)
+}
+
+// units groups the indexes of items so that each function call shares a
+// unit with its outputs; any other item is a unit of its own. Units are
+// ordered by their first item.
+func units(items []responses.ResponseInputItemUnionParam) [][]int {
+	var out [][]int
+	calls := map[string]int{}
+	for i, item := range items {
+		switch {
+		case item.OfFunctionCall != nil:
+			calls[item.OfFunctionCall.CallID] = len(out)
+			out = append(out, []int{i})
+		case item.OfFunctionCallOutput != nil:
+			if u, ok := calls[item.OfFunctionCallOutput.CallID]; ok {
+				out[u] = append(out[u], i)
+				continue
+			}
+			out = append(out, []int{i})
+		default:
+			out = append(out, []int{i})
+		}
+	}
+	return out
+}
+
+// keepNewest keeps the newest units whose combined cost fits budget, and at
+// least the newest one. pinned items are always kept and cost nothing.
+func keepNewest(
+	items []responses.ResponseInputItemUnionParam,
+	pinned func(responses.ResponseInputItemUnionParam) bool,
+	cost func(responses.ResponseInputItemUnionParam) int,
+	budget int,
+) []responses.ResponseInputItemUnionParam {
+	keep := make([]bool, len(items))
+	var free [][]int
+	for _, u := range units(items) {
+		if len(u) == 1 && pinned != nil && pinned(items[u[0]]) {
+			keep[u[0]] = true
+			continue
+		}
+		free = append(free, u)
+	}
+
+	used := 0
+	for i := len(free) - 1; i >= 0; i-- {
+		c := 0
+		for _, idx := range free[i] {
+			c += cost(items[idx])
+		}
+		if used+c > budget && i != len(free)-1 {
+			break
+		}
+		used += c
+		for _, idx := range free[i] {
+			keep[idx] = true
+		}
+	}
+
+	kept := make([]responses.ResponseInputItemUnionParam, 0, len(items))
+	for i, item := range items {
+		if keep[i] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// isSystem reports whether item is a system or developer message.
+func isSystem(item responses.ResponseInputItemUnionParam) bool {
+	var role string
+	switch {
+	case item.OfMessage != nil:
+		role = string(item.OfMessage.Role)
+	case item.OfInputMessage != nil:
+		role = item.OfInputMessage.Role
+	}
+	return role == "system" || role == "developer"
+}
@@ -0,0 +1,109 @@
+package history
+
+import (
+	"context"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// DefaultOmittedToolOutput replaces tool outputs dropped by DropToolOutputsFirst.
+const DefaultOmittedToolOutput = "[tool output omitted to save context]"
+
+// SlidingWindow keeps the newest items that fit in MaxTokens. The newest
+// item, with its function call or outputs, is always kept.
+type SlidingWindow struct {
+	MaxTokens int
+	// Counter estimates tokens. Nil uses ApproxTokenCounter.
+	Counter TokenCounter
+	// KeepSystem keeps system and developer messages outside the window.
+	KeepSystem bool
+}
+
+// Trim implements Strategy.
+func (s SlidingWindow) Trim(_ context.Context, items []responses.ResponseInputItemUnionParam) ([]responses.ResponseInputItemUnionParam, error) {
+	var pinned func(responses.ResponseInputItemUnionParam) bool
+	if s.KeepSystem {
+		pinned = isSystem
+	}
+	return keepNewest(items, pinned, counter(s.Counter).CountTokens, s.MaxTokens), nil
+}
+
+// KeepSystemAndLastN keeps every system and developer message plus the last
+// N other items. A function call and its outputs are kept or dropped
+// together, so fewer than N items may be kept; the newest is always kept.
+type KeepSystemAndLastN struct {
+	N int
+}
+
+// Trim implements Strategy.
+func (s KeepSystemAndLastN) Trim(_ context.Context, items []responses.ResponseInputItemUnionParam) ([]responses.ResponseInputItemUnionParam, error) {
+	one := func(responses.ResponseInputItemUnionParam) int { return 1 }
+	return keepNewest(items, isSystem, one, s.N), nil
+}
+
+// DropToolOutputsFirst fits the history in MaxTokens by first replacing the
+// oldest tool outputs with Placeholder, then, if that is not enough, dropping
+// the oldest items as SlidingWindow does. Outputs following the last user
+// message belong to the request being worked on and are never replaced.
+type DropToolOutputsFirst struct {
+	MaxTokens int
+	// Counter estimates tokens. Nil uses ApproxTokenCounter.
+	Counter TokenCounter
+	// Placeholder replaces dropped outputs. Empty uses DefaultOmittedToolOutput.
+	Placeholder string
+}
+
+// Trim implements Strategy.
+func (s DropToolOutputsFirst) Trim(ctx context.Context, items []responses.ResponseInputItemUnionParam) ([]responses.ResponseInputItemUnionParam, error) {
+	c := counter(s.Counter)
+	total := CountTokens(c, items)
+	if total <= s.MaxTokens {
+		return items, nil
+	}
+
+	placeholder := s.Placeholder
+	if placeholder == "" {
+		placeholder = DefaultOmittedToolOutput
+	}
+	current := len(items)
+	for i := len(items) - 1; i >= 0; i-- {
+		if isUserMessage(items[i]) {
+			current = i
+			break
+		}
+	}
+
+	trimmed := make([]responses.ResponseInputItemUnionParam, len(items))
+	copy(trimmed, items)
+	for i := 0; i < current && total > s.MaxTokens; i++ {
+		output := trimmed[i].OfFunctionCallOutput
+		if output == nil {
+			continue
+		}
+		replaced := responses.ResponseInputItemParamOfFunctionCallOutput(output.CallID, placeholder)
+		total += c.CountTokens(replaced) - c.CountTokens(trimmed[i])
+		trimmed[i] = replaced
+	}
+	if total <= s.MaxTokens {
+		return trimmed, nil
+	}
+	return SlidingWindow{MaxTokens: s.MaxTokens, Counter: c}.Trim(ctx, trimmed)
+}
+
+func counter(c TokenCounter) TokenCounter {
+	if c == nil {
+		return ApproxTokenCounter{}
+	}
+	return c
+}
+
+// isUserMessage reports whether item is a user message.
+func isUserMessage(item responses.ResponseInputItemUnionParam) bool {
+	switch {
+	case item.OfMessage != nil:
+		return item.OfMessage.Role == responses.EasyInputMessageRoleUser
+	case item.OfInputMessage != nil:
+		return item.OfInputMessage.Role == "user"
+	}
+	return false
+}
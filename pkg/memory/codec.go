@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// EncodeItem serializes an item for storage. Message and item reference items
+// are always written with an explicit "type" so they can be told apart when decoded.
+func EncodeItem(item responses.ResponseInputItemUnionParam) ([]byte, error) {
+	if item.OfMessage != nil && item.OfMessage.Type == "" {
+		msg := *item.OfMessage
+		msg.Type = responses.EasyInputMessageTypeMessage
+		item.OfMessage = &msg
+	}
+	if item.OfInputMessage != nil && item.OfInputMessage.Type == "" {
+		msg := *item.OfInputMessage
+		msg.Type = "message"
+		item.OfInputMessage = &msg
+	}
+	if item.OfItemReference != nil && item.OfItemReference.Type == "" {
+		ref := *item.OfItemReference
+		ref.Type = "item_reference"
+		item.OfItemReference = &ref
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidItemData, err)
+	}
+	return data, nil
+}
+
+// storedItemHeader holds the fields used to pick the variant of a stored item.
+type storedItemHeader struct {
+	Type    string          `json:"type"`
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// DecodeItem deserializes an item written by EncodeItem.
+//
+// The three message variants share the "message" type, which the generic union
+// decoder cannot distinguish, so they are resolved by role and content here.
+func DecodeItem(data []byte) (responses.ResponseInputItemUnionParam, error) {
+	var item responses.ResponseInputItemUnionParam
+
+	var header storedItemHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return item, fmt.Errorf("%w: %w", ErrInvalidItemData, err)
+	}
+	if header.Type != "" && header.Type != "message" {
+		if err := json.Unmarshal(data, &item); err != nil {
+			return item, fmt.Errorf("%w: %w", ErrInvalidItemData, err)
+		}
+		return item, nil
+	}
+
+	if header.Role == "assistant" && isOutputContent(header.Content) {
+		var msg responses.ResponseOutputMessageParam
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return item, fmt.Errorf("%w: %w", ErrInvalidItemData, err)
+		}
+		item.OfOutputMessage = &msg
+		return item, nil
+	}
+
+	var msg responses.EasyInputMessageParam
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return item, fmt.Errorf("%w: %w", ErrInvalidItemData, err)
+	}
+	item.OfMessage = &msg
+	return item, nil
+}
+
+// isOutputContent reports whether content is a list of output_text/refusal parts.
+func isOutputContent(content json.RawMessage) bool {
+	var parts []struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(content, &parts); err != nil || len(parts) == 0 {
+		return false
+	}
+	for _, p := range parts {
+		if p.Type != "output_text" && p.Type != "refusal" {
+			return false
+		}
+	}
+	return true
+}
@@ -11,6 +11,8 @@ var (
 	ErrDatabaseInit = errors.New("failed to initialize database schema")
 	// ErrSessionNotFound indicates that the session does not exist.
 	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExists indicates that a session with the target ID already exists.
+	ErrSessionExists = errors.New("session already exists")
 	// ErrInvalidItemData indicates that an item could not be serialized/deserialized.
 	ErrInvalidItemData = errors.New("invalid item data")
 	// ErrTransactionFailed indicates that a database transaction failed.
@@ -0,0 +1,122 @@
+package longterm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+const (
+	// DefaultEmbeddingModel is the model used by OpenAIEmbedder by default.
+	DefaultEmbeddingModel = openai.EmbeddingModelTextEmbedding3Small
+	// DefaultHashDimensions is the vector length of HashEmbedder by default.
+	DefaultHashDimensions = 256
+)
+
+// Embedder turns texts into vectors, one per text and in the same order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// OpenAIEmbedder embeds texts with the OpenAI embeddings API.
+type OpenAIEmbedder struct {
+	Client openai.Client
+	// Model defaults to DefaultEmbeddingModel.
+	Model openai.EmbeddingModel
+	// Dimensions shortens the vectors if set; supported by text-embedding-3 models.
+	Dimensions int
+}
+
+// Embed implements Embedder.
+func (e OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	params := openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model: e.Model,
+	}
+	if params.Model == "" {
+		params.Model = DefaultEmbeddingModel
+	}
+	if e.Dimensions > 0 {
+		params.Dimensions = param.NewOpt(int64(e.Dimensions))
+	}
+
+	resp, err := e.Client.Embeddings.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("call embeddings API: %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || int(d.Index) >= len(texts) {
+			return nil, fmt.Errorf("embeddings API returned index %d for %d texts", d.Index, len(texts))
+		}
+		vector := make([]float32, len(d.Embedding))
+		for i, v := range d.Embedding {
+			vector[i] = float32(v)
+		}
+		vectors[d.Index] = vector
+	}
+	return vectors, nil
+}
+
+// HashEmbedder is a deterministic local embedder for tests and offline use.
+// It hashes the lowercased words of a text into a fixed-size vector, so texts
+// sharing words are similar; it does not capture meaning beyond that.
+type HashEmbedder struct {
+	// Dimensions defaults to DefaultHashDimensions.
+	Dimensions int
+}
+
+// Embed implements Embedder.
+func (e HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	dims := e.Dimensions
+	if dims <= 0 {
+		dims = DefaultHashDimensions
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, dims)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			sum := h.Sum64()
+			// The top bit picks the sign so that collisions tend to cancel out.
+			if sum>>63 == 0 {
+				vector[sum%uint64(dims)]++
+			} else {
+				vector[sum%uint64(dims)]--
+			}
+		}
+		normalize(vector)
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// normalize scales v to unit length in place.
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
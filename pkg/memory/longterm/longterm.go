@@ -0,0 +1,165 @@
+// Package longterm gives agents a semantic memory that outlives sessions.
+//
+// Facts are embedded by an Embedder and kept in a VectorStore; Memory ties the
+// two together and exposes "remember" and "recall" tools that an agent can
+// call to store facts and look them up by meaning.
+package longterm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultLimit is the number of matches returned when SearchOptions.Limit is unset.
+const DefaultLimit = 5
+
+// Record is a fact stored in a VectorStore.
+type Record struct {
+	ID       string
+	Text     string
+	Vector   []float32
+	Metadata map[string]string
+	// CreatedAt is when the fact was last remembered.
+	CreatedAt time.Time
+}
+
+// Match is a record found by VectorStore.Search.
+type Match struct {
+	Record
+	// Score is the cosine similarity between the record and the query, from -1 to 1.
+	Score float32
+}
+
+// SearchOptions narrows a VectorStore.Search.
+type SearchOptions struct {
+	// Limit is the maximum number of matches; defaults to DefaultLimit.
+	Limit int
+	// MinScore drops matches scoring below it.
+	MinScore float32
+	// Filter keeps only records whose metadata has all of these key/values.
+	Filter map[string]string
+}
+
+// VectorStore stores records and finds those closest to a vector.
+type VectorStore interface {
+	// Upsert adds records, replacing any with the same ID.
+	Upsert(ctx context.Context, records ...Record) error
+	// Search returns the records most similar to vector, best first.
+	Search(ctx context.Context, vector []float32, opts SearchOptions) ([]Match, error)
+	// Delete removes the records with the given IDs; unknown IDs are ignored.
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// Memory remembers and recalls facts by meaning.
+type Memory struct {
+	Embedder Embedder
+	Store    VectorStore
+}
+
+// New creates a Memory.
+func New(embedder Embedder, store VectorStore) *Memory {
+	return &Memory{Embedder: embedder, Store: store}
+}
+
+// Remember stores text with metadata and returns the stored record. The same
+// text with the same metadata is stored once.
+func (m *Memory) Remember(ctx context.Context, text string, metadata map[string]string) (Record, error) {
+	vector, err := m.embed(ctx, text)
+	if err != nil {
+		return Record{}, fmt.Errorf("embed memory: %w", err)
+	}
+	record := Record{
+		ID:        RecordID(text, metadata),
+		Text:      text,
+		Vector:    vector,
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	}
+	if err := m.Store.Upsert(ctx, record); err != nil {
+		return Record{}, fmt.Errorf("store memory: %w", err)
+	}
+	return record, nil
+}
+
+// Recall returns the stored facts closest in meaning to query.
+func (m *Memory) Recall(ctx context.Context, query string, opts SearchOptions) ([]Match, error) {
+	vector, err := m.embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	matches, err := m.Store.Search(ctx, vector, opts)
+	if err != nil {
+		return nil, fmt.Errorf("search memories: %w", err)
+	}
+	return matches, nil
+}
+
+// embed embeds a single text, checking that the embedder returned one
+// non-empty vector for it.
+func (m *Memory) embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := m.Embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 text", len(vectors))
+	}
+	if len(vectors[0]) == 0 {
+		return nil, fmt.Errorf("embedder returned an empty vector")
+	}
+	return vectors[0], nil
+}
+
+// Forget removes facts by ID.
+func (m *Memory) Forget(ctx context.Context, ids ...string) error {
+	if err := m.Store.Delete(ctx, ids...); err != nil {
+		return fmt.Errorf("delete memories: %w", err)
+	}
+	return nil
+}
+
+// RecordID derives a stable record ID from text and metadata.
+func RecordID(text string, metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	h.Write([]byte(text))
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00%s=%s", k, metadata[k])
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors of the same
+// length, or 0 if either is zero.
+func CosineSimilarity(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
+
+// matchesFilter reports whether metadata has all key/values of filter.
+func matchesFilter(metadata, filter map[string]string) bool {
+	for k, v := range filter {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+package longterm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultMemoriesTable is the default table name for SQLiteVectorStore records.
+const DefaultMemoriesTable = "agent_memories"
+
+// SQLiteVectorStoreConfig holds configuration for creating a new SQLiteVectorStore.
+type SQLiteVectorStoreConfig struct {
+	// DBPath is the path to the SQLite database file; defaults to ":memory:" for in-memory storage.
+	DBPath string
+	// Table is the table name for records; defaults to "agent_memories".
+	Table string
+}
+
+var _ VectorStore = (*SQLiteVectorStore)(nil)
+
+// SQLiteVectorStore is a VectorStore persisted to SQLite. Records are also
+// kept in memory and searched by brute force, which suits up to tens of
+// thousands of records.
+type SQLiteVectorStore struct {
+	db      *sql.DB
+	table   string
+	mu      sync.RWMutex
+	records map[string]Record
+}
+
+// NewSQLiteVectorStore opens the database, initializes the schema and loads
+// the stored records.
+func NewSQLiteVectorStore(ctx context.Context, config SQLiteVectorStoreConfig) (*SQLiteVectorStore, error) {
+	if config.DBPath == "" {
+		config.DBPath = memory.DefaultDBPath
+	}
+	if config.Table == "" {
+		config.Table = DefaultMemoriesTable
+	}
+
+	db, err := sql.Open("sqlite3", config.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", memory.ErrDatabaseOpen, err)
+	}
+	if config.DBPath == memory.DefaultDBPath {
+		// Every connection to ":memory:" opens a separate database.
+		db.SetMaxOpenConns(1)
+	}
+
+	s := &SQLiteVectorStore{db: db, table: config.Table, records: map[string]Record{}}
+	if err := s.initDB(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.load(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// initDB creates the records table if it does not exist.
+func (s *SQLiteVectorStore) initDB(ctx context.Context) error {
+	createTable := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id TEXT PRIMARY KEY,
+			text TEXT NOT NULL,
+			vector BLOB NOT NULL,
+			metadata TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL
+		)`, s.table)
+	if _, err := s.db.ExecContext(ctx, createTable); err != nil {
+		return fmt.Errorf("%w: %w", memory.ErrDatabaseInit, err)
+	}
+	return nil
+}
+
+// load reads all records into memory.
+func (s *SQLiteVectorStore) load(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, text, vector, metadata, created_at FROM %s`, s.table))
+	if err != nil {
+		return fmt.Errorf("%w: %w", memory.ErrOperationFailed, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r Record
+		var vector []byte
+		var metadata string
+		if err := rows.Scan(&r.ID, &r.Text, &vector, &metadata, &r.CreatedAt); err != nil {
+			return fmt.Errorf("%w: %w", memory.ErrOperationFailed, err)
+		}
+		r.Vector = decodeVector(vector)
+		if err := json.Unmarshal([]byte(metadata), &r.Metadata); err != nil {
+			return fmt.Errorf("%w: record %s: %w", memory.ErrInvalidItemData, r.ID, err)
+		}
+		s.records[r.ID] = r
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%w: %w", memory.ErrOperationFailed, err)
+	}
+	return nil
+}
+
+// Upsert implements VectorStore.
+func (s *SQLiteVectorStore) Upsert(ctx context.Context, records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", memory.ErrTransactionFailed, err)
+	}
+	defer tx.Rollback()
+
+	records = slices.Clone(records)
+	query := fmt.Sprintf(`INSERT OR REPLACE INTO %s (id, text, vector, metadata, created_at) VALUES (?, ?, ?, ?, ?)`, s.table)
+	for i := range records {
+		r := &records[i]
+		if r.ID == "" {
+			r.ID = RecordID(r.Text, r.Metadata)
+		}
+		if r.CreatedAt.IsZero() {
+			r.CreatedAt = time.Now()
+		}
+		metadata, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return fmt.Errorf("%w: %w", memory.ErrInvalidItemData, err)
+		}
+		if _, err := tx.ExecContext(ctx, query, r.ID, r.Text, encodeVector(r.Vector), string(metadata), r.CreatedAt); err != nil {
+			return fmt.Errorf("%w: %w", memory.ErrOperationFailed, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", memory.ErrTransactionFailed, err)
+	}
+
+	for _, r := range records {
+		s.records[r.ID] = r
+	}
+	return nil
+}
+
+// Search implements VectorStore. Records whose vectors have a different
+// dimension than the query, e.g. embedded by another model, are skipped.
+func (s *SQLiteVectorStore) Search(_ context.Context, vector []float32, opts SearchOptions) ([]Match, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []Match
+	for _, r := range s.records {
+		if !matchesFilter(r.Metadata, opts.Filter) {
+			continue
+		}
+		if len(r.Vector) != len(vector) {
+			continue
+		}
+		score := CosineSimilarity(vector, r.Vector)
+		if score < opts.MinScore {
+			continue
+		}
+		matches = append(matches, Match{Record: r, Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// Delete implements VectorStore.
+func (s *SQLiteVectorStore) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = ?`, s.table)
+	for _, id := range ids {
+		if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("%w: %w", memory.ErrOperationFailed, err)
+		}
+		delete(s.records, id)
+	}
+	return nil
+}
+
+// Len returns the number of stored records.
+func (s *SQLiteVectorStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Close closes the underlying database connection.
+func (s *SQLiteVectorStore) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%w: %w", memory.ErrDatabaseClose, err)
+	}
+	return nil
+}
+
+// encodeVector stores v as little-endian float32 values.
+func encodeVector(v []float32) []byte {
+	data := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(x))
+	}
+	return data
+}
+
+func decodeVector(data []byte) []float32 {
+	v := make([]float32, len(data)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return v
+}
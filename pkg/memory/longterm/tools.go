@@ -0,0 +1,65 @@
+package longterm
+
+import (
+	"context"
+
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+)
+
+// ToolOptions configures the tools returned by Memory.Tools.
+type ToolOptions struct {
+	// Scope is attached to remembered facts and required of recalled ones,
+	// e.g. {"user": "42"} to keep each user's memories apart.
+	Scope map[string]string
+	// Limit is the maximum number of facts recalled; defaults to DefaultLimit.
+	Limit int
+	// MinScore drops recalled facts scoring below it.
+	MinScore float32
+}
+
+type rememberArgs struct {
+	Fact string `json:"fact" jsonschema:"description=A self-contained fact worth remembering in later conversations"`
+}
+
+type recallArgs struct {
+	Query string `json:"query" jsonschema:"description=What to look up in memory"`
+}
+
+// RecalledFact is one result of the recall tool.
+type RecalledFact struct {
+	Fact  string  `json:"fact"`
+	Score float32 `json:"score"`
+}
+
+// Tools returns a "remember" tool that stores facts and a "recall" tool that
+// looks them up by meaning.
+func (m *Memory) Tools(opts ToolOptions) []tool.FunctionTool {
+	remember := tool.NewFunctionTool("remember",
+		"Store a fact in long-term memory so it can be recalled in later conversations.",
+		func(ctx context.Context, args rememberArgs) (string, error) {
+			if _, err := m.Remember(ctx, args.Fact, opts.Scope); err != nil {
+				return "", err
+			}
+			return "Remembered.", nil
+		})
+
+	recall := tool.NewFunctionTool("recall",
+		"Look up facts in long-term memory that relate to a query.",
+		func(ctx context.Context, args recallArgs) ([]RecalledFact, error) {
+			matches, err := m.Recall(ctx, args.Query, SearchOptions{
+				Limit:    opts.Limit,
+				MinScore: opts.MinScore,
+				Filter:   opts.Scope,
+			})
+			if err != nil {
+				return nil, err
+			}
+			facts := make([]RecalledFact, len(matches))
+			for i, match := range matches {
+				facts[i] = RecalledFact{Fact: match.Text, Score: match.Score}
+			}
+			return facts, nil
+		})
+
+	return []tool.FunctionTool{remember, recall}
+}
@@ -0,0 +1,265 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisKeyPrefix is the default prefix of the Redis keys holding session items.
+const DefaultRedisKeyPrefix = "agent_session:"
+
+// RedisSessionConfig holds configuration for creating a new RedisSession.
+type RedisSessionConfig struct {
+	// SessionID is the unique identifier for the session (required).
+	SessionID string
+	// Client is the Redis client to use (required). It is not closed by the session.
+	Client redis.UniversalClient
+	// KeyPrefix is prepended to SessionID to form the key of the item list;
+	// defaults to "agent_session:".
+	KeyPrefix string
+	// TTL expires the session after that long without AddItems; zero keeps it forever.
+	TTL time.Duration
+	// MaxItems trims the session to its most recent items on AddItems; zero
+	// keeps all. Function call outputs whose call is trimmed are trimmed too,
+	// so the session can hold fewer items than MaxItems.
+	MaxItems int
+}
+
+// Validate checks if the configuration is valid.
+func (c *RedisSessionConfig) Validate() error {
+	if c.SessionID == "" {
+		return ErrInvalidSessionID
+	}
+	if c.Client == nil {
+		return fmt.Errorf("%w: client is required", ErrDatabaseOpen)
+	}
+	return nil
+}
+
+var (
+	_ Session      = (*RedisSession)(nil)
+	_ ItemReplacer = (*RedisSession)(nil)
+)
+
+// RedisSession implements a session storage using a Redis list per session,
+// suited to ephemeral conversations.
+type RedisSession struct {
+	sessionID string
+	client    redis.UniversalClient
+	key       string
+	ttl       time.Duration
+	maxItems  int
+}
+
+// NewRedisSession creates a new RedisSession based on the provided configuration.
+func NewRedisSession(config RedisSessionConfig) (*RedisSession, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = DefaultRedisKeyPrefix
+	}
+	return &RedisSession{
+		sessionID: config.SessionID,
+		client:    config.Client,
+		key:       config.KeyPrefix + config.SessionID,
+		ttl:       config.TTL,
+		maxItems:  config.MaxItems,
+	}, nil
+}
+
+// SessionID returns the unique identifier for this session.
+func (s *RedisSession) SessionID() string {
+	return s.sessionID
+}
+
+// Key returns the Redis key of the session's item list.
+func (s *RedisSession) Key() string {
+	return s.key
+}
+
+// GetItems retrieves the conversation history items for this session.
+// If limit <= 0, all items are returned in chronological order (oldest first).
+// If limit > 0, the latest 'limit' items are returned in chronological order.
+func (s *RedisSession) GetItems(ctx context.Context, limit int) ([]responses.ResponseInputItemUnionParam, error) {
+	start := int64(0)
+	if limit > 0 {
+		start = -int64(limit)
+	}
+	values, err := s.client.LRange(ctx, s.key, start, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+
+	items := make([]responses.ResponseInputItemUnionParam, 0, len(values))
+	for _, value := range values {
+		item, err := DecodeItem([]byte(value))
+		if err != nil {
+			// Skip corrupted data, as SQLiteSession does.
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// AddItems appends new items to the conversation history in one transaction,
+// trimming the list to MaxItems and refreshing the TTL.
+func (s *RedisSession) AddItems(ctx context.Context, items []responses.ResponseInputItemUnionParam) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return s.push(ctx, items, false)
+}
+
+// ReplaceItems replaces the conversation history with items in one
+// transaction, trimming the list to MaxItems and refreshing the TTL.
+func (s *RedisSession) ReplaceItems(ctx context.Context, items []responses.ResponseInputItemUnionParam) error {
+	return s.push(ctx, items, true)
+}
+
+// redisTrimAttempts bounds how often push retries a trimming transaction
+// that lost a race with another writer.
+const redisTrimAttempts = 10
+
+// push appends items to the list in one transaction, after deleting it if
+// replace is set. With MaxItems, the list is watched while the trim point is
+// computed, and the transaction is retried if another writer changes it.
+func (s *RedisSession) push(ctx context.Context, items []responses.ResponseInputItemUnionParam, replace bool) error {
+	values, err := encodeValues(items)
+	if err != nil {
+		return err
+	}
+	if s.maxItems <= 0 || len(values) == 0 {
+		_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			s.write(ctx, pipe, values, replace, 0)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+		}
+		return nil
+	}
+
+	txf := func(tx *redis.Tx) error {
+		var stored []string
+		if !replace {
+			var err error
+			if stored, err = tx.LRange(ctx, s.key, 0, -1).Result(); err != nil {
+				return err
+			}
+		}
+		all := make([]responses.ResponseInputItemUnionParam, 0, len(stored)+len(items))
+		for _, value := range stored {
+			// Corrupted data never pairs with a call; keep its position.
+			item, _ := DecodeItem([]byte(value))
+			all = append(all, item)
+		}
+		all = append(all, items...)
+		start := trimPoint(all, len(all)-s.maxItems)
+
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			s.write(ctx, pipe, values, replace, int64(start))
+			return nil
+		})
+		return err
+	}
+	for range redisTrimAttempts {
+		err = s.client.Watch(ctx, txf, s.key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// write queues the commands of push on pipe, trimming the list to the items
+// from start on when MaxItems is set.
+func (s *RedisSession) write(ctx context.Context, pipe redis.Pipeliner, values []any, replace bool, start int64) {
+	if replace {
+		pipe.Del(ctx, s.key)
+	}
+	if len(values) == 0 {
+		return
+	}
+	pipe.RPush(ctx, s.key, values...)
+	if s.maxItems > 0 {
+		pipe.LTrim(ctx, s.key, start, -1)
+	}
+	if s.ttl > 0 {
+		pipe.Expire(ctx, s.key, s.ttl)
+	}
+}
+
+// trimPoint moves split forward until no function call output from split on
+// has its call before split. It is the counterpart of splitPoint for hard
+// limits, which cannot keep extra items.
+func trimPoint(items []responses.ResponseInputItemUnionParam, split int) int {
+	if split <= 0 {
+		return 0
+	}
+	callAt := map[string]int{}
+	for i, item := range items {
+		if item.OfFunctionCall != nil {
+			callAt[item.OfFunctionCall.CallID] = i
+		}
+	}
+	for i := split; i < len(items); i++ {
+		output := items[i].OfFunctionCallOutput
+		if output == nil {
+			continue
+		}
+		if at, ok := callAt[output.CallID]; ok && at < split {
+			split = i + 1
+		}
+	}
+	return split
+}
+
+// encodeValues encodes items as list values.
+func encodeValues(items []responses.ResponseInputItemUnionParam) ([]any, error) {
+	values := make([]any, len(items))
+	for i, item := range items {
+		data, err := EncodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = string(data)
+	}
+	return values, nil
+}
+
+// PopItem removes and returns the most recent item from the session.
+// If no items exist, it returns nil without error.
+func (s *RedisSession) PopItem(ctx context.Context) (*responses.ResponseInputItemUnionParam, error) {
+	value, err := s.client.RPop(ctx, s.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+
+	item, err := DecodeItem([]byte(value))
+	if err != nil {
+		// Corrupted data; treat as no item.
+		return nil, nil
+	}
+	return &item, nil
+}
+
+// ClearSession removes all items for this session.
+func (s *RedisSession) ClearSession(ctx context.Context) error {
+	if err := s.client.Del(ctx, s.key).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	return nil
+}
@@ -25,3 +25,10 @@ type Session interface {
 	// ClearSession clears all items for this session.
 	ClearSession(context.Context) error
 }
+
+// An ItemReplacer is a Session that can replace all of its items at once,
+// so that a failed replacement leaves the previous items in place.
+type ItemReplacer interface {
+	// ReplaceItems replaces the conversation history with items.
+	ReplaceItems(ctx context.Context, items []responses.ResponseInputItemUnionParam) error
+}
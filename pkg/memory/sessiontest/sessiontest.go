@@ -0,0 +1,377 @@
+// Package sessiontest provides a conformance suite for memory.Session
+// implementations, so that a custom backend can check it behaves like the
+// built-in ones.
+//
+//	func TestMySession(t *testing.T) {
+//		sessiontest.RunConformance(t, func(t *testing.T) memory.Session {
+//			return newMySession(t, uuid.NewString())
+//		})
+//	}
+package sessiontest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns a new, empty session. Sessions returned by successive calls
+// may share storage but must not share items. The factory registers any
+// cleanup with t.Cleanup.
+type Factory func(t *testing.T) memory.Session
+
+// RunConformance runs the conformance suite against sessions from newSession,
+// each check as a subtest.
+func RunConformance(t *testing.T, newSession Factory) {
+	t.Helper()
+	t.Run("Empty", func(t *testing.T) { testEmpty(t, newSession(t)) })
+	t.Run("Ordering", func(t *testing.T) { testOrdering(t, newSession(t)) })
+	t.Run("GetItemsLimit", func(t *testing.T) { testGetItemsLimit(t, newSession(t)) })
+	t.Run("PopItem", func(t *testing.T) { testPopItem(t, newSession(t)) })
+	t.Run("ClearSession", func(t *testing.T) { testClearSession(t, newSession(t)) })
+	t.Run("Isolation", func(t *testing.T) { testIsolation(t, newSession(t), newSession(t)) })
+	t.Run("ConcurrentAddItems", func(t *testing.T) { testConcurrentAddItems(t, newSession(t)) })
+	t.Run("ConcurrentPopItem", func(t *testing.T) { testConcurrentPopItem(t, newSession(t)) })
+	t.Run("RoundTrip", func(t *testing.T) { testRoundTrip(t, newSession(t)) })
+	t.Run("ReplaceItems", func(t *testing.T) { testReplaceItems(t, newSession(t), newSession(t)) })
+}
+
+func testEmpty(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	items, err := s.GetItems(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	items, err = s.GetItems(ctx, 5)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	item, err := s.PopItem(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, item, "PopItem on an empty session")
+
+	require.NoError(t, s.AddItems(ctx, nil), "AddItems with no items")
+	require.NoError(t, s.ClearSession(ctx), "ClearSession on an empty session")
+}
+
+func testOrdering(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	// Items added within the same second must keep their order.
+	want := texts("a", 20)
+	for _, batch := range [][]string{want[:1], want[1:10], want[10:]} {
+		require.NoError(t, s.AddItems(ctx, messages(batch)))
+	}
+	assertTexts(t, s, 0, want)
+}
+
+func testGetItemsLimit(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	all := texts("m", 5)
+	require.NoError(t, s.AddItems(ctx, messages(all)))
+
+	for _, tc := range []struct {
+		limit int
+		want  []string
+	}{
+		{limit: 0, want: all},
+		{limit: -1, want: all},
+		{limit: 1, want: all[4:]},
+		{limit: 3, want: all[2:]},
+		{limit: 5, want: all},
+		{limit: 50, want: all},
+	} {
+		t.Run(fmt.Sprintf("limit=%d", tc.limit), func(t *testing.T) {
+			assertTexts(t, s, tc.limit, tc.want)
+		})
+	}
+
+	// GetItems must not consume items.
+	assertTexts(t, s, 0, all)
+	_, err := s.GetItems(ctx, 2)
+	require.NoError(t, err)
+	assertTexts(t, s, 0, all)
+}
+
+func testPopItem(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	all := texts("p", 3)
+	require.NoError(t, s.AddItems(ctx, messages(all)))
+
+	for i := len(all) - 1; i >= 0; i-- {
+		item, err := s.PopItem(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, item)
+		assert.Equal(t, all[i], text(*item), "PopItem returns the most recent item")
+		assertTexts(t, s, 0, all[:i])
+	}
+	item, err := s.PopItem(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+
+	// The session stays usable after being emptied.
+	require.NoError(t, s.AddItems(ctx, messages([]string{"again"})))
+	assertTexts(t, s, 0, []string{"again"})
+}
+
+func testClearSession(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	require.NoError(t, s.AddItems(ctx, messages(texts("c", 4))))
+	require.NoError(t, s.ClearSession(ctx))
+	assertTexts(t, s, 0, nil)
+	item, err := s.PopItem(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+
+	// The session stays usable after being cleared.
+	require.NoError(t, s.AddItems(ctx, messages([]string{"after"})))
+	assertTexts(t, s, 0, []string{"after"})
+}
+
+// testReplaceItems checks sessions implementing memory.ItemReplacer.
+func testReplaceItems(t *testing.T, s, other memory.Session) {
+	replacer, ok := s.(memory.ItemReplacer)
+	if !ok {
+		t.Skipf("%T does not implement memory.ItemReplacer", s)
+	}
+	ctx := context.Background()
+	require.NoError(t, s.AddItems(ctx, messages(texts("old", 4))))
+	require.NoError(t, other.AddItems(ctx, messages([]string{"other"})))
+
+	require.NoError(t, replacer.ReplaceItems(ctx, messages([]string{"summary", "old-3"})))
+	assertTexts(t, s, 0, []string{"summary", "old-3"})
+	assertTexts(t, other, 0, []string{"other"})
+
+	// The session stays usable after a replacement.
+	require.NoError(t, s.AddItems(ctx, messages([]string{"new"})))
+	assertTexts(t, s, 0, []string{"summary", "old-3", "new"})
+
+	require.NoError(t, replacer.ReplaceItems(ctx, nil))
+	assertTexts(t, s, 0, nil)
+}
+
+func testIsolation(t *testing.T, a, b memory.Session) {
+	ctx := context.Background()
+	require.NoError(t, a.AddItems(ctx, messages([]string{"a1", "a2"})))
+	require.NoError(t, b.AddItems(ctx, messages([]string{"b1"})))
+	assertTexts(t, a, 0, []string{"a1", "a2"})
+	assertTexts(t, b, 0, []string{"b1"})
+
+	item, err := b.PopItem(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, "b1", text(*item))
+	require.NoError(t, b.ClearSession(ctx))
+	assertTexts(t, a, 0, []string{"a1", "a2"})
+}
+
+func testConcurrentAddItems(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	const writers, batches, batchSize = 8, 10, 3
+
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				assert.NoError(t, s.AddItems(ctx, messages(texts(fmt.Sprintf("w%d-b%d-", w, b), batchSize))))
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := s.GetItems(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, items, writers*batches*batchSize)
+
+	// Each batch is stored contiguously and each writer's batches in order.
+	next := map[string]int{}
+	for i := 0; i < len(items); i += batchSize {
+		var w, b int
+		_, err := fmt.Sscanf(text(items[i]), "w%d-b%d-0", &w, &b)
+		require.NoError(t, err, "item %d starts a batch", i)
+		assert.Equal(t, texts(fmt.Sprintf("w%d-b%d-", w, b), batchSize), textsOf(items[i:i+batchSize]))
+		writer := fmt.Sprint(w)
+		assert.Equal(t, next[writer], b, "batches of writer %d in order", w)
+		next[writer] = b + 1
+	}
+}
+
+func testConcurrentPopItem(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	all := texts("x", 40)
+	require.NoError(t, s.AddItems(ctx, messages(all)))
+
+	var mu sync.Mutex
+	popped := map[string]int{}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, err := s.PopItem(ctx)
+				if !assert.NoError(t, err) || item == nil {
+					return
+				}
+				mu.Lock()
+				popped[text(*item)]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, popped, len(all), "every item popped")
+	for text, n := range popped {
+		assert.Equal(t, 1, n, "%s popped once", text)
+	}
+	assertTexts(t, s, 0, nil)
+}
+
+func testRoundTrip(t *testing.T, s memory.Session) {
+	ctx := context.Background()
+	variants := itemVariants()
+	names := make([]string, 0, len(variants))
+	items := make([]responses.ResponseInputItemUnionParam, 0, len(variants))
+	for _, v := range variants {
+		names = append(names, v.name)
+		items = append(items, v.item)
+	}
+	require.NoError(t, s.AddItems(ctx, items))
+
+	stored, err := s.GetItems(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, stored, len(items), "every variant is stored")
+	for i, item := range items {
+		t.Run(names[i], func(t *testing.T) {
+			want, err := memory.EncodeItem(item)
+			require.NoError(t, err)
+			got, err := memory.EncodeItem(stored[i])
+			require.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+			assert.Equal(t, item.OfOutputMessage != nil, stored[i].OfOutputMessage != nil, "output message variant")
+			assert.Equal(t, item.OfFunctionCall != nil, stored[i].OfFunctionCall != nil, "function call variant")
+			assert.Equal(t, item.OfFunctionCallOutput != nil, stored[i].OfFunctionCallOutput != nil, "function call output variant")
+		})
+	}
+}
+
+type itemVariant struct {
+	name string
+	item responses.ResponseInputItemUnionParam
+}
+
+// itemVariants returns one item of every ResponseInputItemUnionParam variant.
+func itemVariants() []itemVariant {
+	inputContent := responses.ResponseInputMessageContentListParam{
+		{OfInputText: &responses.ResponseInputTextParam{Text: "describe this"}},
+		{OfInputImage: &responses.ResponseInputImageParam{ImageURL: param.NewOpt("https://example.com/cat.png"), Detail: responses.ResponseInputImageDetailLow}},
+	}
+	return []itemVariant{
+		{"Message", responses.ResponseInputItemParamOfMessage("hello", responses.EasyInputMessageRoleUser)},
+		{"MessageContentList", responses.ResponseInputItemParamOfMessage(inputContent, responses.EasyInputMessageRoleUser)},
+		{"SystemMessage", responses.ResponseInputItemParamOfMessage("be terse", responses.EasyInputMessageRoleSystem)},
+		{"AssistantMessage", responses.ResponseInputItemParamOfMessage("earlier answer", responses.EasyInputMessageRoleAssistant)},
+		{"InputMessage", responses.ResponseInputItemParamOfInputMessage(inputContent, "developer")},
+		{"OutputMessage", responses.ResponseInputItemParamOfOutputMessage([]responses.ResponseOutputMessageContentUnionParam{
+			{OfOutputText: &responses.ResponseOutputTextParam{Text: "answer", Annotations: []responses.ResponseOutputTextAnnotationUnionParam{}}},
+			{OfRefusal: &responses.ResponseOutputRefusalParam{Refusal: "no"}},
+		}, "msg_1", responses.ResponseOutputMessageStatusCompleted)},
+		{"FileSearchCall", responses.ResponseInputItemParamOfFileSearchCall("fs_1", []string{"q"}, responses.ResponseFileSearchToolCallStatusCompleted)},
+		{"ComputerCall", responses.ResponseInputItemUnionParam{OfComputerCall: &responses.ResponseComputerToolCallParam{
+			ID:                  "cu_1",
+			CallID:              "call_cu",
+			Action:              responses.ResponseComputerToolCallActionUnionParam{OfClick: &responses.ResponseComputerToolCallActionClickParam{Button: "left", X: 1, Y: 2}},
+			PendingSafetyChecks: []responses.ResponseComputerToolCallPendingSafetyCheckParam{},
+			Status:              responses.ResponseComputerToolCallStatusCompleted,
+			Type:                responses.ResponseComputerToolCallTypeComputerCall,
+		}}},
+		{"ComputerCallOutput", responses.ResponseInputItemParamOfComputerCallOutput("call_cu", responses.ResponseComputerToolCallOutputScreenshotParam{ImageURL: param.NewOpt("data:image/png;base64,AA==")})},
+		{"WebSearchCall", responses.ResponseInputItemParamOfWebSearchCall(responses.ResponseFunctionWebSearchActionSearchParam{Query: "weather"}, "ws_1", responses.ResponseFunctionWebSearchStatusCompleted)},
+		{"FunctionCall", responses.ResponseInputItemParamOfFunctionCall(`{"city":"Paris"}`, "call_fn", "get_weather")},
+		{"FunctionCallOutput", responses.ResponseInputItemParamOfFunctionCallOutput("call_fn", "sunny")},
+		{"Reasoning", responses.ResponseInputItemParamOfReasoning("rs_1", []responses.ResponseReasoningItemSummaryParam{{Text: "thinking"}})},
+		{"ImageGenerationCall", responses.ResponseInputItemParamOfImageGenerationCall("ig_1", "AA==", "completed")},
+		{"CodeInterpreterCall", responses.ResponseInputItemUnionParam{OfCodeInterpreterCall: &responses.ResponseCodeInterpreterToolCallParam{
+			ID:          "ci_1",
+			Code:        param.NewOpt("print(1)"),
+			ContainerID: "cntr_1",
+			Outputs: []responses.ResponseCodeInterpreterToolCallOutputUnionParam{
+				{OfLogs: &responses.ResponseCodeInterpreterToolCallOutputLogsParam{Logs: "1"}},
+			},
+			Status: responses.ResponseCodeInterpreterToolCallStatusCompleted,
+		}}},
+		{"LocalShellCall", responses.ResponseInputItemUnionParam{OfLocalShellCall: &responses.ResponseInputItemLocalShellCallParam{
+			ID:     "ls_1",
+			CallID: "call_ls",
+			Action: responses.ResponseInputItemLocalShellCallActionParam{Command: []string{"ls"}, Env: map[string]string{"HOME": "/"}},
+			Status: "completed",
+		}}},
+		{"LocalShellCallOutput", responses.ResponseInputItemParamOfLocalShellCallOutput("call_ls", "file.txt")},
+		{"McpListTools", responses.ResponseInputItemParamOfMcpListTools("ml_1", "docs", []responses.ResponseInputItemMcpListToolsToolParam{
+			{Name: "search", InputSchema: map[string]any{"type": "object"}},
+		})},
+		{"McpApprovalRequest", responses.ResponseInputItemUnionParam{OfMcpApprovalRequest: &responses.ResponseInputItemMcpApprovalRequestParam{
+			ID: "mr_1", Arguments: `{}`, Name: "delete", ServerLabel: "docs",
+		}}},
+		{"McpApprovalResponse", responses.ResponseInputItemParamOfMcpApprovalResponse("mr_1", true)},
+		{"McpCall", responses.ResponseInputItemUnionParam{OfMcpCall: &responses.ResponseInputItemMcpCallParam{
+			ID: "mc_1", Arguments: `{"q":"go"}`, Name: "search", ServerLabel: "docs", Output: param.NewOpt("found"),
+		}}},
+		{"CustomToolCall", responses.ResponseInputItemParamOfCustomToolCall("call_ct", "SELECT 1", "sql")},
+		{"CustomToolCallOutput", responses.ResponseInputItemParamOfCustomToolCallOutput("call_ct", "1")},
+		{"ItemReference", responses.ResponseInputItemParamOfItemReference("msg_0")},
+	}
+}
+
+// texts returns n distinct texts starting with prefix.
+func texts(prefix string, n int) []string {
+	out := make([]string, n)
+	for i := range out {
+		out[i] = fmt.Sprintf("%s%d", prefix, i)
+	}
+	return out
+}
+
+func messages(texts []string) []responses.ResponseInputItemUnionParam {
+	items := make([]responses.ResponseInputItemUnionParam, len(texts))
+	for i, text := range texts {
+		items[i] = responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleUser)
+	}
+	return items
+}
+
+// text returns the text of a message added by messages.
+func text(item responses.ResponseInputItemUnionParam) string {
+	if item.OfMessage == nil {
+		return ""
+	}
+	return item.OfMessage.Content.OfString.Value
+}
+
+func textsOf(items []responses.ResponseInputItemUnionParam) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = text(item)
+	}
+	return out
+}
+
+// assertTexts checks that GetItems(limit) returns messages with want texts.
+func assertTexts(t *testing.T, s memory.Session, limit int, want []string) {
+	t.Helper()
+	items, err := s.GetItems(context.Background(), limit)
+	require.NoError(t, err)
+	if want == nil {
+		want = []string{}
+	}
+	assert.Equal(t, want, textsOf(items))
+}
@@ -3,7 +3,6 @@ package memory
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -42,7 +41,10 @@ func (c *SQLiteSessionConfig) Validate() error {
 	return nil
 }
 
-var _ Session = (*SQLiteSession)(nil)
+var (
+	_ Session      = (*SQLiteSession)(nil)
+	_ ItemReplacer = (*SQLiteSession)(nil)
+)
 
 // SQLiteSession implements a session storage using SQLite.
 // It manages conversation history with thread-safety using a mutex.
@@ -53,7 +55,9 @@ type SQLiteSession struct {
 	sessionsTable string
 	messagesTable string
 	isMemoryDB    bool
-	mu            sync.Mutex
+	// sharedDB is set for sessions opened by a SessionStore, which owns db.
+	sharedDB bool
+	mu       sync.Mutex
 }
 
 // NewSQLiteSession creates and initializes a new SQLiteSession based on the provided configuration.
@@ -102,8 +106,13 @@ func NewSQLiteSession(ctx context.Context, config SQLiteSessionConfig) (*SQLiteS
 }
 
 // initDB initializes the database schema if it does not exist.
-// It creates tables for sessions and messages, along with necessary indexes.
 func (s *SQLiteSession) initDB(ctx context.Context) error {
+	return initSchema(ctx, s.db, s.sessionsTable, s.messagesTable)
+}
+
+// initSchema creates the tables for sessions and messages, along with
+// necessary indexes, if they do not exist.
+func initSchema(ctx context.Context, db *sql.DB, sessionsTable, messagesTable string) error {
 	// Use parameterized queries where possible, but table names require formatting.
 	// Assuming table names are trusted (set via config).
 	createSessionsTable := fmt.Sprintf(`
@@ -111,7 +120,7 @@ func (s *SQLiteSession) initDB(ctx context.Context) error {
 			session_id TEXT PRIMARY KEY,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`, s.sessionsTable)
+		)`, sessionsTable)
 
 	createMessagesTable := fmt.Sprintf(`
 		CREATE TABLE IF NOT EXISTS %s (
@@ -120,13 +129,13 @@ func (s *SQLiteSession) initDB(ctx context.Context) error {
 			message_data TEXT NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (session_id) REFERENCES %s (session_id) ON DELETE CASCADE
-		)`, s.messagesTable, s.sessionsTable)
+		)`, messagesTable, sessionsTable)
 
 	createIndex := fmt.Sprintf(`
 		CREATE INDEX IF NOT EXISTS idx_%s_session_id 
-		ON %s (session_id, created_at)`, s.messagesTable, s.messagesTable)
+		ON %s (session_id, created_at)`, messagesTable, messagesTable)
 
-	_, err := s.db.ExecContext(ctx, createSessionsTable+";"+createMessagesTable+";"+createIndex)
+	_, err := db.ExecContext(ctx, createSessionsTable+";"+createMessagesTable+";"+createIndex)
 	if err != nil {
 		return fmt.Errorf("%w: %w", ErrDatabaseInit, err)
 	}
@@ -164,10 +173,10 @@ func (s *SQLiteSession) GetItems(ctx context.Context, limit int) ([]responses.Re
 	var query string
 	var args []any
 	if limit > 0 {
-		query = fmt.Sprintf(`SELECT message_data FROM %s WHERE session_id = ? ORDER BY created_at DESC LIMIT ?`, s.messagesTable)
+		query = fmt.Sprintf(`SELECT message_data FROM %s WHERE session_id = ? ORDER BY id DESC LIMIT ?`, s.messagesTable)
 		args = []any{s.sessionID, limit}
 	} else {
-		query = fmt.Sprintf(`SELECT message_data FROM %s WHERE session_id = ? ORDER BY created_at ASC`, s.messagesTable)
+		query = fmt.Sprintf(`SELECT message_data FROM %s WHERE session_id = ? ORDER BY id ASC`, s.messagesTable)
 		args = []any{s.sessionID}
 	}
 
@@ -183,8 +192,8 @@ func (s *SQLiteSession) GetItems(ctx context.Context, limit int) ([]responses.Re
 		if err := rows.Scan(&messageData); err != nil {
 			return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
 		}
-		var item responses.ResponseInputItemUnionParam
-		if err := json.Unmarshal([]byte(messageData), &item); err != nil {
+		item, err := DecodeItem([]byte(messageData))
+		if err != nil {
 			// Log or handle invalid JSON; for now, skip.
 			continue
 		}
@@ -224,6 +233,43 @@ func (s *SQLiteSession) AddItems(ctx context.Context, items []responses.Response
 		}
 	}()
 
+	if err = s.insertItems(ctx, tx, items); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// ReplaceItems replaces the conversation history with items in one transaction.
+func (s *SQLiteSession) ReplaceItems(ctx context.Context, items []responses.ResponseInputItemUnionParam) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+	defer tx.Rollback()
+
+	deleteMessages := fmt.Sprintf(`DELETE FROM %s WHERE session_id = ?`, s.messagesTable)
+	if _, err := tx.ExecContext(ctx, deleteMessages, s.sessionID); err != nil {
+		return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	if err := s.insertItems(ctx, tx, items); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// insertItems appends items within tx and updates the session's timestamp.
+func (s *SQLiteSession) insertItems(ctx context.Context, tx *sql.Tx, items []responses.ResponseInputItemUnionParam) error {
 	// Prepare insert statement for messages.
 	insertQuery := fmt.Sprintf(`INSERT INTO %s (session_id, message_data) VALUES (?, ?)`, s.messagesTable)
 	stmt, err := tx.PrepareContext(ctx, insertQuery)
@@ -233,24 +279,24 @@ func (s *SQLiteSession) AddItems(ctx context.Context, items []responses.Response
 	defer stmt.Close()
 
 	for _, item := range items {
-		data, err := json.Marshal(item)
+		data, err := EncodeItem(item)
 		if err != nil {
-			return fmt.Errorf("%w: %w", ErrInvalidItemData, err)
+			return err
 		}
 		if _, err := stmt.ExecContext(ctx, s.sessionID, string(data)); err != nil {
 			return fmt.Errorf("%w: %w", ErrOperationFailed, err)
 		}
 	}
 
-	// Update session timestamp.
+	// Recreate the session entry if ClearSession removed it, then update its timestamp.
+	ensureQuery := fmt.Sprintf(`INSERT OR IGNORE INTO %s (session_id) VALUES (?)`, s.sessionsTable)
+	if _, err := tx.ExecContext(ctx, ensureQuery, s.sessionID); err != nil {
+		return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
 	updateQuery := fmt.Sprintf(`UPDATE %s SET updated_at = ? WHERE session_id = ?`, s.sessionsTable)
 	if _, err := tx.ExecContext(ctx, updateQuery, time.Now(), s.sessionID); err != nil {
 		return fmt.Errorf("%w: %w", ErrOperationFailed, err)
 	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
-	}
 	return nil
 }
 
@@ -267,7 +313,7 @@ func (s *SQLiteSession) PopItem(ctx context.Context) (*responses.ResponseInputIt
 		WHERE id = (
 			SELECT id FROM %s
 			WHERE session_id = ?
-			ORDER BY created_at DESC
+			ORDER BY id DESC
 			LIMIT 1
 		)
 		RETURNING message_data`, s.messagesTable, s.messagesTable)
@@ -280,8 +326,8 @@ func (s *SQLiteSession) PopItem(ctx context.Context) (*responses.ResponseInputIt
 		return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
 	}
 
-	var item responses.ResponseInputItemUnionParam
-	if err := json.Unmarshal([]byte(messageData), &item); err != nil {
+	item, err := DecodeItem([]byte(messageData))
+	if err != nil {
 		// Corrupted data; treat as no item.
 		return nil, nil
 	}
@@ -323,6 +369,8 @@ func (s *SQLiteSession) ClearSession(ctx context.Context) error {
 
 // Close closes the underlying database connection.
 // It should be called when the session is no longer needed.
+// For a session opened by a SessionStore, the connection stays open until
+// the store is closed.
 func (s *SQLiteSession) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -330,6 +378,10 @@ func (s *SQLiteSession) Close() error {
 	if s.db == nil {
 		return nil
 	}
+	if s.sharedDB {
+		s.db = nil
+		return nil
+	}
 	if err := s.db.Close(); err != nil {
 		return fmt.Errorf("%w: %w", ErrDatabaseClose, err)
 	}
@@ -0,0 +1,420 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// DefaultMetadataTable is the default table name for session metadata key/values.
+const DefaultMetadataTable = "agent_session_metadata"
+
+// SessionStoreConfig holds configuration for creating a new SessionStore.
+type SessionStoreConfig struct {
+	// DBPath is the path to the SQLite database file; defaults to ":memory:" for in-memory storage.
+	DBPath string
+	// SessionsTable is the table name for sessions; defaults to "agent_sessions".
+	SessionsTable string
+	// MessagesTable is the table name for message data; defaults to "agent_messages".
+	MessagesTable string
+	// MetadataTable is the table name for session metadata; defaults to "agent_session_metadata".
+	MetadataTable string
+}
+
+// SessionInfo describes a stored session.
+type SessionInfo struct {
+	SessionID string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// ItemCount is the number of items in the session.
+	ItemCount int
+}
+
+// ListSessionsOptions pages the result of ListSessions.
+type ListSessionsOptions struct {
+	// Limit is the maximum number of sessions to return. If <= 0, returns all.
+	Limit int
+	// Offset skips that many sessions.
+	Offset int
+}
+
+// SweeperOptions configures StartSweeper.
+type SweeperOptions struct {
+	// Interval is the time between sweeps; defaults to one minute.
+	Interval time.Duration
+	// TTL is passed to DeleteIdleSessions: sessions idle longer than this are deleted.
+	TTL time.Duration
+	// OnError is called when a sweep fails (optional). The sweep is retried on
+	// the next tick either way.
+	OnError func(error)
+}
+
+// SessionStore manages the sessions kept in a SQLite database with the
+// SQLiteSession schema: it lists, forks, renames and deletes them, stores
+// metadata per session and expires idle ones.
+type SessionStore struct {
+	db            *sql.DB
+	dbPath        string
+	sessionsTable string
+	messagesTable string
+	metadataTable string
+}
+
+// NewSessionStore opens the database and initializes the schema.
+func NewSessionStore(ctx context.Context, config SessionStoreConfig) (*SessionStore, error) {
+	if config.DBPath == "" {
+		config.DBPath = DefaultDBPath
+	}
+	if config.SessionsTable == "" {
+		config.SessionsTable = DefaultSessionsTable
+	}
+	if config.MessagesTable == "" {
+		config.MessagesTable = DefaultMessagesTable
+	}
+	if config.MetadataTable == "" {
+		config.MetadataTable = DefaultMetadataTable
+	}
+
+	db, err := sql.Open("sqlite3", config.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseOpen, err)
+	}
+	if config.DBPath == DefaultDBPath {
+		// Every connection to ":memory:" opens a separate database.
+		db.SetMaxOpenConns(1)
+	}
+
+	st := &SessionStore{
+		db:            db,
+		dbPath:        config.DBPath,
+		sessionsTable: config.SessionsTable,
+		messagesTable: config.MessagesTable,
+		metadataTable: config.MetadataTable,
+	}
+	if err := st.initDB(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// initDB creates the session tables and the metadata table if they do not exist.
+func (st *SessionStore) initDB(ctx context.Context) error {
+	if err := initSchema(ctx, st.db, st.sessionsTable, st.messagesTable); err != nil {
+		return err
+	}
+	createMetadataTable := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			session_id TEXT NOT NULL,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			PRIMARY KEY (session_id, key),
+			FOREIGN KEY (session_id) REFERENCES %s (session_id) ON DELETE CASCADE
+		)`, st.metadataTable, st.sessionsTable)
+	if _, err := st.db.ExecContext(ctx, createMetadataTable); err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabaseInit, err)
+	}
+	return nil
+}
+
+// Session opens the session with the given ID, creating it if needed. The
+// session shares the store's database connection.
+func (st *SessionStore) Session(ctx context.Context, sessionID string) (*SQLiteSession, error) {
+	if sessionID == "" {
+		return nil, ErrInvalidSessionID
+	}
+	s := &SQLiteSession{
+		sessionID:     sessionID,
+		db:            st.db,
+		sessionsTable: st.sessionsTable,
+		messagesTable: st.messagesTable,
+		isMemoryDB:    st.dbPath == DefaultDBPath,
+		sharedDB:      true,
+	}
+	if err := s.ensureSessionExists(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSessions returns sessions ordered by most recently updated first.
+func (st *SessionStore) ListSessions(ctx context.Context, opts ListSessionsOptions) ([]SessionInfo, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	query := fmt.Sprintf(`
+		SELECT s.session_id, s.created_at, s.updated_at,
+			(SELECT COUNT(*) FROM %s m WHERE m.session_id = s.session_id)
+		FROM %s s
+		ORDER BY julianday(s.updated_at) DESC, s.updated_at DESC, s.session_id
+		LIMIT ? OFFSET ?`, st.messagesTable, st.sessionsTable)
+
+	rows, err := st.db.QueryContext(ctx, query, limit, max(opts.Offset, 0))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	defer rows.Close()
+
+	var sessions []SessionInfo
+	for rows.Next() {
+		var info SessionInfo
+		if err := rows.Scan(&info.SessionID, &info.CreatedAt, &info.UpdatedAt, &info.ItemCount); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+		}
+		sessions = append(sessions, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	return sessions, nil
+}
+
+// ForkSession copies the first upTo items of the session fromID, along with
+// its metadata, into a new session toID. If upTo <= 0, all items are copied.
+func (st *SessionStore) ForkSession(ctx context.Context, fromID, toID string, upTo int) error {
+	if fromID == "" || toID == "" {
+		return ErrInvalidSessionID
+	}
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		if err := st.requireSession(ctx, tx, fromID); err != nil {
+			return err
+		}
+		if err := st.createSession(ctx, tx, toID); err != nil {
+			return err
+		}
+
+		limit := upTo
+		if limit <= 0 {
+			limit = -1
+		}
+		copyMessages := fmt.Sprintf(`
+			INSERT INTO %s (session_id, message_data, created_at)
+			SELECT ?, message_data, created_at FROM %s
+			WHERE session_id = ?
+			ORDER BY id
+			LIMIT ?`, st.messagesTable, st.messagesTable)
+		if _, err := tx.ExecContext(ctx, copyMessages, toID, fromID, limit); err != nil {
+			return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+		}
+
+		copyMetadata := fmt.Sprintf(`
+			INSERT INTO %s (session_id, key, value)
+			SELECT ?, key, value FROM %s WHERE session_id = ?`, st.metadataTable, st.metadataTable)
+		if _, err := tx.ExecContext(ctx, copyMetadata, toID, fromID); err != nil {
+			return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+		}
+		return nil
+	})
+}
+
+// RenameSession moves the items and metadata of the session oldID to the new
+// session newID, which must not exist yet. Sessions already opened for oldID
+// keep writing to oldID.
+func (st *SessionStore) RenameSession(ctx context.Context, oldID, newID string) error {
+	if oldID == "" || newID == "" {
+		return ErrInvalidSessionID
+	}
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		if err := st.requireSession(ctx, tx, oldID); err != nil {
+			return err
+		}
+		if err := st.createSession(ctx, tx, newID); err != nil {
+			return err
+		}
+		queries := []string{
+			fmt.Sprintf(`UPDATE %s SET created_at = (SELECT created_at FROM %s WHERE session_id = ?),
+				updated_at = (SELECT updated_at FROM %s WHERE session_id = ?) WHERE session_id = ?`,
+				st.sessionsTable, st.sessionsTable, st.sessionsTable),
+			fmt.Sprintf(`UPDATE %s SET session_id = ? WHERE session_id = ?`, st.messagesTable),
+			fmt.Sprintf(`UPDATE %s SET session_id = ? WHERE session_id = ?`, st.metadataTable),
+			fmt.Sprintf(`DELETE FROM %s WHERE session_id = ?`, st.sessionsTable),
+		}
+		args := [][]any{{oldID, oldID, newID}, {newID, oldID}, {newID, oldID}, {oldID}}
+		for i, query := range queries {
+			if _, err := tx.ExecContext(ctx, query, args[i]...); err != nil {
+				return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteSession removes a session with its items and metadata. Deleting a
+// session that does not exist is not an error.
+func (st *SessionStore) DeleteSession(ctx context.Context, sessionID string) error {
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := st.deleteSessions(ctx, tx, "session_id = ?", sessionID)
+		return err
+	})
+}
+
+// DeleteIdleSessions removes the sessions that have not been updated for
+// longer than ttl and returns how many were removed.
+func (st *SessionStore) DeleteIdleSessions(ctx context.Context, ttl time.Duration) (int, error) {
+	cutoff := time.Now().Add(-ttl).UTC()
+	var deleted int
+	err := st.inTx(ctx, func(tx *sql.Tx) error {
+		n, err := st.deleteSessions(ctx, tx, "julianday(updated_at) < julianday(?)", cutoff)
+		deleted = n
+		return err
+	})
+	return deleted, err
+}
+
+// StartSweeper calls DeleteIdleSessions with opts.TTL every opts.Interval
+// until ctx is done or the returned stop function is called. Failed sweeps are
+// reported to opts.OnError and retried on the next tick.
+func (st *SessionStore) StartSweeper(ctx context.Context, opts SweeperOptions) (stop func()) {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// A sweep cut short by stop is not a failure.
+				if _, err := st.DeleteIdleSessions(ctx, opts.TTL); err != nil && ctx.Err() == nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// SetMetadata sets a metadata value of an existing session.
+func (st *SessionStore) SetMetadata(ctx context.Context, sessionID, key, value string) error {
+	return st.inTx(ctx, func(tx *sql.Tx) error {
+		if err := st.requireSession(ctx, tx, sessionID); err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`INSERT OR REPLACE INTO %s (session_id, key, value) VALUES (?, ?, ?)`, st.metadataTable)
+		if _, err := tx.ExecContext(ctx, query, sessionID, key, value); err != nil {
+			return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+		}
+		return nil
+	})
+}
+
+// Metadata returns all metadata of a session. A session without metadata
+// yields an empty map.
+func (st *SessionStore) Metadata(ctx context.Context, sessionID string) (map[string]string, error) {
+	query := fmt.Sprintf(`SELECT key, value FROM %s WHERE session_id = ?`, st.metadataTable)
+	rows, err := st.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	defer rows.Close()
+
+	metadata := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+		}
+		metadata[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	return metadata, nil
+}
+
+// DeleteMetadata removes a metadata key of a session.
+func (st *SessionStore) DeleteMetadata(ctx context.Context, sessionID, key string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE session_id = ? AND key = ?`, st.metadataTable)
+	if _, err := st.db.ExecContext(ctx, query, sessionID, key); err != nil {
+		return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	return nil
+}
+
+// Close closes the underlying database connection, including for the
+// sessions opened by the store.
+func (st *SessionStore) Close() error {
+	if err := st.db.Close(); err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabaseClose, err)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (st *SessionStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := st.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w", ErrTransactionFailed, err)
+	}
+	return nil
+}
+
+// requireSession returns ErrSessionNotFound if the session does not exist.
+func (st *SessionStore) requireSession(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	var exists int
+	query := fmt.Sprintf(`SELECT 1 FROM %s WHERE session_id = ?`, st.sessionsTable)
+	err := tx.QueryRowContext(ctx, query, sessionID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	return nil
+}
+
+// createSession inserts a new session entry, returning ErrSessionExists if
+// the session already exists.
+func (st *SessionStore) createSession(ctx context.Context, tx *sql.Tx, sessionID string) error {
+	query := fmt.Sprintf(`INSERT OR IGNORE INTO %s (session_id) VALUES (?)`, st.sessionsTable)
+	res, err := tx.ExecContext(ctx, query, sessionID)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrSessionExists, sessionID)
+	}
+	return nil
+}
+
+// deleteSessions removes the sessions matching where, with their items and
+// metadata, and returns how many sessions were removed.
+func (st *SessionStore) deleteSessions(ctx context.Context, tx *sql.Tx, where string, args ...any) (int, error) {
+	selected := fmt.Sprintf(`SELECT session_id FROM %s WHERE %s`, st.sessionsTable, where)
+	for _, table := range []string{st.messagesTable, st.metadataTable} {
+		query := fmt.Sprintf(`DELETE FROM %s WHERE session_id IN (%s)`, table, selected)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s`, st.sessionsTable, where), args...)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrOperationFailed, err)
+	}
+	return int(n), nil
+}
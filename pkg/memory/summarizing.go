@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chuanbosi666/agent_go/pkg/history"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+const (
+	// DefaultSummaryPrefix starts the summary message of a SummarizingSession.
+	DefaultSummaryPrefix = "Summary of the earlier conversation:\n"
+	// DefaultKeepItems is how many recent items a SummarizingSession keeps
+	// verbatim when it has only a token threshold.
+	DefaultKeepItems = 10
+)
+
+// Summarizer condenses conversation items into a summary text.
+type Summarizer interface {
+	Summarize(ctx context.Context, items []responses.ResponseInputItemUnionParam) (string, error)
+}
+
+// SummarizerFunc adapts a function to Summarizer.
+type SummarizerFunc func(ctx context.Context, items []responses.ResponseInputItemUnionParam) (string, error)
+
+// Summarize implements Summarizer.
+func (f SummarizerFunc) Summarize(ctx context.Context, items []responses.ResponseInputItemUnionParam) (string, error) {
+	return f(ctx, items)
+}
+
+// SummarizingSessionConfig holds configuration for a SummarizingSession.
+type SummarizingSessionConfig struct {
+	// Summarizer writes the summaries (required), e.g. a pattern.AgentSummarizer.
+	Summarizer Summarizer
+	// MaxItems compacts the session once it holds more items (optional).
+	MaxItems int
+	// MaxTokens compacts the session once its items are estimated to take
+	// more tokens (optional).
+	MaxTokens int
+	// Counter estimates tokens for MaxTokens; nil uses history.ApproxTokenCounter.
+	Counter history.TokenCounter
+	// KeepItems is how many recent items are kept verbatim when compacting;
+	// defaults to half of MaxItems, or DefaultKeepItems without MaxItems.
+	KeepItems int
+	// SummaryPrefix starts the summary message; defaults to DefaultSummaryPrefix.
+	SummaryPrefix string
+	// OnError is called when compacting after AddItems fails (optional). The
+	// added items are stored either way, so AddItems does not return the error.
+	OnError func(error)
+}
+
+var (
+	_ Session      = (*SummarizingSession)(nil)
+	_ ItemReplacer = (*SummarizingSession)(nil)
+)
+
+// SummarizingSession wraps a Session and, once it grows past MaxItems or
+// MaxTokens, replaces its oldest items with a single system message holding
+// their summary. A function call and its output are always summarized or
+// kept together.
+//
+// Compaction replaces the wrapped session's items in one ReplaceItems call,
+// so the wrapped session should not be written to by anything else meanwhile.
+type SummarizingSession struct {
+	Session
+	replacer ItemReplacer
+	config   SummarizingSessionConfig
+	mu       sync.Mutex
+}
+
+// NewSummarizingSession wraps session with summarization as configured.
+// The session must implement ItemReplacer, as all sessions of this package do.
+func NewSummarizingSession(session Session, config SummarizingSessionConfig) (*SummarizingSession, error) {
+	if config.Summarizer == nil {
+		return nil, fmt.Errorf("summarizing session: summarizer is required")
+	}
+	replacer, ok := session.(ItemReplacer)
+	if !ok {
+		return nil, fmt.Errorf("summarizing session: %T does not implement ItemReplacer", session)
+	}
+	if config.KeepItems <= 0 {
+		config.KeepItems = DefaultKeepItems
+		if config.MaxItems > 0 {
+			config.KeepItems = max(config.MaxItems/2, 1)
+		}
+	}
+	if config.SummaryPrefix == "" {
+		config.SummaryPrefix = DefaultSummaryPrefix
+	}
+	return &SummarizingSession{Session: session, replacer: replacer, config: config}, nil
+}
+
+// AddItems adds items to the wrapped session, then compacts it if it has
+// grown past a threshold. Only a failure to add the items is returned;
+// compaction failures go to OnError and leave the session uncompacted.
+func (s *SummarizingSession) AddItems(ctx context.Context, items []responses.ResponseInputItemUnionParam) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.Session.AddItems(ctx, items); err != nil {
+		return err
+	}
+	stored, err := s.Session.GetItems(ctx, -1)
+	if err == nil && s.overThreshold(stored) {
+		err = s.compact(ctx, stored)
+	}
+	if err != nil && s.config.OnError != nil {
+		s.config.OnError(err)
+	}
+	return nil
+}
+
+// ReplaceItems replaces the wrapped session's items without compacting them.
+func (s *SummarizingSession) ReplaceItems(ctx context.Context, items []responses.ResponseInputItemUnionParam) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replacer.ReplaceItems(ctx, items)
+}
+
+// Compact summarizes all but the most recent KeepItems items now,
+// regardless of the thresholds.
+func (s *SummarizingSession) Compact(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, err := s.Session.GetItems(ctx, -1)
+	if err != nil {
+		return err
+	}
+	return s.compact(ctx, stored)
+}
+
+func (s *SummarizingSession) overThreshold(items []responses.ResponseInputItemUnionParam) bool {
+	if s.config.MaxItems > 0 && len(items) > s.config.MaxItems {
+		return true
+	}
+	return s.config.MaxTokens > 0 && history.CountTokens(s.config.Counter, items) > s.config.MaxTokens
+}
+
+func (s *SummarizingSession) compact(ctx context.Context, items []responses.ResponseInputItemUnionParam) error {
+	split := splitPoint(items, len(items)-s.config.KeepItems)
+	// A lone previous summary is not worth summarizing again.
+	if split <= 0 || split == 1 && s.isSummary(items[0]) {
+		return nil
+	}
+
+	summary, err := s.config.Summarizer.Summarize(ctx, items[:split])
+	if err != nil {
+		return fmt.Errorf("summarize session: %w", err)
+	}
+	compacted := append([]responses.ResponseInputItemUnionParam{
+		responses.ResponseInputItemParamOfMessage(s.config.SummaryPrefix+summary, responses.EasyInputMessageRoleSystem),
+	}, items[split:]...)
+
+	if err := s.replacer.ReplaceItems(ctx, compacted); err != nil {
+		return fmt.Errorf("summarize session: %w", err)
+	}
+	return nil
+}
+
+// isSummary reports whether item is a summary message written by s.
+func (s *SummarizingSession) isSummary(item responses.ResponseInputItemUnionParam) bool {
+	msg := item.OfMessage
+	if msg == nil || msg.Role != responses.EasyInputMessageRoleSystem {
+		return false
+	}
+	return strings.HasPrefix(msg.Content.OfString.Value, s.config.SummaryPrefix)
+}
+
+// splitPoint moves split back until no function call is separated from its
+// output by it.
+func splitPoint(items []responses.ResponseInputItemUnionParam, split int) int {
+	if split <= 0 {
+		return 0
+	}
+	callAt := map[string]int{}
+	for i, item := range items {
+		if item.OfFunctionCall != nil {
+			callAt[item.OfFunctionCall.CallID] = i
+		}
+	}
+	for i := len(items) - 1; i >= 0 && split > 0; i-- {
+		output := items[i].OfFunctionCallOutput
+		if i < split || output == nil {
+			continue
+		}
+		if at, ok := callAt[output.CallID]; ok && at < split {
+			split = at
+		}
+	}
+	return split
+}
@@ -0,0 +1,315 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// ToolsToChatParams converts tools to Chat Completions API tool definitions.
+func ToolsToChatParams(tools []tool.Tool) []openai.ChatCompletionToolUnionParam {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	params := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		funcTool, ok := t.(tool.FunctionTool)
+		if !ok {
+			continue
+		}
+		params = append(params, openai.ChatCompletionFunctionTool(shared.FunctionDefinitionParam{
+			Name:        funcTool.Name,
+			Description: param.NewOpt(funcTool.Description),
+			Parameters:  shared.FunctionParameters(funcTool.ParamsJSONSchema),
+			Strict:      funcTool.StrictJSONSchema,
+		}))
+	}
+	return params
+}
+
+// ItemsToChatMessages converts Responses API input items, which is what
+// memory.Session stores, into Chat Completions messages.
+//
+// Function calls are folded into the preceding assistant message so that every
+// tool message follows the assistant message that requested it. Items with no
+// Chat Completions equivalent (reasoning, hosted tool calls, ...) are skipped.
+func ItemsToChatMessages(items []responses.ResponseInputItemUnionParam) []openai.ChatCompletionMessageParamUnion {
+	var messages []openai.ChatCompletionMessageParamUnion
+	for _, item := range items {
+		switch {
+		case item.OfMessage != nil:
+			content := item.OfMessage.Content
+			if content.OfInputItemContentList != nil {
+				messages = append(messages, contentListMessage(string(item.OfMessage.Role), content.OfInputItemContentList))
+			} else {
+				messages = append(messages, roleMessage(string(item.OfMessage.Role), content.OfString.Value))
+			}
+		case item.OfInputMessage != nil:
+			messages = append(messages, contentListMessage(item.OfInputMessage.Role, item.OfInputMessage.Content))
+		case item.OfOutputMessage != nil:
+			messages = append(messages, outputMessageToChat(item.OfOutputMessage))
+		case item.OfFunctionCall != nil:
+			call := openai.ChatCompletionMessageToolCallUnionParam{
+				OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+					ID: item.OfFunctionCall.CallID,
+					Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+						Name:      item.OfFunctionCall.Name,
+						Arguments: item.OfFunctionCall.Arguments,
+					},
+				},
+			}
+			if n := len(messages); n > 0 && messages[n-1].OfAssistant != nil {
+				messages[n-1].OfAssistant.ToolCalls = append(messages[n-1].OfAssistant.ToolCalls, call)
+				continue
+			}
+			messages = append(messages, openai.ChatCompletionMessageParamUnion{
+				OfAssistant: &openai.ChatCompletionAssistantMessageParam{
+					ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{call},
+				},
+			})
+		case item.OfFunctionCallOutput != nil:
+			messages = append(messages, openai.ToolMessage(
+				functionCallOutputText(item.OfFunctionCallOutput.Output),
+				item.OfFunctionCallOutput.CallID,
+			))
+		}
+	}
+	return messages
+}
+
+// ChatMessagesToItems converts Chat Completions messages into Responses API
+// input items, e.g. to seed a memory.Session with an existing transcript.
+// It is the inverse of ItemsToChatMessages.
+func ChatMessagesToItems(messages []openai.ChatCompletionMessageParamUnion) []responses.ResponseInputItemUnionParam {
+	var items []responses.ResponseInputItemUnionParam
+	for _, m := range messages {
+		switch {
+		case m.OfSystem != nil:
+			text := m.OfSystem.Content.OfString.Value
+			if m.OfSystem.Content.OfArrayOfContentParts != nil {
+				text = textPartsText(m.OfSystem.Content.OfArrayOfContentParts)
+			}
+			items = append(items, responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleSystem))
+		case m.OfDeveloper != nil:
+			text := m.OfDeveloper.Content.OfString.Value
+			if m.OfDeveloper.Content.OfArrayOfContentParts != nil {
+				text = textPartsText(m.OfDeveloper.Content.OfArrayOfContentParts)
+			}
+			items = append(items, responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleDeveloper))
+		case m.OfUser != nil:
+			if parts := m.OfUser.Content.OfArrayOfContentParts; parts != nil {
+				items = append(items, responses.ResponseInputItemParamOfMessage(
+					chatPartsToInputContent(parts),
+					responses.EasyInputMessageRoleUser,
+				))
+				continue
+			}
+			items = append(items, responses.ResponseInputItemParamOfMessage(
+				m.OfUser.Content.OfString.Value,
+				responses.EasyInputMessageRoleUser,
+			))
+		case m.OfAssistant != nil:
+			text := m.OfAssistant.Content.OfString.Value
+			for _, part := range m.OfAssistant.Content.OfArrayOfContentParts {
+				if part.OfText != nil {
+					text += part.OfText.Text
+				}
+			}
+			if text != "" {
+				items = append(items, responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleAssistant))
+			}
+			for _, tc := range m.OfAssistant.ToolCalls {
+				if tc.OfFunction == nil {
+					continue
+				}
+				items = append(items, responses.ResponseInputItemParamOfFunctionCall(
+					tc.OfFunction.Function.Arguments,
+					tc.OfFunction.ID,
+					tc.OfFunction.Function.Name,
+				))
+			}
+		case m.OfTool != nil:
+			output := m.OfTool.Content.OfString.Value
+			if m.OfTool.Content.OfArrayOfContentParts != nil {
+				output = textPartsText(m.OfTool.Content.OfArrayOfContentParts)
+			}
+			items = append(items, responses.ResponseInputItemParamOfFunctionCallOutput(m.OfTool.ToolCallID, output))
+		}
+	}
+	return items
+}
+
+// roleMessage builds a Chat Completions text message for the given role.
+func roleMessage(role, text string) openai.ChatCompletionMessageParamUnion {
+	switch role {
+	case "assistant":
+		return openai.AssistantMessage(text)
+	case "system":
+		return openai.SystemMessage(text)
+	case "developer":
+		return openai.DeveloperMessage(text)
+	default:
+		return openai.UserMessage(text)
+	}
+}
+
+// contentListMessage builds a Chat Completions message from input content parts.
+// Only user messages can carry images; other roles receive the text parts.
+func contentListMessage(role string, content responses.ResponseInputMessageContentListParam) openai.ChatCompletionMessageParamUnion {
+	if role != "user" && role != "" {
+		return roleMessage(role, inputContentText(content))
+	}
+
+	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(content))
+	for _, c := range content {
+		switch {
+		case c.OfInputText != nil:
+			parts = append(parts, openai.TextContentPart(c.OfInputText.Text))
+		case c.OfInputImage != nil && c.OfInputImage.ImageURL.Valid():
+			parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL:    c.OfInputImage.ImageURL.Value,
+				Detail: string(c.OfInputImage.Detail),
+			}))
+		}
+	}
+	return openai.UserMessage(parts)
+}
+
+// chatPartsToInputContent converts Chat Completions user content parts into Responses input content.
+func chatPartsToInputContent(parts []openai.ChatCompletionContentPartUnionParam) responses.ResponseInputMessageContentListParam {
+	content := make(responses.ResponseInputMessageContentListParam, 0, len(parts))
+	for _, p := range parts {
+		switch {
+		case p.OfText != nil:
+			content = append(content, responses.ResponseInputContentUnionParam{
+				OfInputText: &responses.ResponseInputTextParam{Text: p.OfText.Text},
+			})
+		case p.OfImageURL != nil:
+			detail := responses.ResponseInputImageDetail(p.OfImageURL.ImageURL.Detail)
+			if detail == "" {
+				detail = responses.ResponseInputImageDetailAuto
+			}
+			content = append(content, responses.ResponseInputContentUnionParam{
+				OfInputImage: &responses.ResponseInputImageParam{
+					ImageURL: param.NewOpt(p.OfImageURL.ImageURL.URL),
+					Detail:   detail,
+				},
+			})
+		}
+	}
+	return content
+}
+
+// outputMessageToChat converts a stored model output message into an assistant message.
+func outputMessageToChat(msg *responses.ResponseOutputMessageParam) openai.ChatCompletionMessageParamUnion {
+	var texts, refusals []string
+	for _, part := range msg.Content {
+		switch {
+		case part.OfOutputText != nil:
+			texts = append(texts, part.OfOutputText.Text)
+		case part.OfRefusal != nil:
+			refusals = append(refusals, part.OfRefusal.Refusal)
+		}
+	}
+	assistant := openai.ChatCompletionAssistantMessageParam{}
+	if len(texts) > 0 {
+		assistant.Content.OfString = param.NewOpt(strings.Join(texts, "\n"))
+	}
+	if len(refusals) > 0 {
+		assistant.Refusal = param.NewOpt(strings.Join(refusals, "\n"))
+	}
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+}
+
+// functionCallOutputText returns the text of a function call output.
+func functionCallOutputText(output responses.ResponseInputItemFunctionCallOutputOutputUnionParam) string {
+	if output.OfString.Valid() {
+		return output.OfString.Value
+	}
+	var texts []string
+	for _, part := range output.OfResponseFunctionCallOutputItemArray {
+		if part.OfInputText != nil {
+			texts = append(texts, part.OfInputText.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// inputContentText joins the text parts of an input content list.
+func inputContentText(content responses.ResponseInputMessageContentListParam) string {
+	var texts []string
+	for _, part := range content {
+		if part.OfInputText != nil {
+			texts = append(texts, part.OfInputText.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// textPartsText joins Chat Completions text content parts.
+func textPartsText(parts []openai.ChatCompletionContentPartTextParam) string {
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		texts = append(texts, p.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// chatCompletionToOutput converts a Chat Completions response into Responses API
+// output items, so that both API paths share the same tool-calling loop.
+func chatCompletionToOutput(resp *openai.ChatCompletion) ([]responses.ResponseOutputItemUnion, error) {
+	if len(resp.Choices) == 0 {
+		return nil, nil
+	}
+	message := resp.Choices[0].Message
+
+	var rawItems []map[string]any
+	if message.Content != "" {
+		rawItems = append(rawItems, map[string]any{
+			"id":     resp.ID,
+			"type":   "message",
+			"role":   "assistant",
+			"status": "completed",
+			"content": []map[string]any{{
+				"type":        "output_text",
+				"text":        message.Content,
+				"annotations": []any{},
+			}},
+		})
+	}
+	for _, tc := range message.ToolCalls {
+		if tc.Type == "custom" {
+			continue
+		}
+		rawItems = append(rawItems, map[string]any{
+			"id":        tc.ID,
+			"type":      "function_call",
+			"call_id":   tc.ID,
+			"name":      tc.Function.Name,
+			"arguments": tc.Function.Arguments,
+			"status":    "completed",
+		})
+	}
+
+	output := make([]responses.ResponseOutputItemUnion, 0, len(rawItems))
+	for _, raw := range rawItems {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("marshal chat output item: %w", err)
+		}
+		var item responses.ResponseOutputItemUnion
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("unmarshal chat output item: %w", err)
+		}
+		output = append(output, item)
+	}
+	return output, nil
+}
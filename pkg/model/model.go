@@ -0,0 +1,117 @@
+// Package model defines how the runner talks to language models, so that any
+// provider can back an agent.
+//
+// A Model turns a Request (instructions, conversation items, tools and
+// settings) into a Response made of Responses API output items, which is the
+// shape the runner's tool-calling loop works on. OpenAIResponsesModel and
+// OpenAIChatCompletionsModel implement it for OpenAI and OpenAI-compatible
+// servers; other providers plug in by implementing Model and Provider.
+package model
+
+import (
+	"context"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// Model is a language model the runner can call. Implementations must be
+// safe for concurrent use.
+type Model interface {
+	// GetResponse returns the model's response to req.
+	GetResponse(ctx context.Context, req Request) (*Response, error)
+	// StreamResponse is GetResponse in streaming mode: emit receives events
+	// while the response is generated, and the completed response is returned.
+	StreamResponse(ctx context.Context, req Request, emit func(StreamEvent)) (*Response, error)
+}
+
+// Provider looks up models by name.
+type Provider interface {
+	GetModel(name string) (Model, error)
+}
+
+// Request is a single call to a model.
+type Request struct {
+	// Instructions is the system prompt (optional).
+	Instructions string
+	// Input is the conversation so far.
+	Input []responses.ResponseInputItemUnionParam
+	// Tools are the tools the model may call. Models only support the tool
+	// kinds they know about, usually tool.FunctionTool.
+	Tools    []tool.Tool
+	Settings agent.ModelSettings
+	// OutputSchema is the JSON schema the answer must follow, or nil for text.
+	OutputSchema *OutputSchema
+	// Prompt is the stored prompt of the agent, for models that support
+	// prompts (the Responses API). Others ignore it.
+	Prompt *responses.ResponsePromptParam
+	// DisableRetries asks the model to make a single attempt, because the
+	// caller retries failed requests itself.
+	DisableRetries bool
+}
+
+// OutputSchema is a JSON schema requested for the model's answer.
+type OutputSchema struct {
+	// Name matches [a-zA-Z0-9_-]{1,64}.
+	Name   string
+	Schema map[string]any
+	Strict bool
+}
+
+// Response holds a single model response.
+type Response struct {
+	Output     []responses.ResponseOutputItemUnion
+	Usage      *Usage
+	ResponseID string
+	// Model is the model that answered; one of RunConfig.FallbackModels
+	// when the agent's model failed.
+	Model string
+}
+
+// Usage tracks token consumption across LLM requests.
+type Usage struct {
+	Requests            uint64
+	InputTokens         uint64
+	InputTokensDetails  responses.ResponseUsageInputTokensDetails
+	OutputTokens        uint64
+	OutputTokensDetails responses.ResponseUsageOutputTokensDetails
+	TotalTokens         uint64
+}
+
+// Add adds the counts of other to u.
+func (u *Usage) Add(other Usage) {
+	u.Requests += other.Requests
+	u.InputTokens += other.InputTokens
+	u.InputTokensDetails.CachedTokens += other.InputTokensDetails.CachedTokens
+	u.OutputTokens += other.OutputTokens
+	u.OutputTokensDetails.ReasoningTokens += other.OutputTokensDetails.ReasoningTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// StreamEvent is an event emitted by Model.StreamResponse.
+type StreamEvent interface {
+	isStreamEvent()
+}
+
+// TextDelta carries a chunk of assistant text as it is generated.
+type TextDelta struct {
+	Delta string
+}
+
+// ToolCallStarted is emitted when the model starts a tool call.
+type ToolCallStarted struct {
+	CallID string
+	Name   string
+}
+
+// ToolCallArgumentsDelta carries a chunk of a tool call's JSON arguments.
+type ToolCallArgumentsDelta struct {
+	CallID string
+	Delta  string
+}
+
+func (TextDelta) isStreamEvent()              {}
+func (ToolCallStarted) isStreamEvent()        {}
+func (ToolCallArgumentsDelta) isStreamEvent() {}
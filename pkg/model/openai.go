@@ -0,0 +1,250 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// OpenAIProvider provides OpenAI models sharing one client.
+type OpenAIProvider struct {
+	Client openai.Client
+	// UseResponses selects the Responses API; otherwise the Chat Completions
+	// API is used, which OpenAI-compatible servers also implement.
+	UseResponses bool
+}
+
+// GetModel returns the model called name.
+func (p OpenAIProvider) GetModel(name string) (Model, error) {
+	if p.UseResponses {
+		return NewOpenAIResponsesModel(p.Client, name), nil
+	}
+	return NewOpenAIChatCompletionsModel(p.Client, name), nil
+}
+
+// OpenAIResponsesModel calls a model through the OpenAI Responses API.
+type OpenAIResponsesModel struct {
+	client openai.Client
+	model  string
+}
+
+// NewOpenAIResponsesModel returns the model called name, reached through client.
+func NewOpenAIResponsesModel(client openai.Client, name string) *OpenAIResponsesModel {
+	return &OpenAIResponsesModel{client: client, model: name}
+}
+
+// GetResponse implements Model.
+func (m *OpenAIResponsesModel) GetResponse(ctx context.Context, req Request) (*Response, error) {
+	return m.call(ctx, req, nil)
+}
+
+// StreamResponse implements Model.
+func (m *OpenAIResponsesModel) StreamResponse(ctx context.Context, req Request, emit func(StreamEvent)) (*Response, error) {
+	return m.call(ctx, req, emit)
+}
+
+func (m *OpenAIResponsesModel) call(ctx context.Context, req Request, emit func(StreamEvent)) (*Response, error) {
+	toolParams := ToolsToParams(req.Tools)
+
+	createParams := responses.ResponseNewParams{
+		Model: m.model,
+		Input: responses.ResponseNewParamsInputUnion{
+			OfInputItemList: responses.ResponseInputParam(req.Input),
+		},
+	}
+	if req.Prompt != nil {
+		createParams.Prompt = *req.Prompt
+	}
+	if req.Instructions != "" {
+		createParams.Instructions = param.NewOpt(req.Instructions)
+	}
+	if len(toolParams) > 0 {
+		createParams.Tools = toolParams
+	}
+	applyResponsesSettings(&createParams, req.Settings, len(toolParams) > 0)
+	if req.OutputSchema != nil {
+		createParams.Text = responsesFormat(req.OutputSchema)
+	}
+
+	createParams, opts, err := customizeResponsesRequest(ctx, req.Settings, createParams, requestOptions(req.Settings))
+	if err != nil {
+		return nil, err
+	}
+	if req.DisableRetries {
+		opts = append(opts, option.WithMaxRetries(0))
+	}
+
+	var resp *responses.Response
+	if emit != nil {
+		resp, err = streamResponsesAPI(ctx, m.client, createParams, opts, emit)
+	} else {
+		resp, err = m.client.Responses.New(ctx, createParams, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("call responses API: %w", err)
+	}
+
+	return &Response{
+		Output:     resp.Output,
+		ResponseID: resp.ID,
+		Model:      m.model,
+		Usage: &Usage{
+			Requests:            1,
+			InputTokens:         uint64(resp.Usage.InputTokens),
+			InputTokensDetails:  resp.Usage.InputTokensDetails,
+			OutputTokens:        uint64(resp.Usage.OutputTokens),
+			OutputTokensDetails: resp.Usage.OutputTokensDetails,
+			TotalTokens:         uint64(resp.Usage.TotalTokens),
+		},
+	}, nil
+}
+
+// OpenAIChatCompletionsModel calls a model through the OpenAI-compatible
+// Chat Completions API. Request.Prompt is ignored.
+type OpenAIChatCompletionsModel struct {
+	client openai.Client
+	model  string
+}
+
+// NewOpenAIChatCompletionsModel returns the model called name, reached through client.
+func NewOpenAIChatCompletionsModel(client openai.Client, name string) *OpenAIChatCompletionsModel {
+	return &OpenAIChatCompletionsModel{client: client, model: name}
+}
+
+// GetResponse implements Model.
+func (m *OpenAIChatCompletionsModel) GetResponse(ctx context.Context, req Request) (*Response, error) {
+	return m.call(ctx, req, nil)
+}
+
+// StreamResponse implements Model.
+func (m *OpenAIChatCompletionsModel) StreamResponse(ctx context.Context, req Request, emit func(StreamEvent)) (*Response, error) {
+	return m.call(ctx, req, emit)
+}
+
+func (m *OpenAIChatCompletionsModel) call(ctx context.Context, req Request, emit func(StreamEvent)) (*Response, error) {
+	var messages []openai.ChatCompletionMessageParamUnion
+	if req.Instructions != "" {
+		messages = append(messages, openai.SystemMessage(req.Instructions))
+	}
+	messages = append(messages, ItemsToChatMessages(req.Input)...)
+
+	chatParams := openai.ChatCompletionNewParams{
+		Model:    m.model,
+		Messages: messages,
+	}
+	toolParams := ToolsToChatParams(req.Tools)
+	if len(toolParams) > 0 {
+		chatParams.Tools = toolParams
+	}
+	applyChatSettings(&chatParams, req.Settings, len(toolParams) > 0)
+	if req.OutputSchema != nil {
+		chatParams.ResponseFormat = chatFormat(req.OutputSchema)
+	}
+	if emit != nil {
+		// Usage is only reported for streams when explicitly requested.
+		includeUsage := true
+		if req.Settings.IncludeUsage.Valid() {
+			includeUsage = req.Settings.IncludeUsage.Value
+		}
+		chatParams.StreamOptions.IncludeUsage = param.NewOpt(includeUsage)
+	}
+
+	chatParams, opts, err := customizeChatRequest(ctx, req.Settings, chatParams, requestOptions(req.Settings))
+	if err != nil {
+		return nil, err
+	}
+	if req.DisableRetries {
+		opts = append(opts, option.WithMaxRetries(0))
+	}
+
+	var chatresp *openai.ChatCompletion
+	if emit != nil {
+		chatresp, err = streamChatCompletionsAPI(ctx, m.client, chatParams, opts, emit)
+	} else {
+		chatresp, err = m.client.Chat.Completions.New(ctx, chatParams, opts...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("call chat completions API: %w", err)
+	}
+
+	output, err := chatCompletionToOutput(chatresp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		Output:     output,
+		ResponseID: chatresp.ID,
+		Model:      m.model,
+		Usage: &Usage{
+			Requests:    1,
+			InputTokens: uint64(chatresp.Usage.PromptTokens),
+			InputTokensDetails: responses.ResponseUsageInputTokensDetails{
+				CachedTokens: chatresp.Usage.PromptTokensDetails.CachedTokens,
+			},
+			OutputTokens: uint64(chatresp.Usage.CompletionTokens),
+			OutputTokensDetails: responses.ResponseUsageOutputTokensDetails{
+				ReasoningTokens: chatresp.Usage.CompletionTokensDetails.ReasoningTokens,
+			},
+			TotalTokens: uint64(chatresp.Usage.TotalTokens),
+		},
+	}, nil
+}
+
+// ToolsToParams converts tools to Responses API tool definitions.
+func ToolsToParams(tools []tool.Tool) []responses.ToolUnionParam {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	params := make([]responses.ToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		funcTool, ok := t.(tool.FunctionTool)
+		if !ok {
+			continue
+		}
+		toolParam := responses.ToolUnionParam{
+			OfFunction: &responses.FunctionToolParam{
+				Name:        funcTool.Name,
+				Description: param.NewOpt(funcTool.Description),
+				Parameters:  funcTool.ParamsJSONSchema,
+				Strict:      funcTool.StrictJSONSchema,
+			},
+		}
+		params = append(params, toolParam)
+	}
+	return params
+}
+
+// responsesFormat converts the schema into a Responses API text format.
+func responsesFormat(s *OutputSchema) responses.ResponseTextConfigParam {
+	return responses.ResponseTextConfigParam{
+		Format: responses.ResponseFormatTextConfigUnionParam{
+			OfJSONSchema: &responses.ResponseFormatTextJSONSchemaConfigParam{
+				Name:   s.Name,
+				Schema: s.Schema,
+				Strict: param.NewOpt(s.Strict),
+			},
+		},
+	}
+}
+
+// chatFormat converts the schema into a Chat Completions response format.
+func chatFormat(s *OutputSchema) openai.ChatCompletionNewParamsResponseFormatUnion {
+	return openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   s.Name,
+				Schema: s.Schema,
+				Strict: param.NewOpt(s.Strict),
+			},
+		},
+	}
+}
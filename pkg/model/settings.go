@@ -0,0 +1,127 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// applyResponsesSettings copies model settings onto a Responses API request.
+// The Responses API has no frequency or presence penalty, so those only apply
+// to Chat Completions.
+func applyResponsesSettings(params *responses.ResponseNewParams, s agent.ModelSettings, hasTools bool) {
+	params.Temperature = s.Temperature
+	params.TopP = s.TopP
+	params.MaxOutputTokens = s.MaxTokens
+	params.Store = s.Store
+	params.Reasoning = s.Reasoning
+	params.Metadata = shared.Metadata(s.Metadata)
+	params.Include = s.ResponseInclude
+	if s.Truncation.Valid() {
+		params.Truncation = responses.ResponseNewParamsTruncation(s.Truncation.Value)
+	}
+
+	// Tool options are rejected by the API when no tools are sent.
+	if !hasTools {
+		return
+	}
+	params.ParallelToolCalls = s.ParallelToolCalls
+	if choice, ok := s.ToolChoice.(agent.ToolChoiceString); ok && choice != "" {
+		switch choice {
+		case agent.ToolChoiceAuto, agent.ToolChoiceRequired, agent.ToolChoiceNone:
+			params.ToolChoice.OfToolChoiceMode = param.NewOpt(responses.ToolChoiceOptions(choice))
+		default:
+			params.ToolChoice.OfFunctionTool = &responses.ToolChoiceFunctionParam{Name: string(choice)}
+		}
+	}
+}
+
+// applyChatSettings copies model settings onto a Chat Completions request.
+// Truncation and ResponseInclude have no Chat Completions equivalent.
+func applyChatSettings(params *openai.ChatCompletionNewParams, s agent.ModelSettings, hasTools bool) {
+	params.Temperature = s.Temperature
+	params.TopP = s.TopP
+	params.MaxTokens = s.MaxTokens
+	params.FrequencyPenalty = s.FrequencyPenalty
+	params.PresencePenalty = s.PresencePenalty
+	params.Store = s.Store
+	params.ReasoningEffort = s.Reasoning.Effort
+	params.Metadata = shared.Metadata(s.Metadata)
+
+	if !hasTools {
+		return
+	}
+	params.ParallelToolCalls = s.ParallelToolCalls
+	if choice, ok := s.ToolChoice.(agent.ToolChoiceString); ok && choice != "" {
+		switch choice {
+		case agent.ToolChoiceAuto, agent.ToolChoiceRequired, agent.ToolChoiceNone:
+			params.ToolChoice.OfAuto = param.NewOpt(string(choice))
+		default:
+			params.ToolChoice.OfFunctionToolChoice = &openai.ChatCompletionNamedToolChoiceParam{
+				Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: string(choice)},
+			}
+		}
+	}
+}
+
+// requestOptions turns ExtraHeaders and ExtraQuery into request options.
+// Keys are applied in sorted order so requests are reproducible.
+func requestOptions(s agent.ModelSettings) []option.RequestOption {
+	var opts []option.RequestOption
+	for _, k := range slices.Sorted(maps.Keys(s.ExtraHeaders)) {
+		opts = append(opts, option.WithHeader(k, s.ExtraHeaders[k]))
+	}
+	for _, k := range slices.Sorted(maps.Keys(s.ExtraQuery)) {
+		opts = append(opts, option.WithQuery(k, s.ExtraQuery[k]))
+	}
+	return opts
+}
+
+// customizeResponsesRequest applies the CustomizeResponsesRequest hook, if any.
+func customizeResponsesRequest(
+	ctx context.Context,
+	s agent.ModelSettings,
+	params responses.ResponseNewParams,
+	opts []option.RequestOption,
+) (responses.ResponseNewParams, []option.RequestOption, error) {
+	if s.CustomizeResponsesRequest == nil {
+		return params, opts, nil
+	}
+	customized, customOpts, err := s.CustomizeResponsesRequest(ctx, &params, opts)
+	if err != nil {
+		return params, nil, fmt.Errorf("customize responses request: %w", err)
+	}
+	if customized != nil {
+		params = *customized
+	}
+	return params, customOpts, nil
+}
+
+// customizeChatRequest applies the CustomizeChatCompletionsRequest hook, if any.
+func customizeChatRequest(
+	ctx context.Context,
+	s agent.ModelSettings,
+	params openai.ChatCompletionNewParams,
+	opts []option.RequestOption,
+) (openai.ChatCompletionNewParams, []option.RequestOption, error) {
+	if s.CustomizeChatCompletionsRequest == nil {
+		return params, opts, nil
+	}
+	customized, customOpts, err := s.CustomizeChatCompletionsRequest(ctx, &params, opts)
+	if err != nil {
+		return params, nil, fmt.Errorf("customize chat completions request: %w", err)
+	}
+	if customized != nil {
+		params = *customized
+	}
+	return params, customOpts, nil
+}
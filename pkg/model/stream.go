@@ -0,0 +1,93 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// streamResponsesAPI calls the Responses API in streaming mode and returns the completed response.
+func streamResponsesAPI(
+	ctx context.Context,
+	client openai.Client,
+	params responses.ResponseNewParams,
+	opts []option.RequestOption,
+	emit func(StreamEvent),
+) (*responses.Response, error) {
+	stream := client.Responses.NewStreaming(ctx, params, opts...)
+	defer stream.Close()
+
+	// Argument deltas reference the output item ID, not the call ID.
+	callIDs := make(map[string]string)
+	var completed *responses.Response
+	for stream.Next() {
+		switch ev := stream.Current().AsAny().(type) {
+		case responses.ResponseTextDeltaEvent:
+			emit(TextDelta{Delta: ev.Delta})
+		case responses.ResponseOutputItemAddedEvent:
+			if call, ok := ev.Item.AsAny().(responses.ResponseFunctionToolCall); ok {
+				callIDs[call.ID] = call.CallID
+				emit(ToolCallStarted{CallID: call.CallID, Name: call.Name})
+			}
+		case responses.ResponseFunctionCallArgumentsDeltaEvent:
+			emit(ToolCallArgumentsDelta{CallID: callIDs[ev.ItemID], Delta: ev.Delta})
+		case responses.ResponseCompletedEvent:
+			completed = &ev.Response
+		case responses.ResponseFailedEvent:
+			return nil, fmt.Errorf("response failed: %s", ev.Response.Error.Message)
+		case responses.ResponseErrorEvent:
+			return nil, fmt.Errorf("response stream error: %s", ev.Message)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	if completed == nil {
+		return nil, fmt.Errorf("response stream ended without a completed response")
+	}
+	return completed, nil
+}
+
+// streamChatCompletionsAPI calls the Chat Completions API in streaming mode and
+// returns the accumulated completion.
+func streamChatCompletionsAPI(
+	ctx context.Context,
+	client openai.Client,
+	params openai.ChatCompletionNewParams,
+	opts []option.RequestOption,
+	emit func(StreamEvent),
+) (*openai.ChatCompletion, error) {
+	stream := client.Chat.Completions.NewStreaming(ctx, params, opts...)
+	defer stream.Close()
+
+	acc := openai.ChatCompletionAccumulator{}
+	// Tool call deltas after the first one only carry their index.
+	callIDs := make(map[int64]string)
+	for stream.Next() {
+		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			emit(TextDelta{Delta: delta.Content})
+		}
+		for _, tc := range delta.ToolCalls {
+			if tc.ID != "" {
+				callIDs[tc.Index] = tc.ID
+				emit(ToolCallStarted{CallID: tc.ID, Name: tc.Function.Name})
+			}
+			if tc.Function.Arguments != "" {
+				emit(ToolCallArgumentsDelta{CallID: callIDs[tc.Index], Delta: tc.Function.Arguments})
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return &acc.ChatCompletion, nil
+}
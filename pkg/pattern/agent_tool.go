@@ -45,7 +45,8 @@ func WrapAgentAsTool(a *agent.Agent, maxTurns uint64) tool.FunctionTool {
 				MaxTurns: maxTurns,
 			}}
 
-			result, err := r.Run(ctx, a, params.Input)
+			// Tool approvals in the nested run pause the calling run.
+			result, err := r.RunNested(ctx, a, params.Input)
 			if err != nil {
 				return nil, fmt.Errorf("run agent %q: %w", a.Name, err)
 			}
@@ -0,0 +1,76 @@
+package pattern
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// DefaultSummarizerInstructions suit an agent used as an AgentSummarizer.
+const DefaultSummarizerInstructions = `You summarize conversations between a user and an AI assistant.
+Write a concise summary that keeps the user's goals, the facts and decisions established,
+the results of tool calls that still matter, and any open questions. Reply with the summary only.`
+
+var _ memory.Summarizer = AgentSummarizer{}
+
+// AgentSummarizer summarizes session items by running an agent on their
+// transcript, e.g. for a memory.SummarizingSession.
+type AgentSummarizer struct {
+	// Agent writes the summary; see DefaultSummarizerInstructions.
+	Agent *agent.Agent
+	// Runner runs the agent. The zero value is valid.
+	Runner runner.Runner
+}
+
+// Summarize implements memory.Summarizer.
+func (s AgentSummarizer) Summarize(ctx context.Context, items []responses.ResponseInputItemUnionParam) (string, error) {
+	result, err := s.Runner.Run(ctx, s.Agent, "Summarize this conversation:\n\n"+Transcript(items))
+	if err != nil {
+		return "", fmt.Errorf("run summarizer agent %q: %w", s.Agent.Name, err)
+	}
+	summary, ok := result.FinalOutput.(string)
+	if !ok {
+		return "", fmt.Errorf("summarizer agent %q returned %T, not text", s.Agent.Name, result.FinalOutput)
+	}
+	return summary, nil
+}
+
+// Transcript renders items as plain text, one "role: text" line per message
+// and one line per tool call and output.
+func Transcript(items []responses.ResponseInputItemUnionParam) string {
+	var b strings.Builder
+	for _, m := range runner.ItemsToChatMessages(items) {
+		switch {
+		case m.OfSystem != nil:
+			fmt.Fprintf(&b, "system: %s\n", m.OfSystem.Content.OfString.Value)
+		case m.OfDeveloper != nil:
+			fmt.Fprintf(&b, "developer: %s\n", m.OfDeveloper.Content.OfString.Value)
+		case m.OfUser != nil:
+			text := m.OfUser.Content.OfString.Value
+			for _, part := range m.OfUser.Content.OfArrayOfContentParts {
+				if part.OfText != nil {
+					text += part.OfText.Text
+				}
+			}
+			fmt.Fprintf(&b, "user: %s\n", text)
+		case m.OfAssistant != nil:
+			if text := m.OfAssistant.Content.OfString.Value; text != "" {
+				fmt.Fprintf(&b, "assistant: %s\n", text)
+			}
+			for _, tc := range m.OfAssistant.ToolCalls {
+				if tc.OfFunction != nil {
+					fmt.Fprintf(&b, "assistant called %s(%s)\n", tc.OfFunction.Function.Name, tc.OfFunction.Function.Arguments)
+				}
+			}
+		case m.OfTool != nil:
+			fmt.Fprintf(&b, "tool result: %s\n", m.OfTool.Content.OfString.Value)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// ToolApprovalItem is a tool call waiting for approval.
+type ToolApprovalItem struct {
+	AgentName string `json:"agent_name"`
+	CallID    string `json:"call_id"`
+	ToolName  string `json:"tool_name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolApproval is the decision on a tool call waiting for approval.
+type ToolApproval struct {
+	CallID   string
+	Approved bool
+	// Reason tells the model why the call was rejected (optional).
+	Reason string
+}
+
+// Approve approves the call with the given ID.
+func Approve(callID string) ToolApproval {
+	return ToolApproval{CallID: callID, Approved: true}
+}
+
+// Reject rejects the call with the given ID. The model sees the reason.
+func Reject(callID, reason string) ToolApproval {
+	return ToolApproval{CallID: callID, Reason: reason}
+}
+
+// Resume continues a paused run using DefaultRunner.
+func Resume(ctx context.Context, state *RunState, approvals []ToolApproval) (*RunResult, error) {
+	return DefaultRunner.Resume(ctx, state, approvals)
+}
+
+// Resume continues a run paused for tool approvals. Approved calls run,
+// rejected calls are reported to the model as rejected, and calls without a
+// decision pause the run again.
+//
+// Like its Usage, the returned result's NewItems cover the whole run,
+// including the items produced before the pause; RawResponses holds only
+// the responses received since the pause.
+func (r Runner) Resume(ctx context.Context, state *RunState, approvals []ToolApproval) (*RunResult, error) {
+	if state == nil || state.startingAgent == nil {
+		return nil, fmt.Errorf("%w: state has no starting agent, load it with LoadRunState", ErrInvalidRunState)
+	}
+	decisions := make(map[string]ToolApproval, len(approvals))
+	for _, a := range approvals {
+		decisions[a.CallID] = a
+	}
+	return r.resume(ctx, state, decisions, nil)
+}
+
+// InterruptedError is returned by RunNested when the nested run pauses for
+// tool approvals. The run executing the enclosing tool call then pauses too,
+// listing the nested calls among its own Interruptions.
+type InterruptedError struct {
+	State *RunState
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("run of agent %q paused for %d tool approvals", e.State.CurrentAgent, len(e.State.PendingTurn.Interruptions))
+}
+
+// RunNested runs startingAgent from inside a tool call, as agents used as
+// tools do. If the run pauses for tool approvals it returns an
+// *InterruptedError, so the outer run pauses as well; when the outer run is
+// resumed the tool is called again and RunNested resumes the nested run with
+// the same approvals.
+func (r Runner) RunNested(ctx context.Context, startingAgent *agent.Agent, input string) (*RunResult, error) {
+	var result *RunResult
+	var err error
+	if state, decisions, ok := nestedState(ctx); ok {
+		if state.StartingAgent != startingAgent.Name {
+			return nil, fmt.Errorf("%w: started with agent %q, got %q", ErrInvalidRunState, state.StartingAgent, startingAgent.Name)
+		}
+		state.startingAgent = startingAgent
+		result, err = r.resume(ctx, state, decisions, nil)
+	} else {
+		result, err = r.run(ctx, startingAgent, types.InputString(input), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Interruptions) > 0 {
+		return result, &InterruptedError{State: result.State}
+	}
+	return result, nil
+}
+
+type toolCallKey struct{}
+type nestedResumeKey struct{}
+
+// nestedResume carries the paused nested runs of a resumed turn to the tool calls.
+type nestedResume struct {
+	states    map[string]*RunState
+	decisions map[string]ToolApproval
+}
+
+// nestedState returns the paused nested run of the tool call running in ctx, if any.
+func nestedState(ctx context.Context) (*RunState, map[string]ToolApproval, bool) {
+	callID, _ := ctx.Value(toolCallKey{}).(string)
+	resume, _ := ctx.Value(nestedResumeKey{}).(nestedResume)
+	state, ok := resume.states[callID]
+	return state, resume.decisions, ok
+}
+
+// needsApproval reports whether calls of the named tool wait for approval.
+func needsApproval(tools []tool.Tool, name string) bool {
+	t, found := FindTool(tools, name)
+	if !found {
+		return false
+	}
+	ft, ok := t.(tool.FunctionTool)
+	return ok && ft.NeedsApproval
+}
+
+// rejectedOutput is the tool output the model sees for a rejected call.
+func rejectedOutput(call responses.ResponseFunctionToolCall, reason string) string {
+	if reason == "" {
+		return fmt.Sprintf("Tool call %s was rejected by the user.", call.Name)
+	}
+	return fmt.Sprintf("Tool call %s was rejected by the user: %s", call.Name, reason)
+}
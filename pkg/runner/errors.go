@@ -14,4 +14,13 @@ var (
 
 	// ErrInvalidInput indicates invalid input was provided.
 	ErrInvalidInput = errors.New("invalid input")
+
+	// ErrHandoffUnresolved indicates the agent of a handoff could not be found.
+	ErrHandoffUnresolved = errors.New("handoff agent not resolved")
+
+	// ErrInvalidRunState indicates a RunState that cannot be resumed.
+	ErrInvalidRunState = errors.New("invalid run state")
+
+	// ErrRunIDRequired indicates a CheckpointStore configured without a RunID.
+	ErrRunIDRequired = errors.New("run ID required with a checkpoint store")
 )
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+)
+
+// handoffTools returns the transfer tools exposed for a's handoffs, and the
+// handoff behind each tool name.
+func handoffTools(a *agent.Agent) ([]tool.Tool, map[string]agent.Handoff) {
+	if len(a.Handoffs) == 0 {
+		return nil, nil
+	}
+
+	tools := make([]tool.Tool, 0, len(a.Handoffs))
+	byName := make(map[string]agent.Handoff, len(a.Handoffs))
+	for _, h := range a.Handoffs {
+		name := h.GetToolName()
+		byName[name] = h
+		tools = append(tools, tool.FunctionTool{
+			Name:        name,
+			Description: h.GetToolDescription(),
+			ParamsJSONSchema: map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{},
+				"required":             []string{},
+				"additionalProperties": false,
+			},
+		})
+	}
+	return tools, byName
+}
+
+// resolveHandoff returns the agent receiving a handoff, looking it up through
+// HandoffResolver when the handoff only names it.
+func (r Runner) resolveHandoff(ctx context.Context, h agent.Handoff) (*agent.Agent, error) {
+	if h.Agent != nil {
+		return h.Agent, nil
+	}
+	if r.Config.HandoffResolver == nil {
+		return nil, fmt.Errorf("%w: %q (no HandoffResolver configured)", ErrHandoffUnresolved, h.AgentName)
+	}
+	target, err := r.Config.HandoffResolver(ctx, h.AgentName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrHandoffUnresolved, h.AgentName, err)
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%w: %q", ErrHandoffUnresolved, h.AgentName)
+	}
+	return target, nil
+}
+
+// handoffOutput is the tool output the model sees for a completed handoff.
+func handoffOutput(target *agent.Agent) string {
+	data, _ := json.Marshal(map[string]string{"assistant": target.Name})
+	return string(data)
+}
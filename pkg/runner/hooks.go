@@ -0,0 +1,150 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// RunHooks receives lifecycle callbacks for every agent of a run. Callbacks
+// run synchronously in the run loop, except the tool callbacks, which may be
+// called concurrently when a turn has several tool calls.
+//
+// Embed NoOpRunHooks to implement only the callbacks you need.
+type RunHooks interface {
+	// OnRunStart is called before input guardrails run.
+	OnRunStart(ctx context.Context, a *agent.Agent, input types.Input)
+	// OnRunEnd is called when the run returns, with either result or err set.
+	OnRunEnd(ctx context.Context, result *RunResult, err error)
+	// OnAgentStart is called when a becomes the current agent of the run.
+	OnAgentStart(ctx context.Context, a *agent.Agent)
+	// OnAgentEnd is called when a produces the final output.
+	OnAgentEnd(ctx context.Context, a *agent.Agent, output any)
+	// OnHandoff is called when from hands the conversation off to to.
+	OnHandoff(ctx context.Context, from, to *agent.Agent)
+	// OnLLMStart is called before each model request.
+	OnLLMStart(ctx context.Context, a *agent.Agent, instructions string, input []responses.ResponseInputItemUnionParam)
+	// OnLLMEnd is called after each successful model request.
+	OnLLMEnd(ctx context.Context, a *agent.Agent, response ModelResponse)
+	// OnToolStart is called before a tool call is executed.
+	OnToolStart(ctx context.Context, a *agent.Agent, call responses.ResponseFunctionToolCall)
+	// OnToolEnd is called with the output sent back to the model for a tool call.
+	OnToolEnd(ctx context.Context, a *agent.Agent, call responses.ResponseFunctionToolCall, output string)
+	// OnInputGuardrail is called with the result of each input guardrail.
+	OnInputGuardrail(ctx context.Context, a *agent.Agent, result agent.InputGuardrailResult)
+	// OnOutputGuardrail is called with the result of each output guardrail.
+	OnOutputGuardrail(ctx context.Context, a *agent.Agent, result agent.OutputGuardrailResult)
+}
+
+// NoOpRunHooks implements RunHooks with callbacks that do nothing.
+type NoOpRunHooks struct{}
+
+func (NoOpRunHooks) OnRunStart(context.Context, *agent.Agent, types.Input) {}
+func (NoOpRunHooks) OnRunEnd(context.Context, *RunResult, error)           {}
+func (NoOpRunHooks) OnAgentStart(context.Context, *agent.Agent)            {}
+func (NoOpRunHooks) OnAgentEnd(context.Context, *agent.Agent, any)         {}
+func (NoOpRunHooks) OnHandoff(context.Context, *agent.Agent, *agent.Agent) {}
+func (NoOpRunHooks) OnLLMStart(context.Context, *agent.Agent, string, []responses.ResponseInputItemUnionParam) {
+}
+func (NoOpRunHooks) OnLLMEnd(context.Context, *agent.Agent, ModelResponse)                         {}
+func (NoOpRunHooks) OnToolStart(context.Context, *agent.Agent, responses.ResponseFunctionToolCall) {}
+func (NoOpRunHooks) OnToolEnd(context.Context, *agent.Agent, responses.ResponseFunctionToolCall, string) {
+}
+func (NoOpRunHooks) OnInputGuardrail(context.Context, *agent.Agent, agent.InputGuardrailResult)   {}
+func (NoOpRunHooks) OnOutputGuardrail(context.Context, *agent.Agent, agent.OutputGuardrailResult) {}
+
+// hooks calls RunConfig.Hooks and the hooks of the agent involved, either of
+// which may be nil.
+type hooks struct {
+	run RunHooks
+}
+
+func (h hooks) runStart(ctx context.Context, a *agent.Agent, input types.Input) {
+	if h.run != nil {
+		h.run.OnRunStart(ctx, a, input)
+	}
+}
+
+func (h hooks) runEnd(ctx context.Context, result *RunResult, err error) {
+	if h.run != nil {
+		h.run.OnRunEnd(ctx, result, err)
+	}
+}
+
+func (h hooks) agentStart(ctx context.Context, a *agent.Agent) {
+	if h.run != nil {
+		h.run.OnAgentStart(ctx, a)
+	}
+	if a.Hooks != nil {
+		a.Hooks.OnStart(ctx, a)
+	}
+}
+
+func (h hooks) agentEnd(ctx context.Context, a *agent.Agent, output any) {
+	if h.run != nil {
+		h.run.OnAgentEnd(ctx, a, output)
+	}
+	if a.Hooks != nil {
+		a.Hooks.OnEnd(ctx, a, output)
+	}
+}
+
+func (h hooks) handoff(ctx context.Context, from, to *agent.Agent) {
+	if h.run != nil {
+		h.run.OnHandoff(ctx, from, to)
+	}
+	if to.Hooks != nil {
+		to.Hooks.OnHandoff(ctx, to, from)
+	}
+}
+
+func (h hooks) llmStart(ctx context.Context, a *agent.Agent, instructions string, input []responses.ResponseInputItemUnionParam) {
+	if h.run != nil {
+		h.run.OnLLMStart(ctx, a, instructions, input)
+	}
+	if a.Hooks != nil {
+		a.Hooks.OnLLMStart(ctx, a, instructions, input)
+	}
+}
+
+func (h hooks) llmEnd(ctx context.Context, a *agent.Agent, response ModelResponse) {
+	if h.run != nil {
+		h.run.OnLLMEnd(ctx, a, response)
+	}
+	if a.Hooks != nil {
+		a.Hooks.OnLLMEnd(ctx, a, response.Output)
+	}
+}
+
+func (h hooks) toolStart(ctx context.Context, a *agent.Agent, call responses.ResponseFunctionToolCall) {
+	if h.run != nil {
+		h.run.OnToolStart(ctx, a, call)
+	}
+	if a.Hooks != nil {
+		a.Hooks.OnToolStart(ctx, a, call)
+	}
+}
+
+func (h hooks) toolEnd(ctx context.Context, a *agent.Agent, call responses.ResponseFunctionToolCall, output string) {
+	if h.run != nil {
+		h.run.OnToolEnd(ctx, a, call, output)
+	}
+	if a.Hooks != nil {
+		a.Hooks.OnToolEnd(ctx, a, call, output)
+	}
+}
+
+func (h hooks) inputGuardrail(ctx context.Context, a *agent.Agent, result agent.InputGuardrailResult) {
+	if h.run != nil {
+		h.run.OnInputGuardrail(ctx, a, result)
+	}
+}
+
+func (h hooks) outputGuardrail(ctx context.Context, a *agent.Agent, result agent.OutputGuardrailResult) {
+	if h.run != nil {
+		h.run.OnOutputGuardrail(ctx, a, result)
+	}
+}
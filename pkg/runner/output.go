@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// defaultOutputFormatName is used when an OutputType has no usable name.
+const defaultOutputFormatName = "final_output"
+
+// OutputValidationError is returned when the final output does not satisfy
+// the agent's OutputType and no retries are left.
+type OutputValidationError struct {
+	// Output is the raw text the model produced.
+	Output string
+	Err    error
+}
+
+func (e *OutputValidationError) Error() string {
+	return fmt.Sprintf("final output failed validation: %v", e.Err)
+}
+
+func (e *OutputValidationError) Unwrap() error {
+	return e.Err
+}
+
+// getOutputSchema returns the schema of a's structured output, or nil for plain text.
+func getOutputSchema(a *agent.Agent) (*model.OutputSchema, error) {
+	if a.OutputType == nil || a.OutputType.IsPlainText() {
+		return nil, nil
+	}
+	schema, err := a.OutputType.JSONSchema()
+	if err != nil {
+		return nil, fmt.Errorf("get output schema: %w", err)
+	}
+	return &model.OutputSchema{
+		Name:   outputFormatName(a.OutputType.Name()),
+		Schema: schema,
+		Strict: a.OutputType.IsStrictJSONSchema(),
+	}, nil
+}
+
+// outputFormatName maps an OutputType name onto the [a-zA-Z0-9_-]{1,64} names the API accepts.
+func outputFormatName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	out := b.String()
+	if out == "" {
+		return defaultOutputFormatName
+	}
+	if len(out) > 64 {
+		out = out[:64]
+	}
+	return out
+}
+
+// validateFinalOutput parses the final message against the agent's OutputType.
+func validateFinalOutput(ctx context.Context, a *agent.Agent, msg responses.ResponseOutputMessage) (any, error) {
+	text := outputMessageText(msg)
+	parsed, err := a.OutputType.ValidateJSON(ctx, text)
+	if err != nil {
+		return nil, &OutputValidationError{Output: text, Err: err}
+	}
+	return parsed, nil
+}
+
+// outputMessageText joins the text parts of a model output message.
+func outputMessageText(msg responses.ResponseOutputMessage) string {
+	var texts []string
+	for _, c := range msg.Content {
+		if text, ok := c.AsAny().(responses.ResponseOutputText); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// outputRetryMessage asks the model to correct an output that failed validation.
+func outputRetryMessage(err error) responses.ResponseInputItemUnionParam {
+	return responses.ResponseInputItemParamOfMessage(
+		fmt.Sprintf("Your previous response did not match the required output schema: %v. Reply again with only JSON that matches the schema.", err),
+		responses.EasyInputMessageRoleUser,
+	)
+}
+
+// RunTyped runs the agent with r and returns its final output as a T.
+// If the starting agent has no OutputType, it runs with agent.OutputTypeFor[T]().
+func RunTyped[T any](ctx context.Context, r Runner, startingAgent *agent.Agent, input string) (T, error) {
+	var zero T
+	if startingAgent.OutputType == nil {
+		typed := *startingAgent
+		typed.OutputType = agent.OutputTypeFor[T]()
+		startingAgent = &typed
+	}
+
+	result, err := r.Run(ctx, startingAgent, input)
+	if err != nil {
+		return zero, err
+	}
+	out, ok := result.FinalOutput.(T)
+	if !ok {
+		return zero, fmt.Errorf("final output of agent %q is %T, not %T", result.LastAgent.Name, result.FinalOutput, zero)
+	}
+	return out, nil
+}
@@ -0,0 +1,241 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/config"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// Default RetryPolicy values.
+const (
+	DefaultInitialBackoff    = 500 * time.Millisecond
+	DefaultMaxBackoff        = 30 * time.Second
+	DefaultBackoffMultiplier = 2.0
+	DefaultJitter            = 0.2
+)
+
+// RetryPolicy controls how model calls that fail with a transient error are
+// retried. The zero value makes a single attempt, leaving retries to the
+// model (the OpenAI client retries on its own). Zero durations and factors take the defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per model, the first included.
+	// Above 1, the model's own retries are turned off in favor of this policy.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts, including delays asked for
+	// by a Retry-After header.
+	MaxBackoff time.Duration
+	// Multiplier grows the delay after each attempt.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction of it, e.g. 0.2 for
+	// ±20%. A negative value disables jitter.
+	Jitter float64
+	// Retryable reports whether an error is worth another attempt.
+	// Nil uses IsRetryable.
+	Retryable func(error) bool
+}
+
+// IsRetryable reports whether err is transient: a timeout, conflict, rate
+// limit or server error status (408, 409, 429, 5xx), a network timeout, a
+// reset or refused connection, or a response cut short. Other network
+// failures, such as unknown hosts and TLS errors, are not retryable, nor are
+// cancellation and deadline errors of the caller's context.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return isRetryableStatus(apiErr.StatusCode)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return status >= http.StatusInternalServerError
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// delay returns how long to wait after the given failed attempt (1-based):
+// the server's Retry-After when it sent one, otherwise exponential backoff.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	if d, ok := retryAfter(err); ok {
+		return min(d, maxBackoff)
+	}
+
+	initial := p.InitialBackoff
+	if initial <= 0 {
+		initial = DefaultInitialBackoff
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = DefaultBackoffMultiplier
+	}
+	jitter := p.Jitter
+	if jitter == 0 {
+		jitter = DefaultJitter
+	}
+
+	d := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	d = min(d, float64(maxBackoff))
+	if jitter > 0 {
+		d *= 1 + jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// retryAfter reads the Retry-After-Ms or Retry-After header of an API error.
+// Retry-After holds either seconds or an HTTP date.
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0, false
+	}
+	header := apiErr.Response.Header
+	if v := header.Get("Retry-After-Ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	if v := header.Get("Retry-After"); v != "" {
+		if s, err := strconv.ParseFloat(v, 64); err == nil && s >= 0 {
+			return time.Duration(s * float64(time.Second)), true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(time.Until(t), 0), true
+		}
+	}
+	return 0, false
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// modelTarget is a model a request can be sent to: the agent's own, or one
+// of RunConfig.FallbackModels.
+type modelTarget struct {
+	model model.Model
+	name  string
+}
+
+// modelTargets resolves the models of a run. The fallback models of each
+// agent are built once per run.
+type modelTargets struct {
+	runner    Runner
+	fallbacks map[*agent.Agent][]modelTarget
+}
+
+func newModelTargets(r Runner) *modelTargets {
+	return &modelTargets{runner: r, fallbacks: map[*agent.Agent][]modelTarget{}}
+}
+
+// get lists the agent's model followed by the fallback models.
+func (t *modelTargets) get(a *agent.Agent, name string) ([]modelTarget, error) {
+	m, err := t.runner.getModel(a, name)
+	if err != nil {
+		return nil, err
+	}
+	fallbacks, ok := t.fallbacks[a]
+	if !ok {
+		fallbacks, err = t.runner.fallbackModels(a)
+		if err != nil {
+			return nil, err
+		}
+		t.fallbacks[a] = fallbacks
+	}
+	return append([]modelTarget{{model: m, name: name}}, fallbacks...), nil
+}
+
+// fallbackModels resolves RunConfig.FallbackModels for agent a: by model name
+// through ModelProvider when it is set, otherwise through a client derived
+// from the agent's, so that its HTTP client, headers and other options apply.
+func (r Runner) fallbackModels(a *agent.Agent) ([]modelTarget, error) {
+	var targets []modelTarget
+	for _, fallback := range r.Config.FallbackModels {
+		var fm model.Model
+		var err error
+		if r.Config.ModelProvider != nil {
+			fm, err = r.Config.ModelProvider.GetModel(fallback.Model)
+		} else {
+			provider := model.OpenAIProvider{Client: fallbackClient(a.Client, fallback), UseResponses: a.Prompt != nil}
+			fm, err = provider.GetModel(fallback.Model)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get fallback model %q: %w", fallback.Model, err)
+		}
+		targets = append(targets, modelTarget{model: fm, name: fallback.Model})
+	}
+	return targets, nil
+}
+
+// getModel returns the model called name for agent a, from ModelProvider
+// or else from the agent's OpenAI client.
+func (r Runner) getModel(a *agent.Agent, name string) (model.Model, error) {
+	if r.Config.ModelProvider == nil {
+		return model.OpenAIProvider{Client: a.Client, UseResponses: a.Prompt != nil}.GetModel(name)
+	}
+	m, err := r.Config.ModelProvider.GetModel(name)
+	if err != nil {
+		return nil, fmt.Errorf("get model %q: %w", name, err)
+	}
+	return m, nil
+}
+
+// fallbackClient returns a client with the options of base, overridden by
+// the API key and base URL of c when set.
+func fallbackClient(base openai.Client, c config.ModelConfig) openai.Client {
+	opts := slices.Clone(base.Options)
+	if c.APIKey != "" {
+		opts = append(opts, option.WithAPIKey(c.APIKey))
+	}
+	if c.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(c.BaseURL))
+	}
+	return openai.NewClient(opts...)
+}
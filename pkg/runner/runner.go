@@ -2,15 +2,23 @@ package runner
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/checkpoint"
+	"github.com/chuanbosi666/agent_go/pkg/config"
+	"github.com/chuanbosi666/agent_go/pkg/history"
 	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/model"
 	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/tracing"
 	"github.com/chuanbosi666/agent_go/pkg/types"
 
 	"github.com/openai/openai-go/v3"
-	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/openai/openai-go/v3/responses"
 )
 
@@ -41,21 +49,10 @@ type Output struct {
 }
 
 // Usage tracks token consumption across LLM requests.
-type Usage struct {
-	Requests            uint64
-	InputTokens         uint64
-	InputTokensDetails  responses.ResponseUsageInputTokensDetails
-	OutputTokens        uint64
-	OutputTokensDetails responses.ResponseUsageOutputTokensDetails
-	TotalTokens         uint64
-}
+type Usage = model.Usage
 
 // ModelResponse holds a single LLM response.
-type ModelResponse struct {
-	Output     []responses.ResponseOutputItemUnion
-	Usage      *Usage
-	ResponseID string
-}
+type ModelResponse = model.Response
 
 // RunResult contains the complete results of an Agent execution.
 type RunResult struct {
@@ -66,6 +63,13 @@ type RunResult struct {
 	InputGuardrailResults  []agent.InputGuardrailResult
 	OutputGuardrailResults []agent.OutputGuardrailResult
 	LastAgent              *agent.Agent
+	Usage                  RunUsage
+
+	// Interruptions lists the tool calls waiting for approval. When it is
+	// non-empty the run is paused: FinalOutput is unset and State can be
+	// passed to Resume once the calls are decided.
+	Interruptions []ToolApprovalItem
+	State         *RunState
 }
 
 const DefaultMaxTurns = 10
@@ -91,6 +95,67 @@ type RunConfig struct {
 	HandoffResolver      func(ctx context.Context, agentName string) (*agent.Agent, error)
 	ToolRouter           tool.ToolRouter
 	ToolRoutingThreshold int
+
+	// MaxToolConcurrency limits how many tool calls of one turn run at once.
+	// Zero means no limit; 1 runs them sequentially.
+	MaxToolConcurrency int
+
+	// ToolCallTimeout bounds each tool call (optional). A call that times out
+	// is reported to the model as failed.
+	ToolCallTimeout time.Duration
+
+	// ToolCancelGracePeriod bounds how long the run waits for a tool call
+	// that timed out or was cancelled to return. Zero waits until the tool
+	// returns, so tools should honor their context; a tool still running
+	// after the grace period is abandoned and keeps running in the background.
+	ToolCancelGracePeriod time.Duration
+
+	// OutputValidationRetries is how many times the model is asked to correct a final
+	// output that fails OutputType validation before the run fails.
+	OutputValidationRetries int
+
+	// Hooks receives lifecycle callbacks for every agent of the run (optional).
+	Hooks RunHooks
+
+	// TracingDisabled turns off tracing for the run and the runs nested in it.
+	TracingDisabled bool
+	// TraceProvider receives the run's trace. Nil uses tracing.DefaultProvider().
+	TraceProvider *tracing.Provider
+	// GroupID links the traces of one conversation, e.g. a chat thread ID (optional).
+	GroupID string
+	// TraceMetadata is attached to the run's trace (optional).
+	TraceMetadata map[string]any
+
+	// CheckpointStore receives the run's RunState, keyed by RunID, at the start
+	// of every turn and before each batch of tool calls, so an interrupted run
+	// can be continued with LoadCheckpoint and ResumeFromState (optional).
+	// The checkpoint is deleted when the run completes. Checkpoints mark the
+	// end of the Session's items, so that resuming removes the items saved
+	// after the checkpoint; resuming fails if the mark cannot be found.
+	CheckpointStore checkpoint.Store
+	// RunID identifies the run in CheckpointStore; required with it.
+	RunID string
+
+	// HistoryStrategy trims the history sent to the model each turn, e.g. to
+	// fit its context window (optional). The Session and RunResult keep the
+	// full history.
+	HistoryStrategy history.Strategy
+
+	// ModelProvider resolves the model names of agents (or Model) into the
+	// models that are called. Nil calls the OpenAI API with each agent's
+	// Client: the Responses API for agents with a Prompt, Chat Completions
+	// otherwise.
+	ModelProvider model.Provider
+
+	// RetryPolicy retries model calls that fail with a transient error.
+	RetryPolicy RetryPolicy
+	// FallbackModels are tried in order, each with the full RetryPolicy, once
+	// the agent's model has used up its attempts. With a ModelProvider they
+	// are resolved through it by Model name. Otherwise they are
+	// OpenAI-compatible models called through the same API as the agent's
+	// default one, with a copy of the agent's Client that uses their APIKey
+	// and BaseURL when set.
+	FallbackModels []config.ModelConfig
 }
 
 func (o Output) TotalTokens() int64 {
@@ -104,7 +169,17 @@ func Run(ctx context.Context, startingAgent *agent.Agent, input string) (*RunRes
 
 // Run executes the agent with a string input.
 func (r Runner) Run(ctx context.Context, startingAgent *agent.Agent, input string) (*RunResult, error) {
-	return r.run(ctx, startingAgent, types.InputString(input))
+	return r.run(ctx, startingAgent, types.InputString(input), nil)
+}
+
+// RunInputs executes the agent with structured input items using DefaultRunner.
+func RunInputs(ctx context.Context, startingAgent *agent.Agent, input []responses.ResponseInputItemUnionParam) (*RunResult, error) {
+	return DefaultRunner.RunInputs(ctx, startingAgent, input)
+}
+
+// RunInputs executes the agent with structured input items (messages, tool results, etc.).
+func (r Runner) RunInputs(ctx context.Context, startingAgent *agent.Agent, input []responses.ResponseInputItemUnionParam) (*RunResult, error) {
+	return r.run(ctx, startingAgent, types.InputItems(input), nil)
 }
 
 // MaxTurnsExceededError is returned when execution exceeds MaxTurns.
@@ -130,22 +205,98 @@ func (g *GuardrailTripwireTriggeredError) Error() string {
 	return fmt.Sprintf("output guardrail '%s' triggered", g.GuardrailName)
 }
 
-// run is the core execution loop.
-func (r Runner) run(ctx context.Context, startingAgent *agent.Agent, input types.Input) (*RunResult, error) {
+// run starts a new run.
+func (r Runner) run(ctx context.Context, startingAgent *agent.Agent, input types.Input, events *eventEmitter) (*RunResult, error) {
+	return r.execute(ctx, startingAgent, input, nil, nil, events)
+}
+
+// resume continues the run captured in state.
+func (r Runner) resume(ctx context.Context, state *RunState, decisions map[string]ToolApproval, events *eventEmitter) (*RunResult, error) {
+	return r.execute(ctx, state.startingAgent, state.input(), state, decisions, events)
+}
+
+// execute runs the agent loop between the OnRunStart and OnRunEnd hooks.
+func (r Runner) execute(
+	ctx context.Context,
+	startingAgent *agent.Agent,
+	input types.Input,
+	state *RunState,
+	decisions map[string]ToolApproval,
+	events *eventEmitter,
+) (*RunResult, error) {
+	ctx, trace := r.startTrace(ctx)
+	defer trace.End()
+
+	h := hooks{run: r.Config.Hooks}
+	h.runStart(ctx, startingAgent, input)
+	result, err := r.runLoop(ctx, h, startingAgent, input, state, decisions, events)
+	h.runEnd(ctx, result, err)
+	return result, err
+}
+
+// runLoop is the core execution loop. A non-nil state continues a paused run,
+// with decisions for the tool calls waiting for approval.
+// When events is non-nil, model responses are streamed and progress is reported through it.
+func (r Runner) runLoop(
+	ctx context.Context,
+	h hooks,
+	startingAgent *agent.Agent,
+	input types.Input,
+	state *RunState,
+	decisions map[string]ToolApproval,
+	events *eventEmitter,
+) (_ *RunResult, err error) {
 	result := &RunResult{
 		Input:        types.CopyInput(input),
 		NewItems:     []RunItem{},
 		RawResponses: []ModelResponse{},
 	}
 
-	// Run input guardrails
-	inputGuardrails := append(r.Config.InputGuardrails, startingAgent.InputGuardrails...)
+	runID := r.Config.RunID
+	if state != nil && state.RunID != "" {
+		runID = state.RunID
+	}
+	if r.Config.CheckpointStore != nil && runID == "" {
+		return nil, ErrRunIDRequired
+	}
+
+	// Nested runs started by tool calls find this recorder through ctx.
+	ctx, usage := withUsageRecorder(ctx)
+
+	// Each agent gets a span covering its part of the run; spans of its
+	// model and tool calls are started from ctx, which carries it.
+	runCtx := ctx
+	var agentSpan *tracing.Span
+	startAgentSpan := func(a *agent.Agent) {
+		agentSpan.End()
+		ctx, agentSpan = tracing.StartSpan(runCtx, newAgentSpanData(a))
+	}
+	defer func() {
+		agentSpan.SetError(err)
+		agentSpan.End()
+	}()
+
+	currentAgent := startingAgent
+	if state != nil {
+		currentAgent, err = r.findAgent(ctx, startingAgent, state.CurrentAgent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	startAgentSpan(currentAgent)
+
+	// Run input guardrails; a resumed run passed them before it was paused.
+	var inputGuardrails []agent.InputGuardrail
+	if state == nil {
+		inputGuardrails = append(r.Config.InputGuardrails, startingAgent.InputGuardrails...)
+	}
 	for _, gr := range inputGuardrails {
-		grResult, err := gr.Run(ctx, startingAgent, input)
+		grResult, err := runInputGuardrail(ctx, gr, startingAgent, input)
 		if err != nil {
 			return nil, fmt.Errorf("input guardrail %q failed: %w", gr.Name, err)
 		}
 		result.InputGuardrailResults = append(result.InputGuardrailResults, grResult)
+		h.inputGuardrail(ctx, startingAgent, grResult)
 		if grResult.Output.TripwireTriggered {
 			return nil, &GuardrailTripwireTriggeredError{
 				GuardrailName: gr.Name,
@@ -155,22 +306,116 @@ func (r Runner) run(ctx context.Context, startingAgent *agent.Agent, input types
 		}
 	}
 
-	currentAgent := startingAgent
 	turnCount := uint64(0)
 	maxTurns := r.Config.MaxTurns
 	if maxTurns == 0 {
 		maxTurns = DefaultMaxTurns
 	}
 
+	// The input is part of the conversation from the first turn on, so that
+	// follow-up turns (e.g. after tool calls) still see what the user asked.
 	var accumulatedHistory []responses.ResponseInputItemUnionParam
 
+	// After a handoff with an input filter in a Session run, the receiving
+	// agent sees the filtered history followed by the items saved since.
+	var handoffHistory []responses.ResponseInputItemUnionParam
+
+	saveItems := func(items []responses.ResponseInputItemUnionParam) error {
+		if len(items) == 0 {
+			return nil
+		}
+		if r.Config.Session != nil {
+			if handoffHistory != nil {
+				handoffHistory = append(handoffHistory, items...)
+			}
+			return r.Config.Session.AddItems(ctx, items)
+		}
+		accumulatedHistory = append(accumulatedHistory, items...)
+		return nil
+	}
+
+	finished := false
+	validationRetries := 0
+	models := newModelTargets(r)
+
+	// snapshot captures the run so far, with the turn in progress if any.
+	snapshot := func(pending *PendingTurn) *RunState {
+		s := newRunState(startingAgent, input)
+		s.RunID = runID
+		s.CurrentAgent = currentAgent.Name
+		s.Turn = turnCount
+		s.History = Items(accumulatedHistory)
+		s.HandoffHistory = Items(handoffHistory)
+		s.ValidationRetries = validationRetries
+		for _, item := range result.NewItems {
+			s.NewItems = append(s.NewItems, item.ToInputItem())
+		}
+		s.Usage = usage.snapshot()
+		s.PendingTurn = pending
+		return s
+	}
+	saveCheckpoint := func(s *RunState) error {
+		if r.Config.CheckpointStore == nil {
+			return nil
+		}
+		if r.Config.Session != nil {
+			stored, err := r.Config.Session.GetItems(ctx, -1)
+			if err != nil {
+				return fmt.Errorf("load session history: %w", err)
+			}
+			s.SessionMark, err = newSessionMark(stored)
+			if err != nil {
+				return fmt.Errorf("mark session position: %w", err)
+			}
+		}
+		data, err := json.Marshal(s)
+		if err != nil {
+			return fmt.Errorf("marshal run state: %w", err)
+		}
+		if err := r.Config.CheckpointStore.Save(ctx, runID, data); err != nil {
+			return fmt.Errorf("save checkpoint: %w", err)
+		}
+		return nil
+	}
+
+	// pendingTurn is the turn a resumed run was paused in.
+	var pendingTurn *PendingTurn
+	if state == nil {
+		if err := saveItems(InputToItems(input)); err != nil {
+			return nil, fmt.Errorf("save input to session: %w", err)
+		}
+	} else {
+		turnCount = state.Turn
+		accumulatedHistory = slices.Clone(state.History)
+		handoffHistory = slices.Clone(state.HandoffHistory)
+		validationRetries = state.ValidationRetries
+		if r.Config.Session != nil && state.SessionMark != nil {
+			if err := rewindSession(ctx, r.Config.Session, state.SessionMark); err != nil {
+				return nil, err
+			}
+		}
+		for _, item := range state.NewItems {
+			result.NewItems = append(result.NewItems, WrapRunItem(item))
+		}
+		usage.restore(state.Usage)
+		pendingTurn = state.PendingTurn
+	}
+
+	events.emit(AgentSwitchedEvent{Agent: currentAgent})
+	h.agentStart(ctx, currentAgent)
+
 	// Main execution loop
-	for turnCount < maxTurns {
-		turnCount++
+	for pendingTurn != nil || turnCount < maxTurns {
+		if pendingTurn == nil {
+			if err := saveCheckpoint(snapshot(nil)); err != nil {
+				return nil, err
+			}
+			turnCount++
+		}
 
-		model := r.Config.Model
-		if model == "" {
-			model = currentAgent.Model
+		modelName := r.Config.Model
+		if modelName == "" {
+			modelName = currentAgent.Model
 		}
 
 		// Get instructions
@@ -200,162 +445,254 @@ func (r Runner) run(ctx context.Context, startingAgent *agent.Agent, input types
 			}
 		}
 
+		// Handoff tools are never routed away.
+		transferTools, handoffs := handoffTools(currentAgent)
+		tools = append(tools, transferTools...)
+		setAgentSpanTools(agentSpan, tools)
+
 		modelsettings := currentAgent.ModelSettings.Resolve(r.Config.ModelSettings)
 
-		// Load session history
-		var historyItems []responses.ResponseInputItemUnionParam
-		if r.Config.Session != nil {
-			items, err := r.Config.Session.GetItems(ctx, -1)
+		outSchema, err := getOutputSchema(currentAgent)
+		if err != nil {
+			return nil, err
+		}
+
+		// Load conversation history
+		history := accumulatedHistory
+		switch {
+		case r.Config.Session != nil && handoffHistory != nil:
+			history = handoffHistory
+		case r.Config.Session != nil:
+			history, err = r.Config.Session.GetItems(ctx, -1)
 			if err != nil {
 				return nil, fmt.Errorf("load session history: %w", err)
 			}
-			historyItems = items
 		}
 
-		var modelResponse ModelResponse
-
 		// Choose API path: Responses API or Chat Completions API
-		if currentAgent.Prompt != nil {
-			// Responses API path (OpenAI only)
-			modelResponse, err = r.callResponsesAPI(ctx, currentAgent, model, instructions, tools, modelsettings, historyItems, accumulatedHistory, input, turnCount)
+		useChatCompletions := currentAgent.Prompt == nil
+
+		var modelResponse ModelResponse
+		var completed map[string]string
+		var nested map[string]*RunState
+		if pendingTurn != nil {
+			// The model answered this turn before the run was paused.
+			modelResponse.Output, err = pendingTurn.pendingOutput()
 			if err != nil {
 				return nil, err
 			}
+			completed = pendingTurn.ToolOutputs
+			nested = pendingTurn.Nested
+			pendingTurn = nil
 		} else {
-			// Chat Completions API path (OpenAI-compatible)
-			modelResponse, err = r.callChatCompletionsAPI(ctx, currentAgent, model, instructions, modelsettings, input, turnCount, result)
+			sent := history
+			if r.Config.HistoryStrategy != nil {
+				sent, err = r.Config.HistoryStrategy.Trim(ctx, history)
+				if err != nil {
+					return nil, fmt.Errorf("trim history: %w", err)
+				}
+			}
+			modelResponse, err = r.callModel(ctx, h, models, currentAgent, modelName, instructions, tools, modelsettings, outSchema, sent, events)
 			if err != nil {
 				return nil, err
 			}
+			result.RawResponses = append(result.RawResponses, modelResponse)
+			usage.record(currentAgent.Name, modelResponse.Model, modelResponse.Usage)
 		}
 
-		result.RawResponses = append(result.RawResponses, modelResponse)
-
-		// Process tool calls
+		// Model outputs come first so that tool results follow their calls in history.
+		var turnItems []responses.ResponseInputItemUnionParam
+		var toolCalls []responses.ResponseFunctionToolCall
+		var finalMessage *responses.ResponseOutputMessage
 		for _, outputItem := range modelResponse.Output {
 			switch item := outputItem.AsAny().(type) {
 			case responses.ResponseOutputMessage:
-				// Message output handled below
-			case responses.ResponseFunctionToolCall:
-				t, found := FindTool(tools, item.Name)
-				if !found {
-					errorOutput := responses.ResponseInputItemParamOfFunctionCallOutput(
-						item.CallID,
-						fmt.Sprintf("Tool %s not found", item.Name),
-					)
-					result.NewItems = append(result.NewItems, WrapRunItem(errorOutput))
-					continue
+				turnItems = append(turnItems, outputMessageToInputItem(item))
+				if finalMessage == nil {
+					finalMessage = &item
 				}
+			case responses.ResponseFunctionToolCall:
+				turnItems = append(turnItems, responses.ResponseInputItemParamOfFunctionCall(
+					item.Arguments,
+					item.CallID,
+					item.Name,
+				))
+				toolCalls = append(toolCalls, item)
+			}
+		}
 
-				toolResult, err := executeTool(ctx, currentAgent, t, item.Arguments)
+		// Process tool calls. Handoffs are resolved first, in order; only the
+		// first one of a turn is taken. The remaining calls run concurrently.
+		var nextAgent *agent.Agent
+		var handoff agent.Handoff
+		outputs := make([]responses.ResponseInputItemUnionParam, len(toolCalls))
+		var pending []int
+		for i, call := range toolCalls {
+			h, ok := handoffs[call.Name]
+			if !ok {
+				pending = append(pending, i)
+				continue
+			}
+			output := "Multiple handoffs requested. Ignoring this one."
+			if nextAgent == nil {
+				nextAgent, err = r.resolveHandoff(ctx, h)
 				if err != nil {
-					errorOutput := responses.ResponseInputItemParamOfFunctionCallOutput(
-						item.CallID,
-						fmt.Sprintf("Tool execution failed: %v", err),
-					)
-					result.NewItems = append(result.NewItems, WrapRunItem(errorOutput))
-					continue
-				}
-
-				var outputStr string
-				switch v := toolResult.(type) {
-				case string:
-					outputStr = v
-				default:
-					outputStr = fmt.Sprintf("%v", v)
+					return nil, err
 				}
-				successOutput := responses.ResponseInputItemParamOfFunctionCallOutput(
-					item.CallID,
-					outputStr,
-				)
-				result.NewItems = append(result.NewItems, WrapRunItem(successOutput))
+				handoff = h
+				output = handoffOutput(nextAgent)
 			}
+			outputs[i] = responses.ResponseInputItemParamOfFunctionCallOutput(call.CallID, output)
+			events.emit(ToolOutputEvent{CallID: call.CallID, Name: call.Name, Output: output})
 		}
 
-		// Extract final output from messages
-		for _, outputItem := range modelResponse.Output {
-			if msg, ok := outputItem.AsAny().(responses.ResponseOutputMessage); ok {
-				result.FinalOutput = msg.Content
-				break
+		// Calls of tools that need approval run only once approved. Calls of
+		// agents used as tools whose nested run paused run again to resume it.
+		var runnable []int
+		var interruptions []ToolApprovalItem
+		for _, i := range pending {
+			call := toolCalls[i]
+			if output, ok := completed[call.CallID]; ok {
+				outputs[i] = responses.ResponseInputItemParamOfFunctionCallOutput(call.CallID, output)
+				continue
+			}
+			if _, ok := nested[call.CallID]; ok || !needsApproval(tools, call.Name) {
+				runnable = append(runnable, i)
+				continue
+			}
+			decision, ok := decisions[call.CallID]
+			switch {
+			case !ok:
+				interruptions = append(interruptions, ToolApprovalItem{
+					AgentName: currentAgent.Name,
+					CallID:    call.CallID,
+					ToolName:  call.Name,
+					Arguments: call.Arguments,
+				})
+			case decision.Approved:
+				runnable = append(runnable, i)
+			default:
+				output := rejectedOutput(call, decision.Reason)
+				outputs[i] = responses.ResponseInputItemParamOfFunctionCallOutput(call.CallID, output)
+				events.emit(ToolOutputEvent{CallID: call.CallID, Name: call.Name, Output: output})
 			}
 		}
-
-		// Save tool results to session/history
-		if len(result.NewItems) > 0 {
-			var itemsToSave []responses.ResponseInputItemUnionParam
-			for _, item := range result.NewItems {
-				itemsToSave = append(itemsToSave, item.ToInputItem())
+		if len(runnable) > 0 {
+			// A run interrupted while the tools run starts again from here.
+			if err := saveCheckpoint(snapshot(newPendingTurn(modelResponse.Output, outputs, nested, nil))); err != nil {
+				return nil, err
 			}
-			if r.Config.Session != nil {
-				if err := r.Config.Session.AddItems(ctx, itemsToSave); err != nil {
-					return nil, fmt.Errorf("save tool results to session: %w", err)
-				}
-			} else {
-				accumulatedHistory = append(accumulatedHistory, itemsToSave...)
+		}
+		toolCtx := context.WithValue(ctx, nestedResumeKey{}, nestedResume{states: nested, decisions: decisions})
+		nestedPaused := make([]*RunState, len(toolCalls))
+		r.executeToolCalls(toolCtx, h, currentAgent, tools, modelsettings, toolCalls, runnable, outputs, nestedPaused, events)
+		nested = map[string]*RunState{}
+		for i, s := range nestedPaused {
+			if s != nil {
+				nested[toolCalls[i].CallID] = s
+				interruptions = append(interruptions, s.PendingTurn.Interruptions...)
 			}
 		}
 
-		// Save model output to session/history
-		if len(modelResponse.Output) > 0 {
-			var modelOutputItems []responses.ResponseInputItemUnionParam
-			for _, outputItem := range modelResponse.Output {
-				switch item := outputItem.AsAny().(type) {
-				case responses.ResponseOutputMessage:
-					outputParm := responses.ResponseOutputMessageParam{
-						ID:      item.ID,
-						Content: convertOutputContentToParam(item.Content),
-						Status:  item.Status,
-						Role:    item.Role,
-						Type:    item.Type,
-					}
-					inputItem := responses.ResponseInputItemUnionParam{
-						OfOutputMessage: &outputParm,
-					}
-					modelOutputItems = append(modelOutputItems, inputItem)
-				case responses.ResponseFunctionToolCall:
-					if r.Config.Session == nil {
-						funcCallParam := responses.ResponseInputItemParamOfFunctionCall(
-							item.Arguments,
-							item.CallID,
-							item.Name,
-						)
-						modelOutputItems = append(modelOutputItems, funcCallParam)
-					}
-				}
+		// Pause until the remaining calls are decided. Nothing of this turn is
+		// saved yet, so the history never holds a call without its output.
+		if len(interruptions) > 0 {
+			paused := snapshot(newPendingTurn(modelResponse.Output, outputs, nested, interruptions))
+			if err := saveCheckpoint(paused); err != nil {
+				return nil, err
 			}
-			if len(modelOutputItems) > 0 {
+
+			result.Interruptions = interruptions
+			result.State = paused
+			result.LastAgent = currentAgent
+			result.Usage = paused.Usage
+			return result, nil
+		}
+		turnItems = append(turnItems, outputs...)
+
+		for _, item := range turnItems {
+			result.NewItems = append(result.NewItems, WrapRunItem(item))
+		}
+
+		// Save this turn to session/history
+		if err := saveItems(turnItems); err != nil {
+			return nil, fmt.Errorf("save turn items to session: %w", err)
+		}
+
+		events.emit(TurnFinishedEvent{Turn: turnCount, Response: modelResponse})
+
+		if nextAgent != nil {
+			if handoff.InputFilter != nil {
+				filtered, err := handoff.InputFilter(ctx, agent.HandoffInputData{
+					From:    currentAgent,
+					To:      nextAgent,
+					History: append(append([]responses.ResponseInputItemUnionParam{}, history...), turnItems...),
+				})
+				if err != nil {
+					return nil, fmt.Errorf("handoff input filter: %w", err)
+				}
 				if r.Config.Session != nil {
-					if err := r.Config.Session.AddItems(ctx, modelOutputItems); err != nil {
-						return nil, fmt.Errorf("save model output to session: %w", err)
-					}
+					handoffHistory = filtered
 				} else {
-					accumulatedHistory = append(accumulatedHistory, modelOutputItems...)
+					accumulatedHistory = filtered
 				}
 			}
+			_, handoffSpan := tracing.StartSpan(ctx, &tracing.HandoffSpanData{FromAgent: currentAgent.Name, ToAgent: nextAgent.Name})
+			handoffSpan.End()
+			events.emit(AgentSwitchedEvent{Previous: currentAgent, Agent: nextAgent})
+			h.handoff(ctx, currentAgent, nextAgent)
+			currentAgent = nextAgent
+			startAgentSpan(currentAgent)
+			h.agentStart(ctx, currentAgent)
+			continue
 		}
 
-		if result.FinalOutput != nil {
+		// A message without pending tool calls is the final output.
+		if len(toolCalls) == 0 && finalMessage != nil {
+			switch {
+			case outSchema != nil:
+				parsed, err := validateFinalOutput(ctx, currentAgent, *finalMessage)
+				if err != nil {
+					if validationRetries >= r.Config.OutputValidationRetries {
+						return nil, err
+					}
+					validationRetries++
+					retry := outputRetryMessage(err)
+					result.NewItems = append(result.NewItems, WrapRunItem(retry))
+					if err := saveItems([]responses.ResponseInputItemUnionParam{retry}); err != nil {
+						return nil, fmt.Errorf("save turn items to session: %w", err)
+					}
+					continue
+				}
+				result.FinalOutput = parsed
+			case useChatCompletions, currentAgent.OutputType != nil:
+				result.FinalOutput = outputMessageText(*finalMessage)
+			default:
+				result.FinalOutput = finalMessage.Content
+			}
+			h.agentEnd(ctx, currentAgent, result.FinalOutput)
+			finished = true
 			break
 		}
 	}
 
-	if turnCount >= maxTurns {
+	if !finished {
+		// The checkpoint lets the run continue with a higher MaxTurns.
+		if err := saveCheckpoint(snapshot(nil)); err != nil {
+			return nil, err
+		}
 		return nil, &MaxTurnsExceededError{MaxTurns: maxTurns}
 	}
 
-	if result.FinalOutput == nil {
-		result.LastAgent = currentAgent
-		return result, nil
-	}
-
 	// Run output guardrails
 	outputGuardrails := append(r.Config.OutputGuardrails, currentAgent.OutputGuardrails...)
 	for _, gr := range outputGuardrails {
-		grResult, err := gr.Run(ctx, currentAgent, result.FinalOutput)
+		grResult, err := runOutputGuardrail(ctx, gr, currentAgent, result.FinalOutput)
 		if err != nil {
 			return nil, fmt.Errorf("output guardrail %q failed: %w", gr.Name, err)
 		}
 		result.OutputGuardrailResults = append(result.OutputGuardrailResults, grResult)
+		h.outputGuardrail(ctx, currentAgent, grResult)
 		if grResult.Output.TripwireTriggered {
 			return nil, &GuardrailTripwireTriggeredError{
 				GuardrailName: gr.Name,
@@ -365,146 +702,126 @@ func (r Runner) run(ctx context.Context, startingAgent *agent.Agent, input types
 		}
 	}
 
+	if r.Config.CheckpointStore != nil {
+		if err := r.Config.CheckpointStore.Delete(ctx, runID); err != nil {
+			return nil, fmt.Errorf("delete checkpoint: %w", err)
+		}
+	}
+
 	result.LastAgent = currentAgent
+	result.Usage = usage.snapshot()
 	return result, nil
 }
 
-// callResponsesAPI calls the OpenAI Responses API.
-func (r Runner) callResponsesAPI(
-	ctx context.Context,
-	currentAgent *agent.Agent,
-	model, instructions string,
-	tools []tool.Tool,
-	modelsettings agent.ModelSettings,
-	historyItems, accumulatedHistory []responses.ResponseInputItemUnionParam,
-	input types.Input,
-	turnCount uint64,
-) (ModelResponse, error) {
-	promptParam, hasPrompt, err := agent.PromptUtil().ToModelInput(ctx, currentAgent.Prompt, currentAgent)
+// rewindSession removes the items session holds after mark, i.e. those
+// saved by an interrupted run after its last checkpoint.
+func rewindSession(ctx context.Context, session memory.Session, mark *SessionMark) error {
+	stored, err := session.GetItems(ctx, -1)
 	if err != nil {
-		return ModelResponse{}, fmt.Errorf("get prompt: %w", err)
-	}
-	if !hasPrompt {
-		return ModelResponse{}, fmt.Errorf("prompt is required but not provided")
-	}
-
-	var allInputItems []responses.ResponseInputItemUnionParam
-	if len(historyItems) > 0 {
-		allInputItems = append(allInputItems, historyItems...)
-	} else {
-		allInputItems = append(allInputItems, accumulatedHistory...)
-	}
-
-	if turnCount == 1 {
-		currentInputItems := InputToItems(input)
-		allInputItems = append(allInputItems, currentInputItems...)
-	}
-
-	toolParams := ToolsToParams(tools)
-
-	createParams := responses.ResponseNewParams{
-		Model:  model,
-		Prompt: promptParam,
-		Input: responses.ResponseNewParamsInputUnion{
-			OfInputItemList: responses.ResponseInputParam(allInputItems),
-		},
-	}
-
-	if instructions != "" {
-		createParams.Instructions = param.NewOpt(instructions)
-	}
-	if len(toolParams) > 0 {
-		createParams.Tools = toolParams
+		return fmt.Errorf("load session history: %w", err)
 	}
-	if modelsettings.Temperature.Valid() {
-		createParams.Temperature = modelsettings.Temperature
-	}
-	if modelsettings.MaxTokens.Valid() {
-		createParams.MaxOutputTokens = modelsettings.MaxTokens
+	n, err := mark.position(stored)
+	if err != nil {
+		return err
 	}
-	if modelsettings.TopP.Valid() {
-		createParams.TopP = modelsettings.TopP
+	for range len(stored) - n {
+		if _, err := session.PopItem(ctx); err != nil {
+			return fmt.Errorf("remove items saved after checkpoint: %w", err)
+		}
 	}
-
-	resp, err := currentAgent.Client.Responses.New(ctx, createParams)
-	if err != nil {
-		return ModelResponse{}, fmt.Errorf("call responses API: %w", err)
-	}
-
-	return ModelResponse{
-		Output:     resp.Output,
-		ResponseID: resp.ID,
-		Usage: &Usage{
-			Requests:            1,
-			InputTokens:         uint64(resp.Usage.InputTokens),
-			InputTokensDetails:  resp.Usage.InputTokensDetails,
-			OutputTokens:        uint64(resp.Usage.OutputTokens),
-			OutputTokensDetails: resp.Usage.OutputTokensDetails,
-			TotalTokens:         uint64(resp.Usage.TotalTokens),
-		},
-	}, nil
+	return nil
 }
 
-// callChatCompletionsAPI calls the OpenAI-compatible Chat Completions API.
-func (r Runner) callChatCompletionsAPI(
+// callModel requests the next model response between the LLM hooks, under a generation span.
+func (r Runner) callModel(
 	ctx context.Context,
+	h hooks,
+	models *modelTargets,
 	currentAgent *agent.Agent,
-	model, instructions string,
+	modelName, instructions string,
+	tools []tool.Tool,
 	modelsettings agent.ModelSettings,
-	input types.Input,
-	turnCount uint64,
-	result *RunResult,
+	outSchema *model.OutputSchema,
+	history []responses.ResponseInputItemUnionParam,
+	events *eventEmitter,
 ) (ModelResponse, error) {
-	var messages []openai.ChatCompletionMessageParamUnion
-
-	if instructions != "" {
-		messages = append(messages, openai.SystemMessage(instructions))
-	}
-
-	if turnCount == 1 {
-		switch v := input.(type) {
-		case types.InputString:
-			messages = append(messages, openai.UserMessage(string(v)))
-		}
-	}
-
-	chatParams := openai.ChatCompletionNewParams{
-		Model:    model,
-		Messages: messages,
-	}
-	if modelsettings.Temperature.Valid() {
-		chatParams.Temperature = modelsettings.Temperature
-	}
-	if modelsettings.MaxTokens.Valid() {
-		chatParams.MaxTokens = modelsettings.MaxTokens
+	h.llmStart(ctx, currentAgent, instructions, history)
+	generation := &tracing.GenerationSpanData{Model: modelName, Input: history}
+	_, span := tracing.StartSpan(ctx, generation)
+	defer span.End()
+
+	req := model.Request{
+		Instructions:   instructions,
+		Input:          history,
+		Tools:          tools,
+		Settings:       modelsettings,
+		OutputSchema:   outSchema,
+		DisableRetries: r.Config.RetryPolicy.MaxAttempts > 1,
+	}
+	prompt, hasPrompt, err := agent.PromptUtil().ToModelInput(ctx, currentAgent.Prompt, currentAgent)
+	if err != nil {
+		err = fmt.Errorf("get prompt: %w", err)
+		span.SetError(err)
+		return ModelResponse{}, err
 	}
-	if modelsettings.TopP.Valid() {
-		chatParams.TopP = modelsettings.TopP
+	if hasPrompt {
+		req.Prompt = &prompt
 	}
 
-	chatresp, err := currentAgent.Client.Chat.Completions.New(ctx, chatParams)
+	modelResponse, err := r.callWithRetry(ctx, models, currentAgent, modelName, req, events)
 	if err != nil {
-		return ModelResponse{}, fmt.Errorf("call chat completions API: %w", err)
-	}
-
-	modelResponse := ModelResponse{
-		Output: []responses.ResponseOutputItemUnion{},
-		Usage: &Usage{
-			Requests:     1,
-			InputTokens:  uint64(chatresp.Usage.PromptTokens),
-			OutputTokens: uint64(chatresp.Usage.CompletionTokens),
-			TotalTokens:  uint64(chatresp.Usage.TotalTokens),
-		},
+		span.SetError(err)
+		return ModelResponse{}, err
 	}
+	generation.Model = modelResponse.Model
+	setGenerationResult(generation, modelResponse)
+	h.llmEnd(ctx, currentAgent, modelResponse)
+	return modelResponse, nil
+}
 
-	if len(chatresp.Choices) > 0 {
-		choice := chatresp.Choices[0]
-		if choice.Message.Content != "" {
-			result.FinalOutput = choice.Message.Content
+// callWithRetry sends a request to the agent's model, then to each fallback
+// model, retrying transient failures as RetryPolicy allows. A streamed
+// request is not retried once it has emitted events.
+func (r Runner) callWithRetry(
+	ctx context.Context,
+	models *modelTargets,
+	currentAgent *agent.Agent,
+	modelName string,
+	req model.Request,
+	events *eventEmitter,
+) (ModelResponse, error) {
+	targets, err := models.get(currentAgent, modelName)
+	if err != nil {
+		return ModelResponse{}, err
+	}
+	policy := r.Config.RetryPolicy
+	for _, target := range targets {
+		for attempt := 1; ; attempt++ {
+			sent := events.sent()
+			var resp *ModelResponse
+			if events != nil {
+				resp, err = target.model.StreamResponse(ctx, req, events.emitModelEvent)
+			} else {
+				resp, err = target.model.GetResponse(ctx, req)
+			}
+			if err == nil {
+				if resp.Model == "" {
+					resp.Model = target.name
+				}
+				return *resp, nil
+			}
+			if !policy.retryable(err) || events.sent() != sent {
+				return ModelResponse{}, err
+			}
+			if attempt >= policy.MaxAttempts {
+				break
+			}
+			if sleepErr := sleep(ctx, policy.delay(attempt, err)); sleepErr != nil {
+				return ModelResponse{}, fmt.Errorf("%w while retrying: %w", sleepErr, err)
+			}
 		}
 	}
-
-	return modelResponse, nil
+	return ModelResponse{}, err
 }
 
 // Helper functions
@@ -536,6 +853,41 @@ func FindTool(tools []tool.Tool, name string) (tool.Tool, bool) {
 	return nil, false
 }
 
+// executeToolCall runs a single function call and returns its function_call_output item.
+// Lookup and execution failures are reported back to the model instead of aborting the run.
+// When the tool runs an agent that pauses for approvals, the nested run's state
+// is returned instead of an output.
+func executeToolCall(ctx context.Context, a *agent.Agent, tools []tool.Tool, call responses.ResponseFunctionToolCall) (responses.ResponseInputItemUnionParam, *RunState) {
+	t, found := FindTool(tools, call.Name)
+	if !found {
+		return responses.ResponseInputItemParamOfFunctionCallOutput(
+			call.CallID,
+			fmt.Sprintf("Tool %s not found", call.Name),
+		), nil
+	}
+
+	toolResult, err := executeTool(ctx, a, t, call.Arguments)
+	if err != nil {
+		var interrupted *InterruptedError
+		if errors.As(err, &interrupted) {
+			return responses.ResponseInputItemUnionParam{}, interrupted.State
+		}
+		return responses.ResponseInputItemParamOfFunctionCallOutput(
+			call.CallID,
+			fmt.Sprintf("Tool execution failed: %v", err),
+		), nil
+	}
+
+	var outputStr string
+	switch v := toolResult.(type) {
+	case string:
+		outputStr = v
+	default:
+		outputStr = fmt.Sprintf("%v", v)
+	}
+	return responses.ResponseInputItemParamOfFunctionCallOutput(call.CallID, outputStr), nil
+}
+
 func executeTool(ctx context.Context, a *agent.Agent, t tool.Tool, arguments string) (any, error) {
 	funcTool, ok := t.(tool.FunctionTool)
 	if !ok {
@@ -554,6 +906,9 @@ func executeTool(ctx context.Context, a *agent.Agent, t tool.Tool, arguments str
 
 	result, err := funcTool.OnInvokeTool(ctx, arguments)
 	if err != nil {
+		if errors.As(err, new(*InterruptedError)) {
+			return nil, err
+		}
 		if funcTool.FailureErrorFunction != nil {
 			errorFunc := *funcTool.FailureErrorFunction
 			val, _ := errorFunc(ctx, err)
@@ -580,28 +935,40 @@ func InputToItems(input types.Input) []responses.ResponseInputItemUnionParam {
 	}
 }
 
+// ToolsToParams converts tools to Responses API tool definitions.
 func ToolsToParams(tools []tool.Tool) []responses.ToolUnionParam {
-	if len(tools) == 0 {
-		return nil
-	}
+	return model.ToolsToParams(tools)
+}
 
-	params := make([]responses.ToolUnionParam, 0, len(tools))
-	for _, t := range tools {
-		funcTool, ok := t.(tool.FunctionTool)
-		if !ok {
-			continue
-		}
-		toolParam := responses.ToolUnionParam{
-			OfFunction: &responses.FunctionToolParam{
-				Name:        funcTool.Name,
-				Description: param.NewOpt(funcTool.Description),
-				Parameters:  funcTool.ParamsJSONSchema,
-				Strict:      funcTool.StrictJSONSchema,
-			},
-		}
-		params = append(params, toolParam)
+// ToolsToChatParams converts tools to Chat Completions API tool definitions.
+func ToolsToChatParams(tools []tool.Tool) []openai.ChatCompletionToolUnionParam {
+	return model.ToolsToChatParams(tools)
+}
+
+// ItemsToChatMessages converts Responses API input items, which is what
+// memory.Session stores, into Chat Completions messages.
+// See model.ItemsToChatMessages.
+func ItemsToChatMessages(items []responses.ResponseInputItemUnionParam) []openai.ChatCompletionMessageParamUnion {
+	return model.ItemsToChatMessages(items)
+}
+
+// ChatMessagesToItems converts Chat Completions messages into Responses API
+// input items. It is the inverse of ItemsToChatMessages.
+func ChatMessagesToItems(messages []openai.ChatCompletionMessageParamUnion) []responses.ResponseInputItemUnionParam {
+	return model.ChatMessagesToItems(messages)
+}
+
+// outputMessageToInputItem converts a model output message into a history item.
+func outputMessageToInputItem(msg responses.ResponseOutputMessage) responses.ResponseInputItemUnionParam {
+	return responses.ResponseInputItemUnionParam{
+		OfOutputMessage: &responses.ResponseOutputMessageParam{
+			ID:      msg.ID,
+			Content: convertOutputContentToParam(msg.Content),
+			Status:  msg.Status,
+			Role:    msg.Role,
+			Type:    msg.Type,
+		},
 	}
-	return params
 }
 
 func convertOutputContentToParam(content []responses.ResponseOutputMessageContentUnion) []responses.ResponseOutputMessageContentUnionParam {
@@ -0,0 +1,309 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/checkpoint"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// RunState is the state of a paused or interrupted run. It marshals to JSON,
+// so a run can be paused in one process and resumed in another: agents are
+// stored by name and found again from the starting agent by LoadRunState.
+//
+// With a Session, the conversation history lives in the session and the
+// resumed run must use the same one; otherwise it is kept in History.
+type RunState struct {
+	// RunID is the RunConfig.RunID the run was checkpointed under, if any.
+	RunID         string `json:"run_id,omitempty"`
+	StartingAgent string `json:"starting_agent"`
+	CurrentAgent  string `json:"current_agent"`
+	// Turn is the number of turns taken so far.
+	Turn uint64 `json:"turn"`
+
+	// The run input: InputItems for structured input, InputText otherwise.
+	InputText  string `json:"input_text,omitempty"`
+	InputItems Items  `json:"input_items,omitempty"`
+
+	// History is the conversation so far when the run has no Session.
+	History Items `json:"history,omitempty"`
+	// HandoffHistory is the filtered history seen by the current agent after
+	// a handoff with an input filter, in a Session run.
+	HandoffHistory Items `json:"handoff_history,omitempty"`
+	// SessionMark locates the end of the Session's items when the run was
+	// checkpointed. Items the interrupted run saved after it are removed on
+	// resume, since the resumed run saves them again.
+	SessionMark *SessionMark `json:"session_mark,omitempty"`
+
+	NewItems          Items    `json:"new_items,omitempty"`
+	ValidationRetries int      `json:"validation_retries,omitempty"`
+	Usage             RunUsage `json:"usage"`
+
+	// PendingTurn is the turn waiting for tool approvals, if any.
+	PendingTurn *PendingTurn `json:"pending_turn,omitempty"`
+
+	startingAgent *agent.Agent
+}
+
+// PendingTurn is a turn whose model response has been received but whose tool
+// calls have not all run. Calls without an output run again on resume.
+type PendingTurn struct {
+	// Output holds the model's output items as returned by the API.
+	Output []json.RawMessage `json:"output"`
+	// ToolOutputs holds the outputs of the calls already run, by call ID.
+	ToolOutputs map[string]string `json:"tool_outputs,omitempty"`
+	// Nested holds, by call ID, the paused runs of agents used as tools.
+	Nested map[string]*RunState `json:"nested,omitempty"`
+	// Interruptions lists the calls waiting for approval, including those
+	// of nested runs.
+	Interruptions []ToolApprovalItem `json:"interruptions"`
+}
+
+// sessionMarkTail is how many of the last session items a SessionMark hashes.
+const sessionMarkTail = 3
+
+// SessionMark locates a position in a Session's items: after its first
+// Items items, which end with the items hashed in Tail. The tail lets the
+// position be found again after the session was compacted, e.g. by a
+// memory.SummarizingSession.
+type SessionMark struct {
+	Items int      `json:"items"`
+	Tail  []string `json:"tail,omitempty"`
+}
+
+// newSessionMark marks the end of items.
+func newSessionMark(items []responses.ResponseInputItemUnionParam) (*SessionMark, error) {
+	tail, err := hashItems(items[max(len(items)-sessionMarkTail, 0):])
+	if err != nil {
+		return nil, err
+	}
+	return &SessionMark{Items: len(items), Tail: tail}, nil
+}
+
+// position returns how many items of stored precede the mark. It fails
+// unless the tail is found at the marked position or, in a compacted
+// session, at exactly one other position.
+func (m *SessionMark) position(stored []responses.ResponseInputItemUnionParam) (int, error) {
+	hashes, err := hashItems(stored)
+	if err != nil {
+		return 0, err
+	}
+	endsAt := func(end int) bool {
+		return end >= len(m.Tail) && end <= len(hashes) && slices.Equal(hashes[end-len(m.Tail):end], m.Tail)
+	}
+	if endsAt(m.Items) {
+		return m.Items, nil
+	}
+
+	found := -1
+	if len(m.Tail) > 0 {
+		for end := len(m.Tail); end <= len(hashes); end++ {
+			if !endsAt(end) {
+				continue
+			}
+			if found >= 0 {
+				return 0, fmt.Errorf("%w: the checkpoint's last session items appear more than once", ErrInvalidRunState)
+			}
+			found = end
+		}
+	}
+	switch {
+	case found >= 0:
+		return found, nil
+	case len(stored) < m.Items:
+		return 0, fmt.Errorf("%w: session has %d items, checkpoint expects %d", ErrInvalidRunState, len(stored), m.Items)
+	default:
+		return 0, fmt.Errorf("%w: session no longer holds the checkpoint's last items", ErrInvalidRunState)
+	}
+}
+
+// hashItems returns a short hash of each item's encoding.
+func hashItems(items []responses.ResponseInputItemUnionParam) ([]string, error) {
+	hashes := make([]string, len(items))
+	for i, item := range items {
+		data, err := memory.EncodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		hashes[i] = hex.EncodeToString(sum[:8])
+	}
+	return hashes, nil
+}
+
+// Items is a list of conversation items that keeps every item variant when
+// marshaled to JSON and back.
+type Items []responses.ResponseInputItemUnionParam
+
+// MarshalJSON encodes the items with memory.EncodeItem.
+func (items Items) MarshalJSON() ([]byte, error) {
+	raw := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		data, err := memory.EncodeItem(item)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, data)
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON decodes items written by MarshalJSON.
+func (items *Items) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	decoded := make(Items, 0, len(raw))
+	for _, r := range raw {
+		item, err := memory.DecodeItem(r)
+		if err != nil {
+			return err
+		}
+		decoded = append(decoded, item)
+	}
+	*items = decoded
+	return nil
+}
+
+// LoadRunState decodes a state marshaled from RunResult.State. startingAgent
+// must be the agent the run was started with.
+func LoadRunState(startingAgent *agent.Agent, data []byte) (*RunState, error) {
+	var state RunState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRunState, err)
+	}
+	if state.StartingAgent != startingAgent.Name {
+		return nil, fmt.Errorf("%w: started with agent %q, got %q", ErrInvalidRunState, state.StartingAgent, startingAgent.Name)
+	}
+	state.startingAgent = startingAgent
+	return &state, nil
+}
+
+func (s *RunState) input() types.Input {
+	if s.InputItems != nil {
+		return types.InputItems(s.InputItems)
+	}
+	return types.InputString(s.InputText)
+}
+
+// newRunState captures the state of a run started with startingAgent and input.
+func newRunState(startingAgent *agent.Agent, input types.Input) *RunState {
+	s := &RunState{StartingAgent: startingAgent.Name, startingAgent: startingAgent}
+	switch v := input.(type) {
+	case types.InputString:
+		s.InputText = string(v)
+	default:
+		s.InputItems = Items(v.ToInputItems())
+	}
+	return s
+}
+
+// newPendingTurn captures a turn paused for approvals: the model output, the
+// outputs of the calls that already ran and the paused nested runs.
+func newPendingTurn(
+	output []responses.ResponseOutputItemUnion,
+	outputs []responses.ResponseInputItemUnionParam,
+	nested map[string]*RunState,
+	interruptions []ToolApprovalItem,
+) *PendingTurn {
+	p := &PendingTurn{ToolOutputs: map[string]string{}, Interruptions: interruptions}
+	if len(nested) > 0 {
+		p.Nested = nested
+	}
+	for _, item := range output {
+		raw := json.RawMessage(item.RawJSON())
+		if len(raw) == 0 {
+			raw, _ = json.Marshal(item)
+		}
+		p.Output = append(p.Output, raw)
+	}
+	for _, o := range outputs {
+		if o.OfFunctionCallOutput != nil {
+			p.ToolOutputs[o.OfFunctionCallOutput.CallID] = o.OfFunctionCallOutput.Output.OfString.Value
+		}
+	}
+	return p
+}
+
+// pendingOutput decodes the model output saved in a pending turn.
+func (p *PendingTurn) pendingOutput() ([]responses.ResponseOutputItemUnion, error) {
+	output := make([]responses.ResponseOutputItemUnion, 0, len(p.Output))
+	for _, raw := range p.Output {
+		var item responses.ResponseOutputItemUnion
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRunState, err)
+		}
+		output = append(output, item)
+	}
+	return output, nil
+}
+
+// findAgent looks up the agent called name among the agents reachable from
+// start through handoffs, resolving named handoffs with HandoffResolver.
+func (r Runner) findAgent(ctx context.Context, start *agent.Agent, name string) (*agent.Agent, error) {
+	seen := map[*agent.Agent]bool{}
+	resolved := map[string]bool{}
+	queue := []*agent.Agent{start}
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		if seen[a] {
+			continue
+		}
+		seen[a] = true
+		if a.Name == name {
+			return a, nil
+		}
+		for _, h := range a.Handoffs {
+			switch {
+			case h.Agent != nil:
+				queue = append(queue, h.Agent)
+			case h.AgentName == name:
+				return r.resolveHandoff(ctx, h)
+			case r.Config.HandoffResolver != nil && !resolved[h.AgentName]:
+				// Agents reached through named handoffs may hand off further.
+				resolved[h.AgentName] = true
+				if target, err := r.resolveHandoff(ctx, h); err == nil {
+					queue = append(queue, target)
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: agent %q is not reachable from %q", ErrInvalidRunState, name, start.Name)
+}
+
+// LoadCheckpoint loads the last checkpoint of runID from store, to be passed
+// to ResumeFromState. startingAgent must be the agent the run was started with.
+func LoadCheckpoint(ctx context.Context, store checkpoint.Store, startingAgent *agent.Agent, runID string) (*RunState, error) {
+	data, err := store.Load(ctx, runID)
+	if err != nil {
+		return nil, fmt.Errorf("load checkpoint: %w", err)
+	}
+	return LoadRunState(startingAgent, data)
+}
+
+// ResumeFromState continues an interrupted run using DefaultRunner.
+func ResumeFromState(ctx context.Context, state *RunState) (*RunResult, error) {
+	return DefaultRunner.ResumeFromState(ctx, state)
+}
+
+// ResumeFromState continues a run from state, usually the last checkpoint of
+// a run interrupted by a crash or restart. The turn in progress, if any, runs
+// its remaining tool calls; calls that need approval pause the run again.
+// With a Session, the run must use the same session as before.
+func (r Runner) ResumeFromState(ctx context.Context, state *RunState) (*RunResult, error) {
+	if state == nil || state.startingAgent == nil {
+		return nil, fmt.Errorf("%w: state has no starting agent, load it with LoadRunState", ErrInvalidRunState)
+	}
+	return r.resume(ctx, state, nil, nil)
+}
@@ -0,0 +1,157 @@
+package runner
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+)
+
+// StreamEvent is an event emitted while a streamed run is in progress.
+type StreamEvent interface {
+	isStreamEvent()
+}
+
+// TextDeltaEvent carries a chunk of assistant text as it is generated.
+type TextDeltaEvent struct {
+	Delta string
+}
+
+// ToolCallStartedEvent is emitted when the model starts a tool call.
+type ToolCallStartedEvent struct {
+	CallID string
+	Name   string
+}
+
+// ToolCallArgumentsDeltaEvent carries a chunk of a tool call's JSON arguments.
+type ToolCallArgumentsDeltaEvent struct {
+	CallID string
+	Delta  string
+}
+
+// ToolOutputEvent is emitted after a tool has run, with the output sent back to the model.
+type ToolOutputEvent struct {
+	CallID string
+	Name   string
+	Output string
+}
+
+// AgentSwitchedEvent is emitted when the run starts and whenever the current agent changes.
+// Previous is nil for the starting agent.
+type AgentSwitchedEvent struct {
+	Previous *agent.Agent
+	Agent    *agent.Agent
+}
+
+// TurnFinishedEvent is emitted after each model turn, once its tool calls have run.
+type TurnFinishedEvent struct {
+	Turn     uint64
+	Response ModelResponse
+}
+
+// FinalResultEvent is the last event of a successful run. The run may also
+// have paused for tool approvals, in which case Result.Interruptions is set.
+type FinalResultEvent struct {
+	Result *RunResult
+}
+
+func (TextDeltaEvent) isStreamEvent()              {}
+func (ToolCallStartedEvent) isStreamEvent()        {}
+func (ToolCallArgumentsDeltaEvent) isStreamEvent() {}
+func (ToolOutputEvent) isStreamEvent()             {}
+func (AgentSwitchedEvent) isStreamEvent()          {}
+func (TurnFinishedEvent) isStreamEvent()           {}
+func (FinalResultEvent) isStreamEvent()            {}
+
+// DefaultStreamBufferSize is the capacity of the event channel of a streamed run.
+const DefaultStreamBufferSize = 64
+
+// RunResultStreaming is a run in progress started by RunStreamed.
+type RunResultStreaming struct {
+	events chan StreamEvent
+	done   chan struct{}
+	result *RunResult
+	err    error
+}
+
+// Events returns the event channel. It is closed when the run ends.
+func (s *RunResultStreaming) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// Wait drains any unread events, waits for the run to end and returns its result.
+func (s *RunResultStreaming) Wait() (*RunResult, error) {
+	for range s.events {
+	}
+	<-s.done
+	return s.result, s.err
+}
+
+// RunStreamed executes the agent with a string input using DefaultRunner, streaming events.
+func RunStreamed(ctx context.Context, startingAgent *agent.Agent, input string) *RunResultStreaming {
+	return DefaultRunner.RunStreamed(ctx, startingAgent, input)
+}
+
+// RunStreamed executes the agent in the background and streams its progress.
+// Callers must either consume Events until it is closed or call Wait.
+func (r Runner) RunStreamed(ctx context.Context, startingAgent *agent.Agent, input string) *RunResultStreaming {
+	return r.runStreamed(ctx, startingAgent, types.InputString(input))
+}
+
+func (r Runner) runStreamed(ctx context.Context, startingAgent *agent.Agent, input types.Input) *RunResultStreaming {
+	s := &RunResultStreaming{
+		events: make(chan StreamEvent, DefaultStreamBufferSize),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		defer close(s.events)
+
+		emitter := &eventEmitter{ctx: ctx, ch: s.events}
+		s.result, s.err = r.run(ctx, startingAgent, input, emitter)
+		if s.err == nil {
+			emitter.emit(FinalResultEvent{Result: s.result})
+		}
+	}()
+	return s
+}
+
+// eventEmitter delivers events of a streamed run. A nil emitter discards events.
+type eventEmitter struct {
+	ctx   context.Context
+	ch    chan<- StreamEvent
+	count atomic.Int64
+}
+
+func (e *eventEmitter) emit(ev StreamEvent) {
+	if e == nil {
+		return
+	}
+	e.count.Add(1)
+	select {
+	case e.ch <- ev:
+	case <-e.ctx.Done():
+	}
+}
+
+// emitModelEvent forwards an event of a streamed model response.
+func (e *eventEmitter) emitModelEvent(ev model.StreamEvent) {
+	switch ev := ev.(type) {
+	case model.TextDelta:
+		e.emit(TextDeltaEvent{Delta: ev.Delta})
+	case model.ToolCallStarted:
+		e.emit(ToolCallStartedEvent{CallID: ev.CallID, Name: ev.Name})
+	case model.ToolCallArgumentsDelta:
+		e.emit(ToolCallArgumentsDeltaEvent{CallID: ev.CallID, Delta: ev.Delta})
+	}
+}
+
+// sent reports how many events have been emitted so far.
+func (e *eventEmitter) sent() int64 {
+	if e == nil {
+		return 0
+	}
+	return e.count.Load()
+}
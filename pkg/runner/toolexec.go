@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/tracing"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// executeToolCalls runs calls[i] for every i in pending and stores its
+// function_call_output item in outputs[i], so outputs keep the order of the
+// model's calls however the executions interleave. A call whose tool ran an
+// agent that paused for approvals gets no output; the nested run's state is
+// stored in paused[i] instead.
+//
+// Calls run concurrently up to RunConfig.MaxToolConcurrency, or one at a time
+// when the model settings disable parallel tool calls. Each call gets its own
+// context, cancelled when the call returns or ToolCallTimeout elapses.
+func (r Runner) executeToolCalls(
+	ctx context.Context,
+	h hooks,
+	a *agent.Agent,
+	tools []tool.Tool,
+	modelsettings agent.ModelSettings,
+	calls []responses.ResponseFunctionToolCall,
+	pending []int,
+	outputs []responses.ResponseInputItemUnionParam,
+	paused []*RunState,
+	events *eventEmitter,
+) {
+	limit := r.Config.MaxToolConcurrency
+	if modelsettings.ParallelToolCalls.Valid() && !modelsettings.ParallelToolCalls.Value {
+		limit = 1
+	}
+	if limit <= 0 || limit > len(pending) {
+		limit = len(pending)
+	}
+
+	sem := make(chan struct{}, max(limit, 1))
+	var wg sync.WaitGroup
+	for _, i := range pending {
+		call := calls[i]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// Runs started by the tool, e.g. agents used as tools, nest under its span.
+			data := &tracing.FunctionSpanData{Name: call.Name, Input: call.Arguments}
+			callCtx, span := tracing.StartSpan(ctx, data)
+			defer span.End()
+			callCtx = context.WithValue(callCtx, toolCallKey{}, call.CallID)
+
+			h.toolStart(callCtx, a, call)
+			outputs[i], paused[i] = r.executeToolCallWithTimeout(callCtx, a, tools, call)
+			if paused[i] != nil {
+				return
+			}
+			output := outputs[i].OfFunctionCallOutput.Output.OfString.Value
+			data.Output = output
+			h.toolEnd(callCtx, a, call, output)
+			events.emit(ToolOutputEvent{CallID: call.CallID, Name: call.Name, Output: output})
+		}()
+	}
+	wg.Wait()
+}
+
+// executeToolCallWithTimeout runs a call under its own context. When that
+// context ends first, the call is reported as failed once the tool has
+// returned, or once ToolCancelGracePeriod has elapsed if it is set.
+func (r Runner) executeToolCallWithTimeout(
+	ctx context.Context,
+	a *agent.Agent,
+	tools []tool.Tool,
+	call responses.ResponseFunctionToolCall,
+) (responses.ResponseInputItemUnionParam, *RunState) {
+	var callCtx context.Context
+	var cancel context.CancelFunc
+	if r.Config.ToolCallTimeout > 0 {
+		callCtx, cancel = context.WithTimeout(ctx, r.Config.ToolCallTimeout)
+	} else {
+		callCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	type result struct {
+		output responses.ResponseInputItemUnionParam
+		paused *RunState
+	}
+	done := make(chan result, 1)
+	go func() {
+		output, paused := executeToolCall(callCtx, a, tools, call)
+		done <- result{output, paused}
+	}()
+
+	select {
+	case res := <-done:
+		return res.output, res.paused
+	case <-callCtx.Done():
+	}
+
+	// Wait for the tool to stop so its side effects do not outlive the turn.
+	failed := responses.ResponseInputItemParamOfFunctionCallOutput(
+		call.CallID,
+		fmt.Sprintf("Tool execution failed: %v", callCtx.Err()),
+	)
+	cancel()
+	if r.Config.ToolCancelGracePeriod <= 0 {
+		<-done
+		return failed, nil
+	}
+	timer := time.NewTimer(r.Config.ToolCancelGracePeriod)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+	return failed, nil
+}
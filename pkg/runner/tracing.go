@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/tracing"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+)
+
+// startTrace starts the run's trace, named after RunConfig.WorkflowName.
+// A run nested in a traced run, such as an agent used as a tool, records its
+// spans in the outer trace instead of starting its own.
+func (r Runner) startTrace(ctx context.Context) (context.Context, *tracing.Trace) {
+	if r.Config.TracingDisabled {
+		return tracing.WithoutTrace(ctx), nil
+	}
+	if tracing.CurrentTrace(ctx) != nil {
+		return ctx, nil
+	}
+	provider := r.Config.TraceProvider
+	if provider == nil {
+		provider = tracing.DefaultProvider()
+	}
+	workflowName := r.Config.WorkflowName
+	if workflowName == "" {
+		workflowName = DefaultWorkflowName
+	}
+	return provider.StartTrace(ctx, workflowName, tracing.TraceOptions{
+		GroupID:  r.Config.GroupID,
+		Metadata: r.Config.TraceMetadata,
+	})
+}
+
+func newAgentSpanData(a *agent.Agent) *tracing.AgentSpanData {
+	data := &tracing.AgentSpanData{Name: a.Name}
+	for _, h := range a.Handoffs {
+		data.Handoffs = append(data.Handoffs, h.TargetName())
+	}
+	if a.OutputType != nil {
+		data.OutputType = a.OutputType.Name()
+	}
+	return data
+}
+
+func setAgentSpanTools(span *tracing.Span, tools []tool.Tool) {
+	if span == nil {
+		return
+	}
+	data := span.Data.(*tracing.AgentSpanData)
+	data.Tools = data.Tools[:0]
+	for _, t := range tools {
+		data.Tools = append(data.Tools, t.ToolName())
+	}
+}
+
+// setGenerationResult records the model output and token usage on a generation span.
+func setGenerationResult(data *tracing.GenerationSpanData, resp ModelResponse) {
+	output := make([]json.RawMessage, 0, len(resp.Output))
+	for _, item := range resp.Output {
+		output = append(output, json.RawMessage(item.RawJSON()))
+	}
+	data.Output = output
+	if resp.Usage != nil {
+		data.Usage = &tracing.Usage{
+			InputTokens:  resp.Usage.InputTokens,
+			OutputTokens: resp.Usage.OutputTokens,
+		}
+	}
+}
+
+func runInputGuardrail(ctx context.Context, gr agent.InputGuardrail, a *agent.Agent, input types.Input) (agent.InputGuardrailResult, error) {
+	data := &tracing.GuardrailSpanData{Name: gr.Name}
+	ctx, span := tracing.StartSpan(ctx, data)
+	defer span.End()
+	result, err := gr.Run(ctx, a, input)
+	span.SetError(err)
+	data.Triggered = result.Output.TripwireTriggered
+	return result, err
+}
+
+func runOutputGuardrail(ctx context.Context, gr agent.OutputGuardrail, a *agent.Agent, output any) (agent.OutputGuardrailResult, error) {
+	data := &tracing.GuardrailSpanData{Name: gr.Name}
+	ctx, span := tracing.StartSpan(ctx, data)
+	defer span.End()
+	result, err := gr.Run(ctx, a, output)
+	span.SetError(err)
+	data.Triggered = result.Output.TripwireTriggered
+	return result, err
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// RunUsage is the token usage of a whole run: every turn of every agent that
+// took part through handoffs, plus nested runs started from its tool calls,
+// such as agents wrapped with pattern.WrapAgentAsTool.
+type RunUsage struct {
+	Usage
+
+	// ByAgent breaks the total down by agent name.
+	ByAgent map[string]Usage
+	// ByModel breaks the total down by model name.
+	ByModel map[string]Usage
+}
+
+func (u *RunUsage) add(agentName, model string, usage Usage) {
+	u.Usage.Add(usage)
+	if u.ByAgent == nil {
+		u.ByAgent = make(map[string]Usage)
+		u.ByModel = make(map[string]Usage)
+	}
+	perAgent := u.ByAgent[agentName]
+	perAgent.Add(usage)
+	u.ByAgent[agentName] = perAgent
+	perModel := u.ByModel[model]
+	perModel.Add(usage)
+	u.ByModel[model] = perModel
+}
+
+// usageRecorder collects the usage of one run. Usage recorded on a nested
+// run's recorder is also added to every enclosing run.
+type usageRecorder struct {
+	mu     sync.Mutex
+	usage  RunUsage
+	parent *usageRecorder
+}
+
+type usageRecorderKey struct{}
+
+// withUsageRecorder returns a context carrying a new recorder, nested under the
+// recorder already in ctx, if any.
+func withUsageRecorder(ctx context.Context) (context.Context, *usageRecorder) {
+	parent, _ := ctx.Value(usageRecorderKey{}).(*usageRecorder)
+	rec := &usageRecorder{parent: parent}
+	return context.WithValue(ctx, usageRecorderKey{}, rec), rec
+}
+
+func (r *usageRecorder) record(agentName, model string, usage *Usage) {
+	if usage == nil {
+		return
+	}
+	for rec := r; rec != nil; rec = rec.parent {
+		rec.mu.Lock()
+		rec.usage.add(agentName, model, *usage)
+		rec.mu.Unlock()
+	}
+}
+
+func (r *usageRecorder) snapshot() RunUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return RunUsage{
+		Usage:   r.usage.Usage,
+		ByAgent: maps.Clone(r.usage.ByAgent),
+		ByModel: maps.Clone(r.usage.ByModel),
+	}
+}
+
+// restore seeds the recorder with the usage of a paused run.
+func (r *usageRecorder) restore(u RunUsage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usage = RunUsage{
+		Usage:   u.Usage,
+		ByAgent: maps.Clone(u.ByAgent),
+		ByModel: maps.Clone(u.ByModel),
+	}
+}
@@ -0,0 +1,162 @@
+// Package cassette records the LLM and MCP traffic of a run to a file and
+// replays it, so a run captured once against real services can be repeated
+// in CI without network access.
+//
+// In ModeRecord, Transport and WrapMCPServer forward calls to the real
+// services and record them; Save writes the cassette. In ModeReplay they
+// answer from the cassette: requests are matched by method, URL path and
+// normalized body, and a request with no recorded match fails with
+// ErrNoMatch instead of reaching the network.
+//
+//	c, err := cassette.Open("testdata/run.json", cassette.ModeReplay)
+//	client := openai.NewClient(option.WithHTTPClient(c.HTTPClient()))
+//
+// Request headers, which carry API keys, are never recorded.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Mode selects whether a Cassette records or replays.
+type Mode int
+
+const (
+	// ModeReplay answers from the cassette and never reaches the network.
+	ModeReplay Mode = iota
+	// ModeRecord forwards calls to the real services and records them.
+	ModeRecord
+)
+
+// ErrNoMatch is returned in ModeReplay for a request the cassette has no
+// unused recording of.
+var ErrNoMatch = errors.New("cassette: no recorded interaction matches the request")
+
+// Cassette is a set of recorded interactions backed by a JSON file. It is
+// safe for concurrent use.
+type Cassette struct {
+	path string
+	mode Mode
+
+	mu        sync.Mutex
+	data      file
+	used      map[int]bool
+	usedMCP   map[int]bool
+	unmatched []string
+}
+
+// file is the on-disk format of a cassette.
+type file struct {
+	HTTP []HTTPInteraction `json:"http,omitempty"`
+	MCP  []MCPInteraction  `json:"mcp,omitempty"`
+}
+
+// HTTPInteraction is a recorded HTTP request and its response.
+type HTTPInteraction struct {
+	Request  HTTPRequest  `json:"request"`
+	Response HTTPResponse `json:"response"`
+}
+
+// HTTPRequest is the part of a request used for matching. URL holds the
+// path and query only, so a cassette replays against any base URL.
+type HTTPRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// HTTPResponse is a recorded response.
+type HTTPResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   string              `json:"body"`
+}
+
+// MCPInteraction is a recorded call to an MCP server.
+type MCPInteraction struct {
+	Server string          `json:"server"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Open returns a cassette backed by path. In ModeReplay the file is loaded
+// and must exist; in ModeRecord recording starts empty and Save overwrites it.
+func Open(path string, mode Mode) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, used: map[int]bool{}, usedMCP: map[int]bool{}}
+	if mode == ModeRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.data); err != nil {
+		return nil, fmt.Errorf("decode cassette %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Mode returns the mode the cassette was opened in.
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Save writes the recorded interactions to the cassette file. It does
+// nothing in ModeReplay.
+func (c *Cassette) Save() error {
+	if c.mode != ModeRecord {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.data, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+// Unmatched describes the requests that found no recording in ModeReplay.
+func (c *Cassette) Unmatched() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.unmatched...)
+}
+
+// noMatch records an unmatched request and returns its error.
+func (c *Cassette) noMatch(desc string) error {
+	c.mu.Lock()
+	c.unmatched = append(c.unmatched, desc)
+	c.mu.Unlock()
+	return fmt.Errorf("%w: %s", ErrNoMatch, desc)
+}
+
+// normalize returns a JSON body re-encoded with sorted keys and no
+// insignificant whitespace; other bodies are returned unchanged.
+func normalize(body []byte) string {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return string(body)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(out)
+}
@@ -0,0 +1,101 @@
+package cassette
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Transport returns a RoundTripper that records through next, or
+// http.DefaultTransport when next is nil, in ModeRecord, and replays from the
+// cassette in ModeReplay.
+func (c *Cassette) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{cassette: c, next: next}
+}
+
+// HTTPClient returns a client using Transport(nil), e.g. for
+// option.WithHTTPClient.
+func (c *Cassette) HTTPClient() *http.Client {
+	return &http.Client{Transport: c.Transport(nil)}
+}
+
+type transport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: read request body: %w", err)
+		}
+	}
+	recorded := HTTPRequest{Method: req.Method, URL: req.URL.RequestURI(), Body: normalize(body)}
+
+	if t.cassette.mode == ModeReplay {
+		resp, ok := t.cassette.replayHTTP(recorded)
+		if !ok {
+			return nil, t.cassette.noMatch(fmt.Sprintf("%s %s %s", recorded.Method, recorded.URL, recorded.Body))
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+			StatusCode:    resp.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header(resp.Header).Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(resp.Body))),
+			ContentLength: int64(len(resp.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	resp, err := t.next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	t.cassette.mu.Lock()
+	t.cassette.data.HTTP = append(t.cassette.data.HTTP, HTTPInteraction{
+		Request:  recorded,
+		Response: HTTPResponse{Status: resp.StatusCode, Header: header, Body: string(respBody)},
+	})
+	t.cassette.mu.Unlock()
+	return resp, nil
+}
+
+// replayHTTP returns the response of the first unused recording of req.
+func (c *Cassette) replayHTTP(req HTTPRequest) (HTTPResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.data.HTTP {
+		if !c.used[i] && in.Request == req {
+			c.used[i] = true
+			return in.Response, true
+		}
+	}
+	return HTTPResponse{}, false
+}
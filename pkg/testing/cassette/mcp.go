@@ -0,0 +1,129 @@
+package cassette
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// WrapMCPServer returns server wrapped to record its calls in ModeRecord and
+// answer them from the cassette in ModeReplay, where server is never
+// connected to and only its Name and UseStructuredContent are used.
+func (c *Cassette) WrapMCPServer(server tool.MCPServer) tool.MCPServer {
+	return &mcpServer{MCPServer: server, cassette: c}
+}
+
+type mcpServer struct {
+	tool.MCPServer
+	cassette *Cassette
+}
+
+func (s *mcpServer) Connect(ctx context.Context) error {
+	if s.cassette.mode == ModeReplay {
+		return nil
+	}
+	return s.MCPServer.Connect(ctx)
+}
+
+func (s *mcpServer) Cleanup(ctx context.Context) error {
+	if s.cassette.mode == ModeReplay {
+		return nil
+	}
+	return s.MCPServer.Cleanup(ctx)
+}
+
+func (s *mcpServer) ListTools(ctx context.Context, a types.AgentLike) ([]*mcp.Tool, error) {
+	// Tool filters may depend on the agent, so its name is part of the match.
+	var params map[string]string
+	if a != nil {
+		params = map[string]string{"agent": a.GetName()}
+	}
+	return mcpCall(s, "tools/list", params, func() ([]*mcp.Tool, error) {
+		return s.MCPServer.ListTools(ctx, a)
+	})
+}
+
+func (s *mcpServer) CallTool(ctx context.Context, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	params := map[string]any{"name": name, "arguments": args}
+	return mcpCall(s, "tools/call", params, func() (*mcp.CallToolResult, error) {
+		return s.MCPServer.CallTool(ctx, name, args)
+	})
+}
+
+func (s *mcpServer) ListPrompts(ctx context.Context) (*mcp.ListPromptsResult, error) {
+	return mcpCall(s, "prompts/list", nil, func() (*mcp.ListPromptsResult, error) {
+		return s.MCPServer.ListPrompts(ctx)
+	})
+}
+
+func (s *mcpServer) GetPrompt(ctx context.Context, name string, args map[string]string) (*mcp.GetPromptResult, error) {
+	params := map[string]any{"name": name, "arguments": args}
+	return mcpCall(s, "prompts/get", params, func() (*mcp.GetPromptResult, error) {
+		return s.MCPServer.GetPrompt(ctx, name, args)
+	})
+}
+
+// mcpCall records or replays one call of method with params.
+func mcpCall[T any](s *mcpServer, method string, params any, call func() (T, error)) (T, error) {
+	var zero T
+	var rawParams json.RawMessage
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return zero, fmt.Errorf("cassette: encode %s params: %w", method, err)
+		}
+		rawParams = json.RawMessage(normalize(data))
+	}
+	recorded := MCPInteraction{Server: s.Name(), Method: method, Params: rawParams}
+
+	c := s.cassette
+	if c.mode == ModeReplay {
+		in, ok := c.replayMCP(recorded)
+		if !ok {
+			return zero, c.noMatch(fmt.Sprintf("mcp %s %s %s", recorded.Server, method, rawParams))
+		}
+		if in.Error != "" {
+			return zero, errors.New(in.Error)
+		}
+		var out T
+		if err := json.Unmarshal(in.Result, &out); err != nil {
+			return zero, fmt.Errorf("cassette: decode %s result: %w", method, err)
+		}
+		return out, nil
+	}
+
+	out, err := call()
+	if err != nil {
+		recorded.Error = err.Error()
+	} else {
+		data, mErr := json.Marshal(out)
+		if mErr != nil {
+			return out, fmt.Errorf("cassette: encode %s result: %w", method, mErr)
+		}
+		recorded.Result = data
+	}
+	c.mu.Lock()
+	c.data.MCP = append(c.data.MCP, recorded)
+	c.mu.Unlock()
+	return out, err
+}
+
+// replayMCP returns the first unused recording of the call in.
+func (c *Cassette) replayMCP(in MCPInteraction) (MCPInteraction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, rec := range c.data.MCP {
+		if !c.usedMCP[i] && rec.Server == in.Server && rec.Method == in.Method &&
+			normalize(rec.Params) == normalize(in.Params) {
+			c.usedMCP[i] = true
+			return rec, true
+		}
+	}
+	return MCPInteraction{}, false
+}
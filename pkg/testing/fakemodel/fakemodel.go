@@ -0,0 +1,241 @@
+// Package fakemodel provides a scripted model for testing agents offline.
+//
+// A Model answers each call with the next Turn of its script: messages, tool
+// calls, an error, and the usage to report. It records every request so
+// tests can assert on what the runner sent, and doubles as a model.Provider:
+//
+//	m := fakemodel.New(
+//		fakemodel.Output(fakemodel.ToolCall("call_1", "calculator", `{"operation":"add","a":1,"b":1}`)),
+//		fakemodel.Text("1 + 1 = 2"),
+//	)
+//	r := runner.Runner{Config: runner.RunConfig{ModelProvider: m}}
+package fakemodel
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// ErrScriptExhausted is returned by a call made after the last scripted turn.
+var ErrScriptExhausted = errors.New("fakemodel: no scripted turn left")
+
+// Turn is the scripted result of one model call.
+type Turn struct {
+	// Output holds the items returned, built with Message, ToolCall and Handoff.
+	Output []responses.ResponseOutputItemUnion
+	// Err, when set, is returned instead of a response.
+	Err error
+	// Usage is reported with the response. Nil reports one request and no tokens.
+	Usage *model.Usage
+	// Expect checks the request before the turn is played; an error fails the call.
+	Expect func(req model.Request) error
+}
+
+// Text returns a turn answering with a single message.
+func Text(text string) Turn {
+	return Turn{Output: []responses.ResponseOutputItemUnion{Message(text)}}
+}
+
+// Output returns a turn answering with items.
+func Output(items ...responses.ResponseOutputItemUnion) Turn {
+	return Turn{Output: items}
+}
+
+// Error returns a turn failing with err.
+func Error(err error) Turn {
+	return Turn{Err: err}
+}
+
+// WithUsage returns t reporting usage.
+func (t Turn) WithUsage(usage model.Usage) Turn {
+	t.Usage = &usage
+	return t
+}
+
+// Expecting returns t checking its request with check.
+func (t Turn) Expecting(check func(req model.Request) error) Turn {
+	t.Expect = check
+	return t
+}
+
+// Message returns an assistant message output item.
+func Message(text string) responses.ResponseOutputItemUnion {
+	return outputItem(map[string]any{
+		"id":     "msg_fake",
+		"type":   "message",
+		"role":   "assistant",
+		"status": "completed",
+		"content": []map[string]any{{
+			"type":        "output_text",
+			"text":        text,
+			"annotations": []any{},
+		}},
+	})
+}
+
+// ToolCall returns a function call output item.
+func ToolCall(callID, name, arguments string) responses.ResponseOutputItemUnion {
+	return outputItem(map[string]any{
+		"id":        "fc_" + callID,
+		"type":      "function_call",
+		"call_id":   callID,
+		"name":      name,
+		"arguments": arguments,
+		"status":    "completed",
+	})
+}
+
+// Handoff returns a call of the default handoff tool to the agent called agentName.
+func Handoff(callID, agentName string) responses.ResponseOutputItemUnion {
+	return ToolCall(callID, agent.HandoffToolName(agentName), "{}")
+}
+
+func outputItem(raw map[string]any) responses.ResponseOutputItemUnion {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		panic(fmt.Errorf("fakemodel: marshal output item: %w", err))
+	}
+	var item responses.ResponseOutputItemUnion
+	if err := json.Unmarshal(data, &item); err != nil {
+		panic(fmt.Errorf("fakemodel: unmarshal output item: %w", err))
+	}
+	return item
+}
+
+// Model is a model.Model playing a script of turns, one per call. It is safe
+// for concurrent use.
+type Model struct {
+	mu       sync.Mutex
+	turns    []Turn
+	requests []model.Request
+}
+
+// New returns a model playing turns in order.
+func New(turns ...Turn) *Model {
+	return &Model{turns: turns}
+}
+
+// Add appends turns to the script.
+func (m *Model) Add(turns ...Turn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.turns = append(m.turns, turns...)
+}
+
+// GetModel implements model.Provider: every name resolves to m.
+func (m *Model) GetModel(string) (model.Model, error) {
+	return m, nil
+}
+
+// GetResponse implements model.Model by playing the next turn.
+func (m *Model) GetResponse(ctx context.Context, req model.Request) (*model.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	n := len(m.requests)
+	if len(m.turns) == 0 {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: call %d", ErrScriptExhausted, n)
+	}
+	turn := m.turns[0]
+	m.turns = m.turns[1:]
+	m.mu.Unlock()
+
+	if turn.Expect != nil {
+		if err := turn.Expect(req); err != nil {
+			return nil, fmt.Errorf("fakemodel: unexpected request in call %d: %w", n, err)
+		}
+	}
+	if turn.Err != nil {
+		return nil, turn.Err
+	}
+
+	usage := model.Usage{Requests: 1}
+	if turn.Usage != nil {
+		usage = *turn.Usage
+	}
+	return &model.Response{
+		Output:     turn.Output,
+		Usage:      &usage,
+		ResponseID: fmt.Sprintf("resp_fake_%d", n),
+	}, nil
+}
+
+// StreamResponse implements model.Model by playing the next turn, emitting
+// each message's text and each tool call in one event apiece.
+func (m *Model) StreamResponse(ctx context.Context, req model.Request, emit func(model.StreamEvent)) (*model.Response, error) {
+	resp, err := m.GetResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range resp.Output {
+		switch v := item.AsAny().(type) {
+		case responses.ResponseOutputMessage:
+			for _, c := range v.Content {
+				if text, ok := c.AsAny().(responses.ResponseOutputText); ok {
+					emit(model.TextDelta{Delta: text.Text})
+				}
+			}
+		case responses.ResponseFunctionToolCall:
+			emit(model.ToolCallStarted{CallID: v.CallID, Name: v.Name})
+			emit(model.ToolCallArgumentsDelta{CallID: v.CallID, Delta: v.Arguments})
+		}
+	}
+	return resp, nil
+}
+
+// Requests returns the requests received so far.
+func (m *Model) Requests() []model.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]model.Request(nil), m.requests...)
+}
+
+// Remaining returns the number of turns not played yet.
+func (m *Model) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.turns)
+}
+
+// Provider resolves model names to models, e.g. a scripted Model per agent.
+type Provider map[string]model.Model
+
+// GetModel implements model.Provider.
+func (p Provider) GetModel(name string) (model.Model, error) {
+	m, ok := p[name]
+	if !ok {
+		return nil, fmt.Errorf("fakemodel: no model called %q", name)
+	}
+	return m, nil
+}
+
+// ToolNames returns the names of the tools sent with req.
+func ToolNames(req model.Request) []string {
+	names := make([]string, 0, len(req.Tools))
+	for _, t := range req.Tools {
+		names = append(names, t.ToolName())
+	}
+	return names
+}
+
+// ToolOutput returns the output of the tool call callID found in the input of req.
+func ToolOutput(req model.Request, callID string) (string, bool) {
+	for _, item := range req.Input {
+		if o := item.OfFunctionCallOutput; o != nil && o.CallID == callID {
+			return o.Output.OfString.Value, true
+		}
+	}
+	return "", false
+}
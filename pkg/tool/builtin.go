@@ -12,41 +12,23 @@ import (
 
 // NewTimeTool creates a tool that returns the current time.
 func NewTimeTool() FunctionTool {
-	return FunctionTool{
-		Name:        "get_current_time",
-		Description: "Get the current date and time",
-		ParamsJSONSchema: map[string]any{
-			"type": "object",
-			"properties": map[string]any{
-				"timezone": map[string]any{
-					"type":        "string",
-					"description": "Timezone name (e.g., 'Asia/Shanghai', 'UTC'). Default is local timezone.",
-				},
-			},
-		},
-		OnInvokeTool: func(ctx context.Context, arguments string) (any, error) { // 完整签名
-			var params struct {
-				Timezone string `json:"timezone"`
-			}
-			if arguments != "" {
-				json.Unmarshal([]byte(arguments), &params)
-			}
-
-			var loc *time.Location
-			var err error
+	type timeArgs struct {
+		Timezone string `json:"timezone,omitempty" jsonschema:"description=Timezone name (e.g., 'Asia/Shanghai', 'UTC'). Default is local timezone."`
+	}
+	return NewFunctionTool("get_current_time", "Get the current date and time",
+		func(ctx context.Context, params timeArgs) (string, error) {
+			loc := time.Local
 			if params.Timezone != "" {
+				var err error
 				loc, err = time.LoadLocation(params.Timezone)
 				if err != nil {
-					return nil, fmt.Errorf("invalid timezone: %s", params.Timezone)
+					return "", fmt.Errorf("invalid timezone: %s", params.Timezone)
 				}
-			} else {
-				loc = time.Local
 			}
 
 			now := time.Now().In(loc)
 			return fmt.Sprintf("Current time: %s", now.Format("2006-01-02 15:04:05 MST")), nil
-		},
-	}
+		})
 }
 
 // NewWebFetchTool creates a tool that fetches content from a URL.
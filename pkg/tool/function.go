@@ -0,0 +1,100 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/chuanbosi666/agent_go/internal/reflectschema"
+	"github.com/chuanbosi666/agent_go/internal/strictschema"
+
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+// NewFunctionTool creates a FunctionTool from a typed Go function.
+//
+// The strict parameter schema is derived from the Args struct: property names
+// follow `json` tags, pointer and omitempty fields are optional, and `jsonschema`
+// tags add keywords, e.g. `jsonschema:"description=Search query,enum=a|b"`.
+// Arguments from the model are checked against the required fields and decoded
+// into Args. A string result is returned as is; any other result is sent to the
+// model as JSON.
+//
+// NewFunctionTool panics if Args is not a struct or has no JSON schema representation.
+func NewFunctionTool[Args any, Out any](name, description string, fn func(ctx context.Context, args Args) (Out, error)) FunctionTool {
+	argsType := reflect.TypeFor[Args]()
+	if argsType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("tool %q: Args must be a struct, got %s", name, argsType))
+	}
+	// The non-strict schema tells which arguments the model must send.
+	loose, err := reflectschema.Generate(argsType, false)
+	if err != nil {
+		panic(fmt.Sprintf("tool %q: %v", name, err))
+	}
+	schema, err := reflectschema.Generate(argsType, true)
+	if err == nil {
+		schema, err = strictschema.EnsureStrictJSONSchema(schema)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("tool %q: %v", name, err))
+	}
+	required, _ := loose["required"].([]string)
+
+	return FunctionTool{
+		Name:             name,
+		Description:      description,
+		ParamsJSONSchema: schema,
+		StrictJSONSchema: param.NewOpt(true),
+		OnInvokeTool: func(ctx context.Context, arguments string) (any, error) {
+			args, err := decodeArguments[Args](arguments, required)
+			if err != nil {
+				return nil, err
+			}
+			out, err := fn(ctx, args)
+			if err != nil {
+				return nil, err
+			}
+			return encodeResult(out)
+		},
+	}
+}
+
+// decodeArguments validates the model's arguments and decodes them into Args.
+func decodeArguments[Args any](arguments string, required []string) (Args, error) {
+	var args Args
+	if arguments == "" {
+		arguments = "{}"
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(arguments), &fields); err != nil {
+		return args, fmt.Errorf("invalid arguments: %w", err)
+	}
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			return args, fmt.Errorf("invalid arguments: missing required argument %q", name)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(arguments)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&args); err != nil {
+		return args, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return args, nil
+}
+
+// encodeResult converts a tool result into the text sent to the model.
+func encodeResult(out any) (any, error) {
+	v := reflect.ValueOf(out)
+	if v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("marshal tool result: %w", err)
+	}
+	return string(data), nil
+}
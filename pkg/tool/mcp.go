@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/chuanbosi666/agent_go/internal/strictschema"
+	"github.com/chuanbosi666/agent_go/pkg/tracing"
 	"github.com/chuanbosi666/agent_go/pkg/types"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -91,10 +92,18 @@ func GetAllFunctionTools(ctx context.Context, servers []MCPServer, strict bool,
 
 // GetFunctionTools retrieves tools from a single MCP server.
 func GetFunctionTools(ctx context.Context, server MCPServer, strict bool, a types.AgentLike) ([]Tool, error) {
+	data := &tracing.MCPListToolsSpanData{Server: server.Name()}
+	ctx, span := tracing.StartSpan(ctx, data)
+	defer span.End()
+
 	mtools, err := server.ListTools(ctx, a)
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
+	for _, mt := range mtools {
+		data.Result = append(data.Result, mt.Name)
+	}
 	ftools := make([]Tool, 0, len(mtools))
 	for _, mt := range mtools {
 		ft, err := ToFunctionTool(mt, server, strict)
@@ -58,6 +58,10 @@ type FunctionTool struct {
 	// IsEnabled optionally controls whether the tool is available.
 	IsEnabled FunctionToolEnabler
 
+	// NeedsApproval pauses the run before each call of this tool until the
+	// call is approved or rejected through runner.Resume.
+	NeedsApproval bool
+
 }
 
 // ToolName returns the tool's name.
@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Record is a finished *Trace or *Span handed to an Exporter.
+type Record interface {
+	isRecord()
+}
+
+func (*Trace) isRecord() {}
+func (*Span) isRecord()  {}
+
+// Exporter sends finished traces and spans to a backend.
+// An Exporter that also implements io.Closer is closed on Shutdown.
+type Exporter interface {
+	Export(ctx context.Context, records []Record) error
+}
+
+// Default BatchOptions values.
+const (
+	DefaultMaxQueueSize  = 8192
+	DefaultMaxBatchSize  = 128
+	DefaultScheduleDelay = 5 * time.Second
+)
+
+// BatchOptions configures a BatchProcessor. Zero fields take the defaults.
+type BatchOptions struct {
+	// MaxQueueSize bounds the records waiting for export; newer ones are dropped.
+	MaxQueueSize int
+	// MaxBatchSize is the most records passed to one Export call. A full
+	// batch is exported without waiting for ScheduleDelay.
+	MaxBatchSize int
+	// ScheduleDelay is the interval between periodic exports.
+	ScheduleDelay time.Duration
+	// OnError is called with export errors (optional).
+	OnError func(error)
+}
+
+// BatchProcessor is a TraceProcessor that queues finished traces and spans
+// and exports them in batches from a background goroutine.
+type BatchProcessor struct {
+	exporter Exporter
+	opts     BatchOptions
+
+	mu      sync.Mutex
+	queue   []Record
+	dropped int
+	closed  bool
+
+	full     chan struct{}
+	flushReq chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewBatchProcessor creates a BatchProcessor and starts its export goroutine.
+// Call Shutdown to export the remaining records and stop it.
+func NewBatchProcessor(exporter Exporter, opts BatchOptions) *BatchProcessor {
+	if opts.MaxQueueSize <= 0 {
+		opts.MaxQueueSize = DefaultMaxQueueSize
+	}
+	if opts.MaxBatchSize <= 0 {
+		opts.MaxBatchSize = DefaultMaxBatchSize
+	}
+	if opts.ScheduleDelay <= 0 {
+		opts.ScheduleDelay = DefaultScheduleDelay
+	}
+	b := &BatchProcessor{
+		exporter: exporter,
+		opts:     opts,
+		full:     make(chan struct{}, 1),
+		flushReq: make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+func (b *BatchProcessor) OnTraceStart(*Trace) {}
+func (b *BatchProcessor) OnSpanStart(*Span)   {}
+func (b *BatchProcessor) OnTraceEnd(t *Trace) { b.enqueue(t) }
+func (b *BatchProcessor) OnSpanEnd(s *Span)   { b.enqueue(s) }
+
+// Dropped reports how many records were dropped because the queue was full.
+func (b *BatchProcessor) Dropped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+func (b *BatchProcessor) enqueue(r Record) {
+	b.mu.Lock()
+	if b.closed || len(b.queue) >= b.opts.MaxQueueSize {
+		b.dropped++
+		b.mu.Unlock()
+		return
+	}
+	b.queue = append(b.queue, r)
+	full := len(b.queue) >= b.opts.MaxBatchSize
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *BatchProcessor) loop() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.opts.ScheduleDelay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.export()
+		case <-b.full:
+			b.export()
+		case flushed := <-b.flushReq:
+			b.export()
+			close(flushed)
+		case <-b.stop:
+			b.export()
+			return
+		}
+	}
+}
+
+// export sends the queued records in batches of at most MaxBatchSize.
+func (b *BatchProcessor) export() {
+	b.mu.Lock()
+	records := b.queue
+	b.queue = nil
+	b.mu.Unlock()
+
+	for len(records) > 0 {
+		n := min(len(records), b.opts.MaxBatchSize)
+		if err := b.exporter.Export(context.Background(), records[:n]); err != nil && b.opts.OnError != nil {
+			b.opts.OnError(err)
+		}
+		records = records[n:]
+	}
+}
+
+// ForceFlush exports all queued records before returning.
+func (b *BatchProcessor) ForceFlush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case b.flushReq <- flushed:
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the remaining records, stops the export goroutine and
+// closes the exporter if it is an io.Closer. Records ended afterwards are dropped.
+func (b *BatchProcessor) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.stop)
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if c, ok := b.exporter.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("close trace exporter: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// JSONLExporter writes each trace and span as one JSON line.
+type JSONLExporter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLExporter creates an exporter writing to w. If w is an io.Closer it
+// is closed together with the exporter.
+func NewJSONLExporter(w io.Writer) *JSONLExporter {
+	return &JSONLExporter{w: w, enc: json.NewEncoder(w)}
+}
+
+// Export writes the records, one per line.
+func (e *JSONLExporter) Export(_ context.Context, records []Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		if err := e.enc.Encode(r); err != nil {
+			return fmt.Errorf("write trace record: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (e *JSONLExporter) Close() error {
+	if c, ok := e.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// NewFileProcessor creates a BatchProcessor appending traces and spans as
+// JSON lines to the file at path, for offline inspection. The file is closed
+// on Shutdown.
+func NewFileProcessor(path string, opts BatchOptions) (*BatchProcessor, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	return NewBatchProcessor(NewJSONLExporter(f), opts), nil
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTelProcessor is a TraceProcessor that mirrors traces and spans as
+// OpenTelemetry spans. Each trace becomes a root span named after the
+// workflow; span data fields become attributes prefixed with "agent_go.".
+//
+// Exporting is left to the OpenTelemetry SDK behind the tracer, so ForceFlush
+// and Shutdown do nothing.
+type OTelProcessor struct {
+	tracer trace.Tracer
+
+	mu    sync.Mutex
+	spans map[string]trace.Span // by trace or span ID, while running
+}
+
+// NewOTelProcessor creates a processor starting spans on tracer.
+func NewOTelProcessor(tracer trace.Tracer) *OTelProcessor {
+	return &OTelProcessor{tracer: tracer, spans: make(map[string]trace.Span)}
+}
+
+func (p *OTelProcessor) OnTraceStart(t *Trace) {
+	attrs := []attribute.KeyValue{
+		attribute.String("agent_go.trace_id", t.ID),
+		attribute.String("agent_go.workflow_name", t.WorkflowName),
+	}
+	if t.GroupID != "" {
+		attrs = append(attrs, attribute.String("agent_go.group_id", t.GroupID))
+	}
+	_, span := p.tracer.Start(context.Background(), t.WorkflowName,
+		trace.WithTimestamp(t.StartedAt),
+		trace.WithAttributes(attrs...),
+	)
+	p.mu.Lock()
+	p.spans[t.ID] = span
+	p.mu.Unlock()
+}
+
+func (p *OTelProcessor) OnTraceEnd(t *Trace) {
+	if span := p.take(t.ID); span != nil {
+		span.End(trace.WithTimestamp(t.EndedAt))
+	}
+}
+
+func (p *OTelProcessor) OnSpanStart(s *Span) {
+	p.mu.Lock()
+	parent := p.spans[s.ParentID]
+	if parent == nil {
+		parent = p.spans[s.TraceID]
+	}
+	p.mu.Unlock()
+
+	ctx := context.Background()
+	if parent != nil {
+		ctx = trace.ContextWithSpan(ctx, parent)
+	}
+	_, span := p.tracer.Start(ctx, otelSpanName(s.Data),
+		trace.WithTimestamp(s.StartedAt),
+		trace.WithAttributes(attribute.String("agent_go.span_id", s.ID)),
+	)
+	p.mu.Lock()
+	p.spans[s.ID] = span
+	p.mu.Unlock()
+}
+
+func (p *OTelProcessor) OnSpanEnd(s *Span) {
+	span := p.take(s.ID)
+	if span == nil {
+		return
+	}
+	span.SetAttributes(spanAttributes(s.Data)...)
+	if s.Error != nil {
+		span.SetStatus(codes.Error, s.Error.Message)
+	}
+	span.End(trace.WithTimestamp(s.EndedAt))
+}
+
+func (p *OTelProcessor) ForceFlush(context.Context) error { return nil }
+func (p *OTelProcessor) Shutdown(context.Context) error   { return nil }
+
+func (p *OTelProcessor) take(id string) trace.Span {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	span := p.spans[id]
+	delete(p.spans, id)
+	return span
+}
+
+// otelSpanName names a span after its type and, when it has one, its name.
+func otelSpanName(data SpanData) string {
+	switch d := data.(type) {
+	case *AgentSpanData:
+		return "agent " + d.Name
+	case *GenerationSpanData:
+		return "generation " + d.Model
+	case *FunctionSpanData:
+		return "function " + d.Name
+	case *GuardrailSpanData:
+		return "guardrail " + d.Name
+	case *HandoffSpanData:
+		return fmt.Sprintf("handoff %s -> %s", d.FromAgent, d.ToAgent)
+	case *MCPListToolsSpanData:
+		return "mcp_tools " + d.Server
+	case *CustomSpanData:
+		return d.Name
+	default:
+		return data.Type()
+	}
+}
+
+// spanAttributes flattens the top-level fields of the span data into
+// attributes. Scalars keep their type; anything else is stored as JSON.
+func spanAttributes(data SpanData) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("agent_go.span_type", data.Type())}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return attrs
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return attrs
+	}
+	for name, value := range fields {
+		key := "agent_go." + data.Type() + "." + name
+		var v any
+		_ = json.Unmarshal(value, &v)
+		switch v := v.(type) {
+		case string:
+			attrs = append(attrs, attribute.String(key, v))
+		case bool:
+			attrs = append(attrs, attribute.Bool(key, v))
+		case float64:
+			if v == float64(int64(v)) {
+				attrs = append(attrs, attribute.Int64(key, int64(v)))
+			} else {
+				attrs = append(attrs, attribute.Float64(key, v))
+			}
+		default:
+			attrs = append(attrs, attribute.String(key, string(value)))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TraceProcessor receives traces and spans as they start and end.
+// Callbacks are made synchronously from the run, possibly from several
+// goroutines at once, so they should be fast and safe for concurrent use.
+type TraceProcessor interface {
+	OnTraceStart(t *Trace)
+	OnTraceEnd(t *Trace)
+	OnSpanStart(s *Span)
+	OnSpanEnd(s *Span)
+	// ForceFlush exports everything buffered so far.
+	ForceFlush(ctx context.Context) error
+	// Shutdown flushes and releases the processor's resources.
+	Shutdown(ctx context.Context) error
+}
+
+// Provider creates traces and dispatches them to its processors.
+type Provider struct {
+	mu         sync.RWMutex
+	processors []TraceProcessor
+}
+
+var defaultProvider = NewProvider()
+
+// NewProvider creates a provider with the given processors.
+func NewProvider(processors ...TraceProcessor) *Provider {
+	return &Provider{processors: processors}
+}
+
+// DefaultProvider returns the provider used when a run does not set one.
+func DefaultProvider() *Provider {
+	return defaultProvider
+}
+
+// AddTraceProcessor adds a processor to the default provider.
+func AddTraceProcessor(p TraceProcessor) {
+	defaultProvider.AddProcessor(p)
+}
+
+// SetTraceProcessors replaces the processors of the default provider.
+func SetTraceProcessors(processors ...TraceProcessor) {
+	defaultProvider.SetProcessors(processors...)
+}
+
+// AddProcessor adds a processor.
+func (p *Provider) AddProcessor(processor TraceProcessor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processors = append(p.processors, processor)
+}
+
+// SetProcessors replaces all processors.
+func (p *Provider) SetProcessors(processors ...TraceProcessor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processors = slices.Clone(processors)
+}
+
+func (p *Provider) getProcessors() []TraceProcessor {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.processors
+}
+
+// StartTrace starts a trace and returns a context carrying it. Spans started
+// from that context belong to the trace.
+func (p *Provider) StartTrace(ctx context.Context, workflowName string, opts TraceOptions) (context.Context, *Trace) {
+	t := &Trace{
+		ID:           newID("trace_", 16),
+		WorkflowName: workflowName,
+		GroupID:      opts.GroupID,
+		Metadata:     opts.Metadata,
+		StartedAt:    time.Now(),
+		provider:     p,
+	}
+	for _, proc := range p.getProcessors() {
+		proc.OnTraceStart(t)
+	}
+	ctx = context.WithValue(ctx, spanKey{}, (*Span)(nil))
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+// ForceFlush flushes all processors.
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, proc := range p.getProcessors() {
+		errs = append(errs, proc.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown shuts down all processors.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, proc := range p.getProcessors() {
+		errs = append(errs, proc.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,76 @@
+package tracing
+
+// SpanData holds the kind-specific fields of a span. The fields may be filled
+// in while the span is running; processors read them once the span has ended.
+type SpanData interface {
+	// Type names the kind of span, e.g. "agent" or "generation".
+	Type() string
+}
+
+// AgentSpanData describes the time one agent was the current agent of a run.
+type AgentSpanData struct {
+	Name       string   `json:"name"`
+	Handoffs   []string `json:"handoffs,omitempty"`
+	Tools      []string `json:"tools,omitempty"`
+	OutputType string   `json:"output_type,omitempty"`
+}
+
+func (*AgentSpanData) Type() string { return "agent" }
+
+// GenerationSpanData describes one model request.
+type GenerationSpanData struct {
+	Model       string         `json:"model"`
+	ModelConfig map[string]any `json:"model_config,omitempty"`
+	Input       any            `json:"input,omitempty"`
+	Output      any            `json:"output,omitempty"`
+	Usage       *Usage         `json:"usage,omitempty"`
+}
+
+func (*GenerationSpanData) Type() string { return "generation" }
+
+// Usage is the token usage of a generation.
+type Usage struct {
+	InputTokens  uint64 `json:"input_tokens"`
+	OutputTokens uint64 `json:"output_tokens"`
+}
+
+// FunctionSpanData describes one tool call.
+type FunctionSpanData struct {
+	Name   string `json:"name"`
+	Input  string `json:"input,omitempty"`
+	Output string `json:"output,omitempty"`
+}
+
+func (*FunctionSpanData) Type() string { return "function" }
+
+// GuardrailSpanData describes one guardrail check.
+type GuardrailSpanData struct {
+	Name      string `json:"name"`
+	Triggered bool   `json:"triggered"`
+}
+
+func (*GuardrailSpanData) Type() string { return "guardrail" }
+
+// HandoffSpanData describes a handoff between two agents.
+type HandoffSpanData struct {
+	FromAgent string `json:"from_agent"`
+	ToAgent   string `json:"to_agent"`
+}
+
+func (*HandoffSpanData) Type() string { return "handoff" }
+
+// MCPListToolsSpanData describes listing the tools of an MCP server.
+type MCPListToolsSpanData struct {
+	Server string   `json:"server"`
+	Result []string `json:"result,omitempty"`
+}
+
+func (*MCPListToolsSpanData) Type() string { return "mcp_tools" }
+
+// CustomSpanData describes an application-defined operation.
+type CustomSpanData struct {
+	Name string         `json:"name"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+func (*CustomSpanData) Type() string { return "custom" }
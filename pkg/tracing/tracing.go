@@ -0,0 +1,203 @@
+// Package tracing records what happens during agent runs as traces and spans.
+//
+// A trace covers one workflow, usually a single Runner.Run call, and holds a
+// tree of spans: agent, generation (model call), function (tool call),
+// guardrail, handoff and MCP list-tools spans. Finished traces and spans are
+// handed to the TraceProcessors registered on a Provider.
+//
+// The current trace and span travel in the context, so spans started by
+// nested runs (e.g. agents used as tools) join the trace of the outer run.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Trace is the root of a tree of spans for one workflow.
+type Trace struct {
+	ID           string
+	WorkflowName string
+	GroupID      string
+	Metadata     map[string]any
+	StartedAt    time.Time
+	EndedAt      time.Time
+
+	provider *Provider
+	once     sync.Once
+}
+
+// TraceOptions configures a new trace.
+type TraceOptions struct {
+	// GroupID links traces of the same conversation, e.g. a chat thread ID (optional).
+	GroupID string
+	// Metadata is attached to the trace as is (optional).
+	Metadata map[string]any
+}
+
+// End finishes the trace and passes it to the processors.
+// Calling End more than once, or on a nil trace, does nothing.
+func (t *Trace) End() {
+	if t == nil {
+		return
+	}
+	t.once.Do(func() {
+		t.EndedAt = time.Now()
+		for _, p := range t.provider.getProcessors() {
+			p.OnTraceEnd(t)
+		}
+	})
+}
+
+// MarshalJSON encodes the trace in the export format.
+func (t *Trace) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Object       string         `json:"object"`
+		ID           string         `json:"id"`
+		WorkflowName string         `json:"workflow_name"`
+		GroupID      string         `json:"group_id,omitempty"`
+		Metadata     map[string]any `json:"metadata,omitempty"`
+		StartedAt    time.Time      `json:"started_at"`
+		EndedAt      *time.Time     `json:"ended_at,omitempty"`
+	}{"trace", t.ID, t.WorkflowName, t.GroupID, t.Metadata, t.StartedAt, timeOrNil(t.EndedAt)})
+}
+
+// Span is a timed operation within a trace.
+type Span struct {
+	ID        string
+	TraceID   string
+	ParentID  string
+	Data      SpanData
+	StartedAt time.Time
+	EndedAt   time.Time
+	Error     *SpanError
+
+	trace *Trace
+	once  sync.Once
+}
+
+// SpanError describes why a span failed.
+type SpanError struct {
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// SetError marks the span as failed. It does nothing on a nil span or nil err.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.Error = &SpanError{Message: err.Error()}
+}
+
+// End finishes the span and passes it to the processors.
+// Calling End more than once, or on a nil span, does nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.EndedAt = time.Now()
+		for _, p := range s.trace.provider.getProcessors() {
+			p.OnSpanEnd(s)
+		}
+	})
+}
+
+// MarshalJSON encodes the span in the export format. The span data is written
+// as an object whose "type" field names the kind of span.
+func (s *Span) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(s.Data)
+	if err != nil {
+		return nil, err
+	}
+	typ, _ := json.Marshal(s.Data.Type())
+	spanData := append([]byte(`{"type":`), typ...)
+	if len(data) > 2 {
+		spanData = append(append(spanData, ','), data[1:]...)
+	} else {
+		spanData = append(spanData, '}')
+	}
+	var parentID *string
+	if s.ParentID != "" {
+		parentID = &s.ParentID
+	}
+	return json.Marshal(struct {
+		Object    string          `json:"object"`
+		ID        string          `json:"id"`
+		TraceID   string          `json:"trace_id"`
+		ParentID  *string         `json:"parent_id"`
+		StartedAt time.Time       `json:"started_at"`
+		EndedAt   *time.Time      `json:"ended_at,omitempty"`
+		SpanData  json.RawMessage `json:"span_data"`
+		Error     *SpanError      `json:"error,omitempty"`
+	}{"trace.span", s.ID, s.TraceID, parentID, s.StartedAt, timeOrNil(s.EndedAt), spanData, s.Error})
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+type traceKey struct{}
+type spanKey struct{}
+
+// CurrentTrace returns the trace carried by ctx, or nil.
+func CurrentTrace(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// CurrentSpan returns the innermost span carried by ctx, or nil.
+func CurrentSpan(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// WithoutTrace returns a context in which no spans are recorded, even if
+// ctx carries a trace.
+func WithoutTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, traceKey{}, (*Trace)(nil))
+}
+
+// StartSpan starts a span under the current span of ctx and returns a context
+// carrying it. Without a trace in ctx nothing is recorded and the returned span
+// is nil; its methods are safe to call.
+func StartSpan(ctx context.Context, data SpanData) (context.Context, *Span) {
+	t := CurrentTrace(ctx)
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{
+		ID:        newID("span_", 12),
+		TraceID:   t.ID,
+		Data:      data,
+		StartedAt: time.Now(),
+		trace:     t,
+	}
+	if parent := CurrentSpan(ctx); parent != nil {
+		s.ParentID = parent.ID
+	}
+	for _, p := range t.provider.getProcessors() {
+		p.OnSpanStart(s)
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// StartTrace starts a trace on the default provider.
+func StartTrace(ctx context.Context, workflowName string, opts TraceOptions) (context.Context, *Trace) {
+	return defaultProvider.StartTrace(ctx, workflowName, opts)
+}
+
+// newID returns prefix followed by n random bytes in hex.
+func newID(prefix string, n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,293 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/pattern"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deleteTool 是需要审批的工具，记录实际执行时收到的参数
+type deleteTool struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (d *deleteTool) tool() tool.FunctionTool {
+	return tool.FunctionTool{
+		Name:        "delete_file",
+		Description: "Delete a file.",
+		ParamsJSONSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"path": map[string]any{"type": "string"}},
+		},
+		NeedsApproval: true,
+		OnInvokeTool: func(_ context.Context, arguments string) (any, error) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.calls = append(d.calls, arguments)
+			return "deleted", nil
+		},
+	}
+}
+
+func (d *deleteTool) invocations() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.calls...)
+}
+
+// reloadState 模拟在另一个进程中恢复：状态经 JSON 往返后重新加载
+func reloadState(t *testing.T, a *agent.Agent, state *runner.RunState) *runner.RunState {
+	t.Helper()
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+	loaded, err := runner.LoadRunState(a, data)
+	require.NoError(t, err)
+	return loaded
+}
+
+func TestRunner_ToolApprovalPauseAndResume(t *testing.T) {
+	paths := []struct {
+		name   string
+		prompt agent.Prompter
+	}{
+		{name: "ChatCompletions"},
+		{name: "Responses", prompt: agent.Prompt{ID: "pmpt_test"}},
+	}
+
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeLLMServer(t,
+				scriptedReply{toolCalls: []scriptedToolCall{
+					{id: "call_calc", name: "calculator", arguments: `{"operation":"add","a":1,"b":1}`},
+					{id: "call_del", name: "delete_file", arguments: `{"path":"a.txt"}`},
+				}},
+				scriptedReply{text: "done"},
+			)
+			del := &deleteTool{}
+			a := agent.New("ops").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithTools([]tool.FunctionTool{tool.NewCalculatorTool(), del.tool()})
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+
+			result, err := runner.Run(ctx, a, "Clean up")
+			require.NoError(t, err)
+			assert.Nil(t, result.FinalOutput)
+			require.Equal(t, []runner.ToolApprovalItem{{
+				AgentName: "ops",
+				CallID:    "call_del",
+				ToolName:  "delete_file",
+				Arguments: `{"path":"a.txt"}`,
+			}}, result.Interruptions)
+			require.NotNil(t, result.State)
+			assert.Empty(t, del.invocations())
+			assert.Equal(t, 1, server.requestCount())
+
+			result, err = runner.Resume(ctx, reloadState(t, a, result.State), []runner.ToolApproval{runner.Approve("call_del")})
+			require.NoError(t, err)
+			assert.Equal(t, "done", finalText(t, result.FinalOutput))
+			assert.Empty(t, result.Interruptions)
+			assert.Equal(t, []string{`{"path":"a.txt"}`}, del.invocations())
+			require.Equal(t, 2, server.requestCount())
+			assert.Equal(t, uint64(2), result.Usage.Requests)
+
+			// 续跑时模型看到原始输入、两个调用及其结果
+			second, _ := json.Marshal(server.request(1))
+			assert.Contains(t, string(second), "Clean up")
+			assert.Contains(t, string(second), "call_calc")
+			assert.Contains(t, string(second), "deleted")
+		})
+	}
+}
+
+func TestRunner_ToolApprovalRejectAndPartialDecisions(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_1", name: "delete_file", arguments: `{"path":"a.txt"}`},
+			{id: "call_2", name: "delete_file", arguments: `{"path":"b.txt"}`},
+		}},
+		scriptedReply{text: "kept b.txt"},
+	)
+	del := &deleteTool{}
+	a := agent.New("ops").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{del.tool()})
+
+	result, err := runner.Run(ctx, a, "Delete both files")
+	require.NoError(t, err)
+	require.Len(t, result.Interruptions, 2)
+
+	// 只决定了一个调用：已批准的调用执行，运行再次暂停
+	result, err = runner.Resume(ctx, reloadState(t, a, result.State), []runner.ToolApproval{runner.Approve("call_1")})
+	require.NoError(t, err)
+	require.Len(t, result.Interruptions, 1)
+	assert.Equal(t, "call_2", result.Interruptions[0].CallID)
+	assert.Equal(t, []string{`{"path":"a.txt"}`}, del.invocations())
+	assert.Equal(t, 1, server.requestCount())
+
+	result, err = runner.Resume(ctx, reloadState(t, a, result.State), []runner.ToolApproval{runner.Reject("call_2", "b.txt is still in use")})
+	require.NoError(t, err)
+	assert.Equal(t, "kept b.txt", result.FinalOutput)
+	assert.Len(t, del.invocations(), 1, "approved call must not run twice")
+
+	messages := server.request(1)["messages"].([]any)
+	require.Len(t, messages, 4)
+	assert.Equal(t, "deleted", messages[2].(map[string]any)["content"])
+	assert.Contains(t, messages[3].(map[string]any)["content"], "b.txt is still in use")
+}
+
+func TestRunner_ToolApprovalWithSession(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_del", name: "delete_file", arguments: `{"path":"a.txt"}`},
+		}},
+		scriptedReply{text: "done"},
+	)
+	session, err := memory.NewSQLiteSession(ctx, memory.SQLiteSessionConfig{SessionID: "approval"})
+	require.NoError(t, err)
+	defer session.Close()
+
+	del := &deleteTool{}
+	a := agent.New("ops").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{del.tool()})
+	r := runner.Runner{Config: runner.RunConfig{Session: session}}
+
+	result, err := r.Run(ctx, a, "Clean up")
+	require.NoError(t, err)
+	require.Len(t, result.Interruptions, 1)
+
+	// 暂停时会话中只有用户输入，不会留下没有结果的工具调用
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+
+	result, err = r.Resume(ctx, reloadState(t, a, result.State), []runner.ToolApproval{runner.Approve("call_del")})
+	require.NoError(t, err)
+	assert.Equal(t, "done", result.FinalOutput)
+
+	stored, err = session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Len(t, stored, 4)
+}
+
+func TestRunner_ToolApprovalInAgentTool(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_outer", name: "call_agent_janitor", arguments: `{"input":"remove a.txt"}`},
+		}},
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_inner", name: "delete_file", arguments: `{"path":"a.txt"}`},
+		}},
+		scriptedReply{text: "a.txt removed"},
+		scriptedReply{text: "all clean"},
+	)
+	del := &deleteTool{}
+	janitor := agent.New("janitor").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{del.tool()})
+	manager := agent.New("manager").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{pattern.WrapAgentAsTool(janitor, 0)})
+
+	// 子 Agent 中需要审批的调用使外层运行一并暂停
+	result, err := runner.Run(ctx, manager, "Tidy up")
+	require.NoError(t, err)
+	require.Equal(t, []runner.ToolApprovalItem{{
+		AgentName: "janitor",
+		CallID:    "call_inner",
+		ToolName:  "delete_file",
+		Arguments: `{"path":"a.txt"}`,
+	}}, result.Interruptions)
+	assert.Equal(t, 2, server.requestCount())
+
+	result, err = runner.Resume(ctx, reloadState(t, manager, result.State), []runner.ToolApproval{runner.Approve("call_inner")})
+	require.NoError(t, err)
+	assert.Equal(t, "all clean", result.FinalOutput)
+	assert.Equal(t, []string{`{"path":"a.txt"}`}, del.invocations())
+	require.Equal(t, 4, server.requestCount())
+
+	// 外层模型收到子 Agent 的最终输出
+	messages := server.request(3)["messages"].([]any)
+	assert.Equal(t, "a.txt removed", messages[len(messages)-1].(map[string]any)["content"])
+	assert.Equal(t, uint64(4), result.Usage.Requests)
+}
+
+func TestLoadRunState_WrongAgent(t *testing.T) {
+	server := newFakeLLMServer(t, scriptedReply{toolCalls: []scriptedToolCall{
+		{id: "call_del", name: "delete_file", arguments: `{}`},
+	}})
+	del := &deleteTool{}
+	a := agent.New("ops").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{del.tool()})
+
+	result, err := runner.Run(context.Background(), a, "Clean up")
+	require.NoError(t, err)
+	data, err := json.Marshal(result.State)
+	require.NoError(t, err)
+
+	_, err = runner.LoadRunState(agent.New("other"), data)
+	assert.True(t, errors.Is(err, runner.ErrInvalidRunState))
+
+	_, err = runner.Resume(context.Background(), &runner.RunState{}, nil)
+	assert.True(t, errors.Is(err, runner.ErrInvalidRunState))
+}
+
+func TestRunner_ToolApprovalResumeResult(t *testing.T) {
+	ctx := context.Background()
+	m := fakemodel.New(
+		fakemodel.Output(fakemodel.ToolCall("call_calc", "calculator", `{"operation":"add","a":1,"b":1}`)),
+		fakemodel.Output(fakemodel.ToolCall("call_del", "delete_file", `{"path":"a.txt"}`)),
+		fakemodel.Text("done"),
+	)
+	del := &deleteTool{}
+	a := agent.New("ops").WithTools([]tool.FunctionTool{tool.NewCalculatorTool(), del.tool()})
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: m}}
+
+	result, err := r.Run(ctx, a, "Clean up")
+	require.NoError(t, err)
+	require.Len(t, result.Interruptions, 1)
+
+	result, err = r.Resume(ctx, reloadState(t, a, result.State), []runner.ToolApproval{runner.Approve("call_del")})
+	require.NoError(t, err)
+
+	// NewItems 包含暂停前的条目，RawResponses 只包含暂停后的响应
+	var items []responses.ResponseInputItemUnionParam
+	for _, item := range result.NewItems {
+		items = append(items, item.ToInputItem())
+	}
+	assert.Equal(t, []string{
+		"call:call_calc", "output:call_calc=2",
+		"call:call_del", "output:call_del=deleted",
+		"assistant:",
+	}, itemLabels(items))
+	assert.Equal(t, "done", result.FinalOutput)
+	assert.Len(t, result.RawResponses, 1)
+	assert.Equal(t, uint64(3), result.Usage.Requests)
+}
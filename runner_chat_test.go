@@ -0,0 +1,497 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedReply 描述一轮模型回复：要么是文本，要么是若干工具调用；
+// status 非零时返回该 HTTP 错误及 header
+type scriptedReply struct {
+	text      string
+	toolCalls []scriptedToolCall
+	status    int
+	header    map[string]string
+}
+
+type scriptedToolCall struct {
+	id        string
+	name      string
+	arguments string
+}
+
+// fakeLLMServer 是同时模拟 Responses API 与 Chat Completions API 的 httptest 服务
+type fakeLLMServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	replies  []scriptedReply
+	requests []map[string]any
+	paths    []string
+	headers  []http.Header
+	queries  []url.Values
+}
+
+func newFakeLLMServer(t *testing.T, replies ...scriptedReply) *fakeLLMServer {
+	t.Helper()
+	f := &fakeLLMServer{replies: replies}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeLLMServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req map[string]any
+	_ = json.Unmarshal(body, &req)
+
+	f.mu.Lock()
+	idx := len(f.requests)
+	f.requests = append(f.requests, req)
+	f.paths = append(f.paths, r.URL.Path)
+	f.headers = append(f.headers, r.Header.Clone())
+	f.queries = append(f.queries, r.URL.Query())
+	f.mu.Unlock()
+
+	if idx >= len(f.replies) {
+		http.Error(w, `{"error":{"message":"no scripted reply"}}`, http.StatusInternalServerError)
+		return
+	}
+	reply := f.replies[idx]
+
+	if reply.status != 0 {
+		for k, v := range reply.header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.status)
+		_, _ = w.Write([]byte(`{"error":{"message":"scripted failure"}}`))
+		return
+	}
+
+	if stream, _ := req["stream"].(bool); stream {
+		writeSSE(w, r.URL.Path, reply)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		_ = json.NewEncoder(w).Encode(chatCompletionBody(reply))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(responsesBody(reply))
+}
+
+func (f *fakeLLMServer) client() openai.Client {
+	return openai.NewClient(
+		option.WithAPIKey("test-key"),
+		option.WithBaseURL(f.URL),
+		option.WithMaxRetries(0),
+	)
+}
+
+func (f *fakeLLMServer) request(i int) map[string]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[i]
+}
+
+func (f *fakeLLMServer) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func chatCompletionBody(reply scriptedReply) map[string]any {
+	message := map[string]any{"role": "assistant", "content": reply.text}
+	finishReason := "stop"
+	if len(reply.toolCalls) > 0 {
+		var calls []map[string]any
+		for _, tc := range reply.toolCalls {
+			calls = append(calls, map[string]any{
+				"id":   tc.id,
+				"type": "function",
+				"function": map[string]any{
+					"name":      tc.name,
+					"arguments": tc.arguments,
+				},
+			})
+		}
+		message["tool_calls"] = calls
+		finishReason = "tool_calls"
+	}
+	return map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   "test-model",
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       message,
+			"finish_reason": finishReason,
+		}},
+		"usage": map[string]any{
+			"prompt_tokens":             10,
+			"prompt_tokens_details":     map[string]any{"cached_tokens": 4},
+			"completion_tokens":         5,
+			"completion_tokens_details": map[string]any{"reasoning_tokens": 2},
+			"total_tokens":              15,
+		},
+	}
+}
+
+func responsesBody(reply scriptedReply) map[string]any {
+	var output []map[string]any
+	if reply.text != "" {
+		output = append(output, map[string]any{
+			"id":     "msg_test",
+			"type":   "message",
+			"role":   "assistant",
+			"status": "completed",
+			"content": []map[string]any{{
+				"type":        "output_text",
+				"text":        reply.text,
+				"annotations": []any{},
+			}},
+		})
+	}
+	for _, tc := range reply.toolCalls {
+		output = append(output, map[string]any{
+			"id":        "fc_" + tc.id,
+			"type":      "function_call",
+			"call_id":   tc.id,
+			"name":      tc.name,
+			"arguments": tc.arguments,
+			"status":    "completed",
+		})
+	}
+	return map[string]any{
+		"id":         "resp_test",
+		"object":     "response",
+		"created_at": 0,
+		"model":      "test-model",
+		"status":     "completed",
+		"output":     output,
+		"usage": map[string]any{
+			"input_tokens":          10,
+			"input_tokens_details":  map[string]any{"cached_tokens": 4},
+			"output_tokens":         5,
+			"output_tokens_details": map[string]any{"reasoning_tokens": 2},
+			"total_tokens":          15,
+		},
+	}
+}
+
+// finalText 将两种 API 路径的 FinalOutput 统一为文本
+func finalText(t *testing.T, output any) string {
+	t.Helper()
+	switch v := output.(type) {
+	case string:
+		return v
+	case []responses.ResponseOutputMessageContentUnion:
+		var texts []string
+		for _, c := range v {
+			texts = append(texts, c.Text)
+		}
+		return strings.Join(texts, "\n")
+	default:
+		t.Fatalf("unexpected final output type %T", output)
+		return ""
+	}
+}
+
+func TestRunner_ToolCallingParity(t *testing.T) {
+	paths := []struct {
+		name   string
+		prompt agent.Prompter
+	}{
+		{name: "ChatCompletions"},
+		{name: "Responses", prompt: agent.Prompt{ID: "pmpt_test"}},
+	}
+
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			server := newFakeLLMServer(t,
+				scriptedReply{toolCalls: []scriptedToolCall{
+					{id: "call_1", name: "calculator", arguments: `{"operation":"add","a":2,"b":3}`},
+				}},
+				scriptedReply{text: "2 + 3 = 5"},
+			)
+
+			a := agent.New("calc").
+				WithInstructions("You are a calculator.").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+
+			result, err := runner.Run(context.Background(), a, "What is 2 + 3?")
+			require.NoError(t, err)
+			assert.Equal(t, "2 + 3 = 5", finalText(t, result.FinalOutput))
+			assert.Len(t, result.RawResponses, 2)
+			require.Equal(t, 2, server.requestCount())
+
+			// 第一轮请求应携带工具定义
+			first, _ := json.Marshal(server.request(0)["tools"])
+			assert.Contains(t, string(first), `"calculator"`)
+
+			// 第二轮请求应包含原始输入、工具调用与工具结果
+			second, _ := json.Marshal(server.request(1))
+			assert.Contains(t, string(second), "What is 2 + 3?")
+			assert.Contains(t, string(second), "call_1")
+			assert.Contains(t, string(second), `"5"`)
+		})
+	}
+}
+
+func TestRunner_ChatCompletionsToolMessages(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_a", name: "calculator", arguments: `{"operation":"multiply","a":6,"b":7}`},
+			{id: "call_b", name: "missing_tool", arguments: `{}`},
+		}},
+		scriptedReply{text: "done"},
+	)
+
+	a := agent.New("calc").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+
+	result, err := runner.Run(context.Background(), a, "6 * 7?")
+	require.NoError(t, err)
+	assert.Equal(t, "done", result.FinalOutput)
+
+	messages, ok := server.request(1)["messages"].([]any)
+	require.True(t, ok)
+	require.Len(t, messages, 4)
+
+	// user -> assistant(tool_calls) -> tool -> tool
+	assistant := messages[1].(map[string]any)
+	assert.Equal(t, "assistant", assistant["role"])
+	assert.Len(t, assistant["tool_calls"], 2)
+
+	toolA := messages[2].(map[string]any)
+	assert.Equal(t, "tool", toolA["role"])
+	assert.Equal(t, "call_a", toolA["tool_call_id"])
+	assert.Equal(t, "42", toolA["content"])
+
+	toolB := messages[3].(map[string]any)
+	assert.Equal(t, "call_b", toolB["tool_call_id"])
+	assert.Contains(t, toolB["content"], "not found")
+}
+
+func TestRunner_MaxTurnsWithPendingToolCalls(t *testing.T) {
+	loop := scriptedReply{toolCalls: []scriptedToolCall{
+		{id: "call_loop", name: "calculator", arguments: `{"operation":"add","a":1,"b":1}`},
+	}}
+	server := newFakeLLMServer(t, loop, loop)
+
+	a := agent.New("looper").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+
+	r := runner.Runner{Config: runner.RunConfig{MaxTurns: 2}}
+	_, err := r.Run(context.Background(), a, "loop")
+
+	var maxTurnsErr *runner.MaxTurnsExceededError
+	require.ErrorAs(t, err, &maxTurnsErr)
+	assert.Equal(t, uint64(2), maxTurnsErr.MaxTurns)
+}
+
+func TestItemsToChatMessages(t *testing.T) {
+	items := []responses.ResponseInputItemUnionParam{
+		responses.ResponseInputItemParamOfMessage("be brief", responses.EasyInputMessageRoleDeveloper),
+		responses.ResponseInputItemParamOfMessage(responses.ResponseInputMessageContentListParam{
+			{OfInputText: &responses.ResponseInputTextParam{Text: "what is this?"}},
+			{OfInputImage: &responses.ResponseInputImageParam{
+				ImageURL: param.NewOpt("https://example.com/cat.png"),
+				Detail:   responses.ResponseInputImageDetailLow,
+			}},
+		}, responses.EasyInputMessageRoleUser),
+		{OfOutputMessage: &responses.ResponseOutputMessageParam{
+			ID:     "msg_1",
+			Status: responses.ResponseOutputMessageStatusCompleted,
+			Content: []responses.ResponseOutputMessageContentUnionParam{
+				{OfOutputText: &responses.ResponseOutputTextParam{Text: "Let me look."}},
+			},
+		}},
+		responses.ResponseInputItemParamOfFunctionCall(`{"q":"cat"}`, "call_1", "search"),
+		responses.ResponseInputItemParamOfFunctionCallOutput("call_1", "a cat"),
+		responses.ResponseInputItemParamOfReasoning("rs_1", nil),
+	}
+
+	messages := runner.ItemsToChatMessages(items)
+	require.Len(t, messages, 4)
+
+	assert.NotNil(t, messages[0].OfDeveloper)
+	require.NotNil(t, messages[1].OfUser)
+	assert.Len(t, messages[1].OfUser.Content.OfArrayOfContentParts, 2)
+
+	// 文本输出与随后的工具调用合并为同一条 assistant 消息
+	require.NotNil(t, messages[2].OfAssistant)
+	assert.Equal(t, "Let me look.", messages[2].OfAssistant.Content.OfString.Value)
+	require.Len(t, messages[2].OfAssistant.ToolCalls, 1)
+	assert.Equal(t, "call_1", messages[2].OfAssistant.ToolCalls[0].OfFunction.ID)
+
+	require.NotNil(t, messages[3].OfTool)
+	assert.Equal(t, "call_1", messages[3].OfTool.ToolCallID)
+
+	// 反向转换后再转换应得到相同的消息
+	roundTrip := runner.ItemsToChatMessages(runner.ChatMessagesToItems(messages))
+	want, err := json.Marshal(messages)
+	require.NoError(t, err)
+	got, err := json.Marshal(roundTrip)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
+
+func TestSQLiteSession_RoundTripsRunnerItems(t *testing.T) {
+	ctx := context.Background()
+	session, err := memory.NewSQLiteSession(ctx, memory.SQLiteSessionConfig{SessionID: "round-trip"})
+	require.NoError(t, err)
+	defer session.Close()
+
+	items := []responses.ResponseInputItemUnionParam{
+		responses.ResponseInputItemParamOfMessage("hello", responses.EasyInputMessageRoleUser),
+		{OfOutputMessage: &responses.ResponseOutputMessageParam{
+			ID:     "msg_1",
+			Status: responses.ResponseOutputMessageStatusCompleted,
+			Content: []responses.ResponseOutputMessageContentUnionParam{
+				{OfOutputText: &responses.ResponseOutputTextParam{Text: "hi there"}},
+			},
+		}},
+		responses.ResponseInputItemParamOfFunctionCall(`{}`, "call_1", "get_current_time"),
+		responses.ResponseInputItemParamOfFunctionCallOutput("call_1", "noon"),
+	}
+	require.NoError(t, session.AddItems(ctx, items))
+
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	require.Len(t, stored, 4)
+	require.NotNil(t, stored[0].OfMessage)
+	assert.Equal(t, "hello", stored[0].OfMessage.Content.OfString.Value)
+	require.NotNil(t, stored[1].OfOutputMessage)
+	assert.Equal(t, "hi there", stored[1].OfOutputMessage.Content[0].OfOutputText.Text)
+	require.NotNil(t, stored[2].OfFunctionCall)
+	require.NotNil(t, stored[3].OfFunctionCallOutput)
+}
+
+// chatTranscript 将请求体中的对话统一为 "role: text" 形式，便于比较两种 API 路径
+func chatTranscript(t *testing.T, req map[string]any) []string {
+	t.Helper()
+	var transcript []string
+	if messages, ok := req["messages"].([]any); ok {
+		for _, m := range messages {
+			msg := m.(map[string]any)
+			if msg["role"] == "system" {
+				continue
+			}
+			transcript = append(transcript, fmt.Sprintf("%s: %v", msg["role"], msg["content"]))
+		}
+		return transcript
+	}
+	for _, raw := range req["input"].([]any) {
+		item := raw.(map[string]any)
+		switch content := item["content"].(type) {
+		case string:
+			transcript = append(transcript, fmt.Sprintf("%s: %s", item["role"], content))
+		case []any:
+			var texts []string
+			for _, c := range content {
+				texts = append(texts, c.(map[string]any)["text"].(string))
+			}
+			transcript = append(transcript, fmt.Sprintf("%s: %s", item["role"], strings.Join(texts, "\n")))
+		}
+	}
+	return transcript
+}
+
+func TestRunner_SessionParityAcrossAPIs(t *testing.T) {
+	transcripts := map[string][]string{}
+
+	for _, usePrompt := range []bool{false, true} {
+		name := "ChatCompletions"
+		if usePrompt {
+			name = "Responses"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeLLMServer(t,
+				scriptedReply{text: "Nice to meet you, Alice."},
+				scriptedReply{text: "Your name is Alice."},
+			)
+			session, err := memory.NewSQLiteSession(ctx, memory.SQLiteSessionConfig{SessionID: name})
+			require.NoError(t, err)
+			defer session.Close()
+
+			a := agent.New("assistant").
+				WithInstructions("Remember what the user says.").
+				WithModel("test-model").
+				WithClient(server.client())
+			if usePrompt {
+				a.WithPrompt(agent.Prompt{ID: "pmpt_test"})
+			}
+			r := runner.Runner{Config: runner.RunConfig{Session: session}}
+
+			_, err = r.Run(ctx, a, "My name is Alice.")
+			require.NoError(t, err)
+			result, err := r.Run(ctx, a, "What is my name?")
+			require.NoError(t, err)
+			assert.Equal(t, "Your name is Alice.", finalText(t, result.FinalOutput))
+
+			transcripts[name] = chatTranscript(t, server.request(1))
+			assert.Equal(t, []string{
+				"user: My name is Alice.",
+				"assistant: Nice to meet you, Alice.",
+				"user: What is my name?",
+			}, transcripts[name])
+
+			stored, err := session.GetItems(ctx, -1)
+			require.NoError(t, err)
+			assert.Len(t, stored, 4)
+		})
+	}
+
+	assert.Equal(t, transcripts["ChatCompletions"], transcripts["Responses"])
+}
+
+func TestRunner_ChatCompletionsInputItems(t *testing.T) {
+	server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+
+	_, err := runner.RunInputs(context.Background(), a, []responses.ResponseInputItemUnionParam{
+		responses.ResponseInputItemParamOfMessage("earlier question", responses.EasyInputMessageRoleUser),
+		responses.ResponseInputItemParamOfMessage("earlier answer", responses.EasyInputMessageRoleAssistant),
+		responses.ResponseInputItemParamOfMessage("follow-up", responses.EasyInputMessageRoleUser),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"user: earlier question",
+		"assistant: earlier answer",
+		"user: follow-up",
+	}, chatTranscript(t, server.request(0)))
+}
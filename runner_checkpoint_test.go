@@ -0,0 +1,332 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/checkpoint"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStore 包装检查点存储并记录每次保存的内容
+type recordingStore struct {
+	checkpoint.Store
+	mu    sync.Mutex
+	saves [][]byte
+}
+
+func (s *recordingStore) Save(ctx context.Context, runID string, data []byte) error {
+	s.mu.Lock()
+	s.saves = append(s.saves, data)
+	s.mu.Unlock()
+	return s.Store.Save(ctx, runID, data)
+}
+
+func checkpointStores(t *testing.T) map[string]checkpoint.Store {
+	t.Helper()
+	fileStore, err := checkpoint.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	sqliteStore, err := checkpoint.NewSQLiteStore(context.Background(), checkpoint.SQLiteStoreConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteStore.Close() })
+	return map[string]checkpoint.Store{"File": fileStore, "SQLite": sqliteStore}
+}
+
+func TestRunner_ResumeFromCheckpointAfterFailure(t *testing.T) {
+	for name, store := range checkpointStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := runner.RunConfig{CheckpointStore: store, RunID: "run-1"}
+
+			// 第二轮请求失败，模拟进程在运行中途崩溃
+			crashed := newFakeLLMServer(t, scriptedReply{toolCalls: []scriptedToolCall{
+				{id: "call_1", name: "calculator", arguments: `{"operation":"add","a":1,"b":1}`},
+			}})
+			a := agent.New("calc").
+				WithModel("test-model").
+				WithClient(crashed.client()).
+				WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+			_, err := runner.Runner{Config: cfg}.Run(ctx, a, "1 + 1?")
+			require.Error(t, err)
+
+			// 新进程中重建 Agent 并从检查点继续
+			restarted := newFakeLLMServer(t, scriptedReply{text: "1 + 1 = 2"})
+			a = agent.New("calc").
+				WithModel("test-model").
+				WithClient(restarted.client()).
+				WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+			state, err := runner.LoadCheckpoint(ctx, store, a, "run-1")
+			require.NoError(t, err)
+			assert.Equal(t, "run-1", state.RunID)
+			assert.Equal(t, "calc", state.CurrentAgent)
+			assert.Equal(t, uint64(1), state.Turn)
+			assert.Nil(t, state.PendingTurn)
+			assert.Len(t, state.History, 3)
+			assert.Equal(t, uint64(1), state.Usage.Requests)
+
+			result, err := runner.Runner{Config: cfg}.ResumeFromState(ctx, state)
+			require.NoError(t, err)
+			assert.Equal(t, "1 + 1 = 2", result.FinalOutput)
+			assert.Len(t, result.NewItems, 3)
+			assert.Equal(t, uint64(2), result.Usage.Requests)
+
+			require.Equal(t, 1, restarted.requestCount())
+			messages := restarted.request(0)["messages"].([]any)
+			assert.Len(t, messages, 3)
+
+			// 运行完成后检查点被删除
+			_, err = store.Load(ctx, "run-1")
+			assert.True(t, errors.Is(err, checkpoint.ErrNotFound))
+		})
+	}
+}
+
+func TestRunner_CheckpointBeforeToolCalls(t *testing.T) {
+	ctx := context.Background()
+	fileStore, err := checkpoint.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	store := &recordingStore{Store: fileStore}
+
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_1", name: "calculator", arguments: `{"operation":"add","a":1,"b":1}`},
+		}},
+		scriptedReply{text: "done"},
+	)
+	a := agent.New("calc").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	r := runner.Runner{Config: runner.RunConfig{CheckpointStore: store, RunID: "run-2"}}
+
+	_, err = r.Run(ctx, a, "1 + 1?")
+	require.NoError(t, err)
+
+	// 第一轮开始、工具执行前、第二轮开始各保存一次
+	require.Len(t, store.saves, 3)
+	var turns []uint64
+	for _, data := range store.saves {
+		var state runner.RunState
+		require.NoError(t, json.Unmarshal(data, &state))
+		turns = append(turns, state.Turn)
+	}
+	assert.Equal(t, []uint64{0, 1, 1}, turns)
+
+	// 从工具执行前的检查点恢复：不再请求模型，直接执行工具
+	state, err := runner.LoadRunState(a, store.saves[1])
+	require.NoError(t, err)
+	require.NotNil(t, state.PendingTurn)
+	assert.Empty(t, state.PendingTurn.Interruptions)
+
+	resumed := newFakeLLMServer(t, scriptedReply{text: "resumed"})
+	a.WithClient(resumed.client())
+	result, err := runner.ResumeFromState(ctx, state)
+	require.NoError(t, err)
+	assert.Equal(t, "resumed", result.FinalOutput)
+	require.Equal(t, 1, resumed.requestCount())
+	messages := resumed.request(0)["messages"].([]any)
+	require.Len(t, messages, 3)
+	assert.Equal(t, "2", messages[2].(map[string]any)["content"])
+}
+
+// crashingStore 在第 saveLimit 次之后的保存或删除时失败，模拟进程在会话写入后崩溃
+type crashingStore struct {
+	checkpoint.Store
+	saveLimit int
+	saves     int
+}
+
+var errCrash = errors.New("process crashed")
+
+func (s *crashingStore) Save(ctx context.Context, runID string, data []byte) error {
+	if s.saveLimit > 0 && s.saves >= s.saveLimit {
+		return errCrash
+	}
+	s.saves++
+	return s.Store.Save(ctx, runID, data)
+}
+
+func (s *crashingStore) Delete(context.Context, string) error {
+	return errCrash
+}
+
+func TestRunner_ResumeAfterCrashDoesNotDuplicateSessionItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		saveLimit int
+	}{
+		// 工具轮已写入会话，下一轮的检查点保存前崩溃
+		{name: "AfterToolTurn", saveLimit: 2},
+		// 最终消息已写入会话，删除检查点前崩溃
+		{name: "AfterFinalMessage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			fileStore, err := checkpoint.NewFileStore(t.TempDir())
+			require.NoError(t, err)
+			session := newTestSQLiteSession(t, "crash")
+			a := agent.New("calc").WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+
+			crashed := fakemodel.New(
+				fakemodel.Output(fakemodel.ToolCall("call_1", "calculator", `{"operation":"add","a":1,"b":1}`)),
+				fakemodel.Text("1 + 1 = 2"),
+			)
+			_, err = runner.Runner{Config: runner.RunConfig{
+				ModelProvider:   crashed,
+				Session:         session,
+				CheckpointStore: &crashingStore{Store: fileStore, saveLimit: tt.saveLimit},
+				RunID:           "run-crash",
+			}}.Run(ctx, a, "1 + 1?")
+			require.ErrorIs(t, err, errCrash)
+
+			restarted := fakemodel.New(fakemodel.Text("1 + 1 = 2"))
+			r := runner.Runner{Config: runner.RunConfig{
+				ModelProvider:   restarted,
+				Session:         session,
+				CheckpointStore: fileStore,
+				RunID:           "run-crash",
+			}}
+			state, err := runner.LoadCheckpoint(ctx, fileStore, a, "run-crash")
+			require.NoError(t, err)
+			result, err := r.ResumeFromState(ctx, state)
+			require.NoError(t, err)
+			assert.Equal(t, "1 + 1 = 2", result.FinalOutput)
+
+			// 会话中每个条目只出现一次
+			items, err := session.GetItems(ctx, -1)
+			require.NoError(t, err)
+			assert.Equal(t, []string{
+				"user:1 + 1?",
+				"call:call_1", "output:call_1=2",
+				"assistant:1 + 1 = 2",
+			}, itemLabels(items))
+		})
+	}
+}
+
+func TestRunner_ResumeAfterCrashInSummarizingSession(t *testing.T) {
+	ctx := context.Background()
+	fileStore, err := checkpoint.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	session, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "compacting"), memory.SummarizingSessionConfig{
+		Summarizer: memory.SummarizerFunc(func(context.Context, []responses.ResponseInputItemUnionParam) (string, error) {
+			return "earlier chat", nil
+		}),
+		MaxItems:  6,
+		KeepItems: 5,
+	})
+	require.NoError(t, err)
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{
+		userItem("u0"), assistantItem("a0"), userItem("u1"), assistantItem("a1"),
+	}))
+	a := agent.New("calc").WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+
+	// 工具轮写入后会话被压缩，随后在下一轮的检查点保存前崩溃
+	crashed := fakemodel.New(fakemodel.Output(fakemodel.ToolCall("call_1", "calculator", `{"operation":"add","a":1,"b":1}`)))
+	_, err = runner.Runner{Config: runner.RunConfig{
+		ModelProvider:   crashed,
+		Session:         session,
+		CheckpointStore: &crashingStore{Store: fileStore, saveLimit: 2},
+		RunID:           "run-compact",
+	}}.Run(ctx, a, "1 + 1?")
+	require.ErrorIs(t, err, errCrash)
+	items, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	require.Equal(t, "system:"+memory.DefaultSummaryPrefix+"earlier chat", itemLabels(items)[0])
+
+	state, err := runner.LoadCheckpoint(ctx, fileStore, a, "run-compact")
+	require.NoError(t, err)
+	result, err := runner.Runner{Config: runner.RunConfig{
+		ModelProvider:   fakemodel.New(fakemodel.Text("1 + 1 = 2")),
+		Session:         session,
+		CheckpointStore: fileStore,
+	}}.ResumeFromState(ctx, state)
+	require.NoError(t, err)
+	assert.Equal(t, "1 + 1 = 2", result.FinalOutput)
+
+	// 压缩后仍能定位检查点，崩溃后写入的条目不会重复
+	items, err = session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"system:" + memory.DefaultSummaryPrefix + "earlier chat",
+		"assistant:a1", "user:1 + 1?",
+		"call:call_1", "output:call_1=2",
+		"assistant:1 + 1 = 2",
+	}, itemLabels(items))
+}
+
+func TestRunner_ResumeFromCheckpointWithTruncatedSession(t *testing.T) {
+	ctx := context.Background()
+	fileStore, err := checkpoint.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	session := newTestSQLiteSession(t, "truncated")
+	a := agent.New("calc").WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+
+	_, err = runner.Runner{Config: runner.RunConfig{
+		ModelProvider:   fakemodel.New(fakemodel.Output(fakemodel.ToolCall("call_1", "calculator", `{"operation":"add","a":1,"b":1}`))),
+		Session:         session,
+		CheckpointStore: &crashingStore{Store: fileStore, saveLimit: 2},
+		RunID:           "run-truncated",
+	}}.Run(ctx, a, "1 + 1?")
+	require.ErrorIs(t, err, errCrash)
+
+	// 会话在恢复前被清空，与检查点不再一致
+	require.NoError(t, session.ClearSession(ctx))
+	state, err := runner.LoadCheckpoint(ctx, fileStore, a, "run-truncated")
+	require.NoError(t, err)
+	restarted := fakemodel.New(fakemodel.Text("unreachable"))
+	_, err = runner.Runner{Config: runner.RunConfig{ModelProvider: restarted, Session: session}}.ResumeFromState(ctx, state)
+	assert.ErrorIs(t, err, runner.ErrInvalidRunState)
+	assert.ErrorContains(t, err, "session has 0 items, checkpoint expects 1")
+	assert.Empty(t, restarted.Requests())
+}
+
+func TestRunner_CheckpointRequiresRunID(t *testing.T) {
+	store, err := checkpoint.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	r := runner.Runner{Config: runner.RunConfig{CheckpointStore: store}}
+
+	_, err = r.Run(context.Background(), agent.New("a"), "hi")
+	assert.True(t, errors.Is(err, runner.ErrRunIDRequired))
+}
+
+func TestCheckpointStores(t *testing.T) {
+	for name, store := range checkpointStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			_, err := store.Load(ctx, "missing")
+			assert.True(t, errors.Is(err, checkpoint.ErrNotFound))
+
+			require.NoError(t, store.Save(ctx, "run", []byte(`{"turn":1}`)))
+			require.NoError(t, store.Save(ctx, "run", []byte(`{"turn":2}`)))
+			data, err := store.Load(ctx, "run")
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"turn":2}`, string(data))
+
+			require.NoError(t, store.Delete(ctx, "run"))
+			require.NoError(t, store.Delete(ctx, "run"))
+			_, err = store.Load(ctx, "run")
+			assert.True(t, errors.Is(err, checkpoint.ErrNotFound))
+
+			assert.True(t, errors.Is(store.Save(ctx, "", nil), checkpoint.ErrInvalidRunID))
+		})
+	}
+
+	fileStore, err := checkpoint.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	err = fileStore.Save(context.Background(), "../escape", []byte("{}"))
+	assert.True(t, errors.Is(err, checkpoint.ErrInvalidRunID))
+}
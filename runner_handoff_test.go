@@ -0,0 +1,231 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// systemPrompt 返回 Chat Completions 请求中的 system 消息
+func systemPrompt(req map[string]any) string {
+	for _, m := range req["messages"].([]any) {
+		msg := m.(map[string]any)
+		if msg["role"] == "system" {
+			return msg["content"].(string)
+		}
+	}
+	return ""
+}
+
+// toolNames 返回请求中携带的工具名称
+func toolNames(req map[string]any) []string {
+	var names []string
+	tools, _ := req["tools"].([]any)
+	for _, raw := range tools {
+		t := raw.(map[string]any)
+		if fn, ok := t["function"].(map[string]any); ok {
+			names = append(names, fn["name"].(string))
+		} else {
+			names = append(names, t["name"].(string))
+		}
+	}
+	return names
+}
+
+func TestHandoffToolName(t *testing.T) {
+	assert.Equal(t, "transfer_to_billing", agent.HandoffToolName("billing"))
+	assert.Equal(t, "transfer_to_billing_agent", agent.HandoffToolName("Billing Agent"))
+	assert.Equal(t, "transfer_to_custom", agent.Handoff{AgentName: "x", ToolName: "transfer_to_custom"}.GetToolName())
+}
+
+func TestRunner_Handoff(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_transfer", name: "transfer_to_billing", arguments: `{}`},
+		}},
+		scriptedReply{text: "Your invoice is on its way."},
+	)
+
+	billing := agent.New("billing").
+		WithInstructions("You handle billing.").
+		WithHandoffDescription("Handles invoices and refunds.").
+		WithModel("billing-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	triage := agent.New("triage").
+		WithInstructions("You route requests.").
+		WithModel("triage-model").
+		WithClient(server.client()).
+		AddHandoff(agent.HandoffTo(billing))
+
+	stream := runner.RunStreamed(context.Background(), triage, "I need my invoice")
+	var switches []runner.AgentSwitchedEvent
+	for ev := range stream.Events() {
+		if e, ok := ev.(runner.AgentSwitchedEvent); ok {
+			switches = append(switches, e)
+		}
+	}
+	result, err := stream.Wait()
+	require.NoError(t, err)
+
+	assert.Equal(t, billing, result.LastAgent)
+	assert.Equal(t, "Your invoice is on its way.", result.FinalOutput)
+
+	require.Len(t, switches, 2)
+	assert.Equal(t, triage, switches[1].Previous)
+	assert.Equal(t, billing, switches[1].Agent)
+
+	// 第一轮由 triage 处理，并暴露 transfer 工具
+	first := server.request(0)
+	assert.Equal(t, "triage-model", first["model"])
+	assert.Equal(t, "You route requests.", systemPrompt(first))
+	assert.Equal(t, []string{"transfer_to_billing"}, toolNames(first))
+	raw, _ := json.Marshal(first["tools"])
+	assert.Contains(t, string(raw), "Handles invoices and refunds.")
+
+	// 第二轮切换为 billing 的模型、指令与工具，并能看到完整历史
+	second := server.request(1)
+	assert.Equal(t, "billing-model", second["model"])
+	assert.Equal(t, "You handle billing.", systemPrompt(second))
+	assert.Equal(t, []string{"calculator"}, toolNames(second))
+	messages := second["messages"].([]any)
+	require.Len(t, messages, 4)
+	transfer := messages[3].(map[string]any)
+	assert.Equal(t, "call_transfer", transfer["tool_call_id"])
+	assert.JSONEq(t, `{"assistant":"billing"}`, transfer["content"].(string))
+}
+
+func TestRunner_HandoffInputFilter(t *testing.T) {
+	for _, useSession := range []bool{false, true} {
+		name := "History"
+		if useSession {
+			name = "Session"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeLLMServer(t,
+				scriptedReply{toolCalls: []scriptedToolCall{
+					{id: "call_transfer", name: "transfer_to_billing", arguments: `{}`},
+				}},
+				scriptedReply{toolCalls: []scriptedToolCall{
+					{id: "call_calc", name: "calculator", arguments: `{"operation":"add","a":1,"b":2}`},
+				}},
+				scriptedReply{text: "done"},
+			)
+
+			billing := agent.New("billing").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+			triage := agent.New("triage").
+				WithModel("test-model").
+				WithClient(server.client()).
+				AddHandoff(agent.HandoffTo(billing).WithInputFilter(agent.RemoveToolHistory))
+
+			var r runner.Runner
+			var session *memory.SQLiteSession
+			if useSession {
+				var err error
+				session, err = memory.NewSQLiteSession(ctx, memory.SQLiteSessionConfig{SessionID: "handoff-filter"})
+				require.NoError(t, err)
+				defer session.Close()
+				r.Config.Session = session
+			}
+
+			_, err := r.Run(ctx, triage, "refund please")
+			require.NoError(t, err)
+
+			// 过滤器移除了转交时的工具调用，但之后的新条目仍会发送
+			assert.Equal(t, []string{"user: refund please"}, chatTranscript(t, server.request(1)))
+			third := server.request(2)["messages"].([]any)
+			require.Len(t, third, 3)
+			assert.Equal(t, "call_calc", third[2].(map[string]any)["tool_call_id"])
+
+			if useSession {
+				// 会话中保存的历史不受过滤器影响
+				stored, err := session.GetItems(ctx, -1)
+				require.NoError(t, err)
+				assert.Len(t, stored, 6)
+			}
+		})
+	}
+}
+
+func TestRunner_HandoffResolver(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_transfer", name: "transfer_to_support", arguments: `{}`},
+		}},
+		scriptedReply{text: "support here"},
+	)
+	support := agent.New("support").WithModel("test-model").WithClient(server.client())
+	triage := agent.New("triage").
+		WithModel("test-model").
+		WithClient(server.client()).
+		AddHandoff(agent.HandoffToName("support"))
+
+	var resolved []string
+	r := runner.Runner{Config: runner.RunConfig{
+		HandoffResolver: func(_ context.Context, name string) (*agent.Agent, error) {
+			resolved = append(resolved, name)
+			return support, nil
+		},
+	}}
+	result, err := r.Run(context.Background(), triage, "help")
+	require.NoError(t, err)
+	assert.Equal(t, support, result.LastAgent)
+	assert.Equal(t, []string{"support"}, resolved)
+
+	// 未配置 HandoffResolver 时无法解析仅有名称的 handoff
+	server = newFakeLLMServer(t, scriptedReply{toolCalls: []scriptedToolCall{
+		{id: "call_transfer", name: "transfer_to_support", arguments: `{}`},
+	}})
+	triage.WithClient(server.client())
+	_, err = runner.Run(context.Background(), triage, "help")
+	assert.True(t, errors.Is(err, runner.ErrHandoffUnresolved))
+}
+
+func TestRunner_ResumeAfterNamedHandoff(t *testing.T) {
+	ctx := context.Background()
+	m := fakemodel.New(
+		fakemodel.Output(fakemodel.Handoff("call_support", "support")),
+		fakemodel.Output(fakemodel.Handoff("call_billing", "billing")),
+		fakemodel.Output(fakemodel.ToolCall("call_del", "delete_file", `{"path":"invoice.pdf"}`)),
+		fakemodel.Text("deleted"),
+	)
+	del := &deleteTool{}
+	billing := agent.New("billing").WithTools([]tool.FunctionTool{del.tool()})
+	support := agent.New("support").AddHandoff(agent.HandoffTo(billing))
+	triage := agent.New("triage").AddHandoff(agent.HandoffToName("support"))
+
+	// billing 只能经由按名称解析的 support 到达
+	r := runner.Runner{Config: runner.RunConfig{
+		ModelProvider: m,
+		HandoffResolver: func(_ context.Context, name string) (*agent.Agent, error) {
+			if name == "support" {
+				return support, nil
+			}
+			return nil, errors.New("unknown agent")
+		},
+	}}
+	result, err := r.Run(ctx, triage, "Delete my invoice")
+	require.NoError(t, err)
+	require.Len(t, result.Interruptions, 1)
+	assert.Equal(t, "billing", result.State.CurrentAgent)
+
+	result, err = r.Resume(ctx, reloadState(t, triage, result.State), []runner.ToolApproval{runner.Approve("call_del")})
+	require.NoError(t, err)
+	assert.Equal(t, billing, result.LastAgent)
+	assert.Equal(t, "deleted", result.FinalOutput)
+	assert.Equal(t, []string{`{"path":"invoice.pdf"}`}, del.invocations())
+}
@@ -0,0 +1,163 @@
+package agentgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/history"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func userItem(text string) responses.ResponseInputItemUnionParam {
+	return responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleUser)
+}
+
+func assistantItem(text string) responses.ResponseInputItemUnionParam {
+	return responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleAssistant)
+}
+
+// itemLabels 以简短标签描述历史项，便于断言
+func itemLabels(items []responses.ResponseInputItemUnionParam) []string {
+	var labels []string
+	for _, item := range items {
+		switch {
+		case item.OfMessage != nil:
+			labels = append(labels, string(item.OfMessage.Role)+":"+item.OfMessage.Content.OfString.Value)
+		case item.OfOutputMessage != nil:
+			text := ""
+			for _, part := range item.OfOutputMessage.Content {
+				if part.OfOutputText != nil {
+					text += part.OfOutputText.Text
+				}
+			}
+			labels = append(labels, "assistant:"+text)
+		case item.OfFunctionCall != nil:
+			labels = append(labels, "call:"+item.OfFunctionCall.CallID)
+		case item.OfFunctionCallOutput != nil:
+			labels = append(labels, "output:"+item.OfFunctionCallOutput.CallID+"="+item.OfFunctionCallOutput.Output.OfString.Value)
+		}
+	}
+	return labels
+}
+
+// perItem 把每个历史项计为一个 token
+var perItem = history.TokenCounterFunc(func(responses.ResponseInputItemUnionParam) int { return 1 })
+
+func sampleHistory() []responses.ResponseInputItemUnionParam {
+	return []responses.ResponseInputItemUnionParam{
+		responses.ResponseInputItemParamOfMessage("Be terse.", responses.EasyInputMessageRoleSystem),
+		userItem("first"),
+		responses.ResponseInputItemParamOfFunctionCall(`{}`, "call_a", "lookup"),
+		responses.ResponseInputItemParamOfFunctionCall(`{}`, "call_b", "lookup"),
+		responses.ResponseInputItemParamOfFunctionCallOutput("call_a", "result a"),
+		responses.ResponseInputItemParamOfFunctionCallOutput("call_b", "result b"),
+		assistantItem("answer"),
+		userItem("second"),
+	}
+}
+
+func TestHistoryStrategies(t *testing.T) {
+	ctx := context.Background()
+	items := sampleHistory()
+
+	t.Run("SlidingWindow", func(t *testing.T) {
+		trimmed, err := history.SlidingWindow{MaxTokens: 4, Counter: perItem}.Trim(ctx, items)
+		require.NoError(t, err)
+		// call_a 与其结果一起被丢弃，call_b 成对保留
+		assert.Equal(t, []string{"call:call_b", "output:call_b=result b", "assistant:answer", "user:second"}, itemLabels(trimmed))
+
+		trimmed, err = history.SlidingWindow{MaxTokens: 3, Counter: perItem, KeepSystem: true}.Trim(ctx, items)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"system:Be terse.", "assistant:answer", "user:second"}, itemLabels(trimmed))
+
+		// 最新的一项即使超出预算也会保留
+		trimmed, err = history.SlidingWindow{MaxTokens: 0, Counter: perItem}.Trim(ctx, items)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"user:second"}, itemLabels(trimmed))
+	})
+
+	t.Run("KeepSystemAndLastN", func(t *testing.T) {
+		trimmed, err := history.KeepSystemAndLastN{N: 5}.Trim(ctx, items)
+		require.NoError(t, err)
+		// 再保留 call_a 这一对就超过 5 项，因此只保留到 call_b
+		assert.Equal(t, []string{
+			"system:Be terse.", "call:call_b", "output:call_b=result b", "assistant:answer", "user:second",
+		}, itemLabels(trimmed))
+
+		trimmed, err = history.KeepSystemAndLastN{N: 3}.Trim(ctx, items)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"system:Be terse.", "assistant:answer", "user:second"}, itemLabels(trimmed))
+	})
+
+	t.Run("DropToolOutputsFirst", func(t *testing.T) {
+		counter := history.TokenCounterFunc(func(item responses.ResponseInputItemUnionParam) int {
+			if item.OfFunctionCallOutput != nil && item.OfFunctionCallOutput.Output.OfString.Value != "-" {
+				return 10
+			}
+			return 1
+		})
+		trimmed, err := history.DropToolOutputsFirst{MaxTokens: 17, Counter: counter, Placeholder: "-"}.Trim(ctx, items)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"system:Be terse.", "user:first", "call:call_a", "call:call_b",
+			"output:call_a=-", "output:call_b=result b", "assistant:answer", "user:second",
+		}, itemLabels(trimmed))
+
+		// 输入未被修改
+		assert.Equal(t, "result a", items[4].OfFunctionCallOutput.Output.OfString.Value)
+
+		// 只替换结果仍放不下时退回滑动窗口
+		trimmed, err = history.DropToolOutputsFirst{MaxTokens: 4, Counter: counter, Placeholder: "-"}.Trim(ctx, items)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"call:call_b", "output:call_b=-", "assistant:answer", "user:second"}, itemLabels(trimmed))
+	})
+}
+
+func TestRunner_HistoryStrategyTrimsRequestNotSession(t *testing.T) {
+	ctx := context.Background()
+	session, err := memory.NewSQLiteSession(ctx, memory.SQLiteSessionConfig{SessionID: "long"})
+	require.NoError(t, err)
+	defer session.Close()
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[1:]))
+
+	m := fakemodel.New(
+		fakemodel.Output(fakemodel.ToolCall("call_c", "calculator", `{"operation":"add","a":1,"b":1}`)),
+		fakemodel.Text("2"),
+	)
+	a := agent.New("calc").WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	r := runner.Runner{Config: runner.RunConfig{
+		ModelProvider:   m,
+		Session:         session,
+		HistoryStrategy: history.KeepSystemAndLastN{N: 3},
+	}}
+
+	result, err := r.Run(ctx, a, "1 + 1?")
+	require.NoError(t, err)
+	assert.Equal(t, "2", result.FinalOutput)
+
+	requests := m.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, []string{"assistant:answer", "user:second", "user:1 + 1?"}, itemLabels(requests[0].Input))
+	assert.Equal(t, []string{"user:1 + 1?", "call:call_c", "output:call_c=2"}, itemLabels(requests[1].Input))
+
+	// 会话保存完整历史
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Len(t, stored, 7+4)
+}
+
+func TestCountTokens(t *testing.T) {
+	items := []responses.ResponseInputItemUnionParam{userItem("hello world")}
+	assert.Positive(t, history.CountTokens(nil, items))
+	assert.Equal(t, 1, history.CountTokens(perItem, items))
+	long := []responses.ResponseInputItemUnionParam{userItem(string(make([]byte, 400)))}
+	assert.Greater(t, history.CountTokens(nil, long), history.CountTokens(nil, items))
+}
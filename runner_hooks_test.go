@@ -0,0 +1,156 @@
+package agentgo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hookLog 按调用顺序记录回调
+type hookLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *hookLog) add(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, fmt.Sprintf(format, args...))
+}
+
+// recordingRunHooks 实现全部 RunHooks 回调
+type recordingRunHooks struct{ log *hookLog }
+
+func (h recordingRunHooks) OnRunStart(_ context.Context, a *agent.Agent, _ types.Input) {
+	h.log.add("run start %s", a.Name)
+}
+func (h recordingRunHooks) OnRunEnd(_ context.Context, result *runner.RunResult, err error) {
+	h.log.add("run end %v %v", result != nil, err)
+}
+func (h recordingRunHooks) OnAgentStart(_ context.Context, a *agent.Agent) {
+	h.log.add("agent start %s", a.Name)
+}
+func (h recordingRunHooks) OnAgentEnd(_ context.Context, a *agent.Agent, output any) {
+	h.log.add("agent end %s %v", a.Name, output)
+}
+func (h recordingRunHooks) OnHandoff(_ context.Context, from, to *agent.Agent) {
+	h.log.add("handoff %s -> %s", from.Name, to.Name)
+}
+func (h recordingRunHooks) OnLLMStart(_ context.Context, a *agent.Agent, _ string, input []responses.ResponseInputItemUnionParam) {
+	h.log.add("llm start %s %d", a.Name, len(input))
+}
+func (h recordingRunHooks) OnLLMEnd(_ context.Context, a *agent.Agent, response runner.ModelResponse) {
+	h.log.add("llm end %s %d", a.Name, len(response.Output))
+}
+func (h recordingRunHooks) OnToolStart(_ context.Context, a *agent.Agent, call responses.ResponseFunctionToolCall) {
+	h.log.add("tool start %s %s %s", a.Name, call.Name, call.Arguments)
+}
+func (h recordingRunHooks) OnToolEnd(_ context.Context, a *agent.Agent, call responses.ResponseFunctionToolCall, output string) {
+	h.log.add("tool end %s %s %s", a.Name, call.Name, output)
+}
+func (h recordingRunHooks) OnInputGuardrail(_ context.Context, a *agent.Agent, result agent.InputGuardrailResult) {
+	h.log.add("input guardrail %s %s", a.Name, result.Guardrail.Name)
+}
+func (h recordingRunHooks) OnOutputGuardrail(_ context.Context, a *agent.Agent, result agent.OutputGuardrailResult) {
+	h.log.add("output guardrail %s %s", a.Name, result.Guardrail.Name)
+}
+
+// recordingAgentHooks 只实现部分 AgentHooks 回调
+type recordingAgentHooks struct {
+	agent.NoOpAgentHooks
+	log *hookLog
+}
+
+func (h recordingAgentHooks) OnStart(_ context.Context, a *agent.Agent) {
+	h.log.add("[%s] start", a.Name)
+}
+func (h recordingAgentHooks) OnHandoff(_ context.Context, a, source *agent.Agent) {
+	h.log.add("[%s] handoff from %s", a.Name, source.Name)
+}
+func (h recordingAgentHooks) OnToolEnd(_ context.Context, a *agent.Agent, call responses.ResponseFunctionToolCall, output string) {
+	h.log.add("[%s] tool end %s %s", a.Name, call.Name, output)
+}
+func (h recordingAgentHooks) OnEnd(_ context.Context, a *agent.Agent, output any) {
+	h.log.add("[%s] end %v", a.Name, output)
+}
+
+func passGuardrail(context.Context, types.AgentLike, any) (agent.GuardrailFunctionOutput, error) {
+	return agent.GuardrailFunctionOutput{}, nil
+}
+
+func TestRunner_Hooks(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{{id: "call_transfer", name: "transfer_to_math", arguments: `{}`}}},
+		scriptedReply{toolCalls: []scriptedToolCall{{id: "call_add", name: "calculator", arguments: `{"operation":"add","a":1,"b":1}`}}},
+		scriptedReply{text: "2"},
+	)
+	log := &hookLog{}
+	math := agent.New("math").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()}).
+		WithOutputGuardrails([]agent.OutputGuardrail{agent.NewOutputGuardrail("check_output", passGuardrail)}).
+		WithHooks(recordingAgentHooks{log: log})
+	triage := agent.New("triage").
+		WithModel("test-model").
+		WithClient(server.client()).
+		AddHandoff(agent.HandoffTo(math)).
+		WithInputGuardrails([]agent.InputGuardrail{agent.NewInputGuardrail("check_input",
+			func(context.Context, types.AgentLike, types.Input) (agent.GuardrailFunctionOutput, error) {
+				return agent.GuardrailFunctionOutput{}, nil
+			})})
+
+	r := runner.Runner{Config: runner.RunConfig{Hooks: recordingRunHooks{log: log}}}
+	_, err := r.Run(context.Background(), triage, "1+1")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"run start triage",
+		"input guardrail triage check_input",
+		"agent start triage",
+		"llm start triage 1",
+		"llm end triage 1",
+		"handoff triage -> math",
+		"[math] handoff from triage",
+		"agent start math",
+		"[math] start",
+		"llm start math 3",
+		"llm end math 1",
+		`tool start math calculator {"operation":"add","a":1,"b":1}`,
+		"tool end math calculator 2",
+		"[math] tool end calculator 2",
+		"llm start math 5",
+		"llm end math 1",
+		"agent end math 2",
+		"[math] end 2",
+		"output guardrail math check_output",
+		"run end true <nil>",
+	}, log.events)
+}
+
+func TestRunner_HooksOnError(t *testing.T) {
+	server := newFakeLLMServer(t)
+	log := &hookLog{}
+	a := agent.New("broken").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithHooks(recordingAgentHooks{log: log})
+
+	r := runner.Runner{Config: runner.RunConfig{Hooks: recordingRunHooks{log: log}}}
+	_, err := r.Run(context.Background(), a, "hi")
+	require.Error(t, err)
+
+	require.Len(t, log.events, 5)
+	assert.Equal(t, []string{"run start broken", "agent start broken", "[broken] start", "llm start broken 1"}, log.events[:4])
+	assert.Contains(t, log.events[4], "run end false")
+}
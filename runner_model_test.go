@@ -0,0 +1,166 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cannedModel 是不依赖 OpenAI 的自定义模型，按顺序返回预设输出项
+type cannedModel struct {
+	mu       sync.Mutex
+	outputs  [][]string
+	requests []model.Request
+}
+
+func (m *cannedModel) GetResponse(_ context.Context, req model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, req)
+	if len(m.outputs) == 0 {
+		return nil, errors.New("no more canned outputs")
+	}
+	raw := m.outputs[0]
+	m.outputs = m.outputs[1:]
+
+	resp := &model.Response{Usage: &model.Usage{Requests: 1, TotalTokens: 7}}
+	for _, r := range raw {
+		var item responses.ResponseOutputItemUnion
+		if err := json.Unmarshal([]byte(r), &item); err != nil {
+			return nil, err
+		}
+		resp.Output = append(resp.Output, item)
+	}
+	return resp, nil
+}
+
+// StreamResponse 将文本逐字作为增量事件发出
+func (m *cannedModel) StreamResponse(ctx context.Context, req model.Request, emit func(model.StreamEvent)) (*model.Response, error) {
+	resp, err := m.GetResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range resp.Output {
+		if msg, ok := item.AsAny().(responses.ResponseOutputMessage); ok {
+			for _, r := range msg.Content[0].Text {
+				emit(model.TextDelta{Delta: string(r)})
+			}
+		}
+	}
+	return resp, nil
+}
+
+// cannedProvider 按名称返回模型
+type cannedProvider map[string]model.Model
+
+func (p cannedProvider) GetModel(name string) (model.Model, error) {
+	if m, ok := p[name]; ok {
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown model %q", name)
+}
+
+func cannedMessage(text string) string {
+	return fmt.Sprintf(`{"id":"msg_1","type":"message","role":"assistant","status":"completed","content":[{"type":"output_text","text":%q,"annotations":[]}]}`, text)
+}
+
+func cannedToolCall(callID, name, arguments string) string {
+	return fmt.Sprintf(`{"id":"fc_1","type":"function_call","call_id":%q,"name":%q,"arguments":%q,"status":"completed"}`, callID, name, arguments)
+}
+
+func TestRunner_ModelProvider(t *testing.T) {
+	m := &cannedModel{outputs: [][]string{
+		{cannedToolCall("call_1", "calculator", `{"operation":"add","a":1,"b":1}`)},
+		{cannedMessage("1 + 1 = 2")},
+	}}
+	// 不设置 OpenAI 客户端，所有请求都经过自定义模型
+	a := agent.New("calc").
+		WithInstructions("You add numbers.").
+		WithModel("canned").
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: cannedProvider{"canned": m}}}
+
+	result, err := r.Run(context.Background(), a, "1 + 1?")
+	require.NoError(t, err)
+	assert.Equal(t, "1 + 1 = 2", result.FinalOutput)
+	require.Len(t, result.RawResponses, 2)
+	assert.Equal(t, "canned", result.RawResponses[0].Model)
+	assert.Equal(t, uint64(2), result.Usage.Requests)
+	assert.Equal(t, uint64(14), result.Usage.ByModel["canned"].TotalTokens)
+
+	require.Len(t, m.requests, 2)
+	first := m.requests[0]
+	assert.Equal(t, "You add numbers.", first.Instructions)
+	require.Len(t, first.Tools, 1)
+	assert.Equal(t, "calculator", first.Tools[0].ToolName())
+	assert.Nil(t, first.Prompt)
+
+	// 第二次请求包含工具调用及其结果
+	second := m.requests[1].Input
+	require.Len(t, second, 3)
+	require.NotNil(t, second[2].OfFunctionCallOutput)
+	assert.Equal(t, "2", second[2].OfFunctionCallOutput.Output.OfString.Value)
+}
+
+func TestRunner_ModelProviderStreamed(t *testing.T) {
+	m := &cannedModel{outputs: [][]string{{cannedMessage("hey")}}}
+	a := agent.New("assistant").WithModel("canned")
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: cannedProvider{"canned": m}}}
+
+	stream := r.RunStreamed(context.Background(), a, "hi")
+	var deltas []string
+	for ev := range stream.Events() {
+		if d, ok := ev.(runner.TextDeltaEvent); ok {
+			deltas = append(deltas, d.Delta)
+		}
+	}
+	result, err := stream.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, "hey", result.FinalOutput)
+	assert.Equal(t, []string{"h", "e", "y"}, deltas)
+}
+
+func TestRunner_ModelProviderUnknownModel(t *testing.T) {
+	a := agent.New("assistant").WithModel("missing")
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: cannedProvider{}}}
+
+	_, err := r.Run(context.Background(), a, "hi")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown model "missing"`)
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	for _, useResponses := range []bool{false, true} {
+		t.Run(fmt.Sprintf("UseResponses=%v", useResponses), func(t *testing.T) {
+			server := newFakeLLMServer(t, scriptedReply{text: "hello"})
+			provider := model.OpenAIProvider{Client: server.client(), UseResponses: useResponses}
+			a := agent.New("assistant").WithModel("test-model")
+			r := runner.Runner{Config: runner.RunConfig{ModelProvider: provider}}
+
+			// Responses API 不再要求设置 Prompt
+			result, err := r.Run(context.Background(), a, "hi")
+			require.NoError(t, err)
+			assert.Equal(t, "hello", finalText(t, result.FinalOutput))
+
+			require.Equal(t, 1, server.requestCount())
+			path := "/chat/completions"
+			if useResponses {
+				path = "/responses"
+			}
+			assert.Equal(t, path, server.paths[0])
+			assert.Equal(t, "test-model", server.request(0)["model"])
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type weatherReport struct {
+	City        string `json:"city"`
+	Temperature int    `json:"temperature"`
+}
+
+// weatherOutputType 是手写的 OutputTypeInterface 实现
+type weatherOutputType struct{}
+
+func (weatherOutputType) IsPlainText() bool        { return false }
+func (weatherOutputType) Name() string             { return "weather report" }
+func (weatherOutputType) IsStrictJSONSchema() bool { return true }
+
+func (weatherOutputType) JSONSchema() (map[string]any, error) {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city":        map[string]any{"type": "string"},
+			"temperature": map[string]any{"type": "integer"},
+		},
+		"required":             []string{"city", "temperature"},
+		"additionalProperties": false,
+	}, nil
+}
+
+func (weatherOutputType) ValidateJSON(_ context.Context, jsonStr string) (any, error) {
+	var report weatherReport
+	if err := json.Unmarshal([]byte(jsonStr), &report); err != nil {
+		return nil, err
+	}
+	if report.City == "" {
+		return nil, fmt.Errorf("city is required")
+	}
+	return report, nil
+}
+
+func TestRunner_StructuredOutput(t *testing.T) {
+	paths := []struct {
+		name   string
+		prompt agent.Prompter
+		format func(req map[string]any) map[string]any
+	}{
+		{
+			name: "ChatCompletions",
+			format: func(req map[string]any) map[string]any {
+				rf := req["response_format"].(map[string]any)
+				assert.Equal(t, "json_schema", rf["type"])
+				return rf["json_schema"].(map[string]any)
+			},
+		},
+		{
+			name:   "Responses",
+			prompt: agent.Prompt{ID: "pmpt_test"},
+			format: func(req map[string]any) map[string]any {
+				format := req["text"].(map[string]any)["format"].(map[string]any)
+				assert.Equal(t, "json_schema", format["type"])
+				return format
+			},
+		},
+	}
+
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			server := newFakeLLMServer(t, scriptedReply{text: `{"city":"Paris","temperature":21}`})
+			a := agent.New("weather").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithOutputType(weatherOutputType{})
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+
+			result, err := runner.Run(context.Background(), a, "Weather in Paris?")
+			require.NoError(t, err)
+			assert.Equal(t, weatherReport{City: "Paris", Temperature: 21}, result.FinalOutput)
+
+			format := p.format(server.request(0))
+			assert.Equal(t, "weather_report", format["name"])
+			assert.Equal(t, true, format["strict"])
+			assert.Equal(t, "object", format["schema"].(map[string]any)["type"])
+		})
+	}
+}
+
+func TestRunner_StructuredOutputRetry(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{text: `{"temperature":21}`},
+		scriptedReply{text: `{"city":"Paris","temperature":21}`},
+	)
+	a := agent.New("weather").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithOutputType(weatherOutputType{})
+
+	r := runner.Runner{Config: runner.RunConfig{OutputValidationRetries: 1}}
+	result, err := r.Run(context.Background(), a, "Weather in Paris?")
+	require.NoError(t, err)
+	assert.Equal(t, weatherReport{City: "Paris", Temperature: 21}, result.FinalOutput)
+
+	// 第二次请求应包含校验错误，提示模型修正
+	transcript := chatTranscript(t, server.request(1))
+	require.Len(t, transcript, 3)
+	assert.Contains(t, transcript[2], "city is required")
+}
+
+func TestRunner_StructuredOutputValidationError(t *testing.T) {
+	server := newFakeLLMServer(t, scriptedReply{text: "not json"})
+	a := agent.New("weather").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithOutputType(weatherOutputType{})
+
+	_, err := runner.Run(context.Background(), a, "Weather in Paris?")
+	var validationErr *runner.OutputValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "not json", validationErr.Output)
+	assert.Equal(t, 1, server.requestCount())
+}
+
+type forecast struct {
+	City    string   `json:"city" jsonschema:"description=City name"`
+	Unit    string   `json:"unit" jsonschema:"enum=celsius|fahrenheit"`
+	Highs   []int    `json:"highs"`
+	Warning *string  `json:"warning,omitempty"`
+	Notes   []string `json:"-"`
+}
+
+func TestOutputTypeFor(t *testing.T) {
+	ot := agent.OutputTypeFor[forecast]()
+	assert.False(t, ot.IsPlainText())
+	assert.True(t, ot.IsStrictJSONSchema())
+	assert.Equal(t, "forecast", ot.Name())
+
+	schema, err := ot.JSONSchema()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"city", "highs", "unit", "warning"}, schema["required"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, "City name", props["city"].(map[string]any)["description"])
+	assert.Equal(t, []any{"celsius", "fahrenheit"}, props["unit"].(map[string]any)["enum"])
+	assert.Contains(t, props["warning"], "anyOf")
+
+	parsed, err := ot.ValidateJSON(context.Background(), `{"city":"Oslo","unit":"celsius","highs":[3,4],"warning":null}`)
+	require.NoError(t, err)
+	assert.Equal(t, forecast{City: "Oslo", Unit: "celsius", Highs: []int{3, 4}}, parsed)
+
+	_, err = ot.ValidateJSON(context.Background(), `{"city":`)
+	assert.Error(t, err)
+
+	// 非对象类型会被包装在 response 字段中
+	list := agent.OutputTypeFor[[]string]()
+	schema, err = list.JSONSchema()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"response"}, schema["required"])
+	parsed, err = list.ValidateJSON(context.Background(), `{"response":["a","b"]}`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, parsed)
+
+	assert.True(t, agent.OutputTypeFor[string]().IsPlainText())
+}
+
+func TestRunTyped(t *testing.T) {
+	paths := []struct {
+		name   string
+		prompt agent.Prompter
+	}{
+		{name: "ChatCompletions"},
+		{name: "Responses", prompt: agent.Prompt{ID: "pmpt_test"}},
+	}
+
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			server := newFakeLLMServer(t,
+				scriptedReply{text: `{"city":"Oslo","unit":"celsius","highs":[3],"warning":"snow"}`},
+				scriptedReply{text: "plain answer"},
+			)
+			a := agent.New("forecaster").WithModel("test-model").WithClient(server.client())
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+
+			got, err := runner.RunTyped[forecast](context.Background(), runner.DefaultRunner, a, "Forecast for Oslo")
+			require.NoError(t, err)
+			assert.Equal(t, "Oslo", got.City)
+			require.NotNil(t, got.Warning)
+			assert.Equal(t, "snow", *got.Warning)
+			// 调用方的 Agent 不会被修改
+			assert.Nil(t, a.OutputType)
+
+			text, err := runner.RunTyped[string](context.Background(), runner.DefaultRunner, a, "Say something")
+			require.NoError(t, err)
+			assert.Equal(t, "plain answer", text)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package agentgo
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyProbe 记录同时运行的工具调用数量
+type concurrencyProbe struct {
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (p *concurrencyProbe) tool(delays map[string]time.Duration) tool.FunctionTool {
+	return tool.NewFunctionTool("work", "Do some work",
+		func(ctx context.Context, args struct {
+			ID string `json:"id"`
+		}) (string, error) {
+			n := p.running.Add(1)
+			defer p.running.Add(-1)
+			for {
+				peak := p.peak.Load()
+				if n <= peak || p.peak.CompareAndSwap(peak, n) {
+					break
+				}
+			}
+			select {
+			case <-time.After(delays[args.ID]):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+			return "done " + args.ID, nil
+		})
+}
+
+func workCalls(ids ...string) scriptedReply {
+	var calls []scriptedToolCall
+	for _, id := range ids {
+		calls = append(calls, scriptedToolCall{
+			id:        "call_" + id,
+			name:      "work",
+			arguments: fmt.Sprintf(`{"id":%q}`, id),
+		})
+	}
+	return scriptedReply{toolCalls: calls}
+}
+
+// toolMessages 返回请求中 tool 消息的 (tool_call_id, content)
+func toolMessages(req map[string]any) [][2]string {
+	var out [][2]string
+	for _, m := range req["messages"].([]any) {
+		msg := m.(map[string]any)
+		if msg["role"] == "tool" {
+			out = append(out, [2]string{msg["tool_call_id"].(string), msg["content"].(string)})
+		}
+	}
+	return out
+}
+
+func TestRunner_ParallelToolCalls(t *testing.T) {
+	// 先发起的调用耗时最长，确保结果顺序与完成顺序无关
+	delays := map[string]time.Duration{"a": 150 * time.Millisecond, "b": 100 * time.Millisecond, "c": 50 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		config   runner.RunConfig
+		settings agent.ModelSettings
+		wantPeak int32
+	}{
+		{name: "Unlimited", wantPeak: 3},
+		{name: "Limited", config: runner.RunConfig{MaxToolConcurrency: 2}, wantPeak: 2},
+		{name: "Sequential", config: runner.RunConfig{MaxToolConcurrency: 1}, wantPeak: 1},
+		{name: "ParallelDisabled", settings: agent.ModelSettings{ParallelToolCalls: param.NewOpt(false)}, wantPeak: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeLLMServer(t, workCalls("a", "b", "c"), scriptedReply{text: "all done"})
+			probe := &concurrencyProbe{}
+			a := agent.New("worker").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithModelSettings(tt.settings).
+				WithTools([]tool.FunctionTool{probe.tool(delays)})
+
+			r := runner.Runner{Config: tt.config}
+			_, err := r.Run(context.Background(), a, "work")
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantPeak, probe.peak.Load())
+			assert.Equal(t, [][2]string{
+				{"call_a", "done a"},
+				{"call_b", "done b"},
+				{"call_c", "done c"},
+			}, toolMessages(server.request(1)))
+		})
+	}
+}
+
+func TestRunner_ToolCallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	// stuck 忽略 ctx，模拟不响应取消的慢速工具
+	stuck := tool.NewFunctionTool("stuck", "Never returns in time",
+		func(context.Context, struct{}) (string, error) {
+			<-release
+			return "late", nil
+		})
+	fast := tool.NewFunctionTool("fast", "Returns immediately",
+		func(context.Context, struct{}) (string, error) {
+			return "quick", nil
+		})
+
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_stuck", name: "stuck", arguments: `{}`},
+			{id: "call_fast", name: "fast", arguments: `{}`},
+		}},
+		scriptedReply{text: "ok"},
+	)
+	a := agent.New("worker").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{stuck, fast})
+
+	r := runner.Runner{Config: runner.RunConfig{
+		ToolCallTimeout:       50 * time.Millisecond,
+		ToolCancelGracePeriod: 50 * time.Millisecond,
+	}}
+	start := time.Now()
+	_, err := r.Run(context.Background(), a, "go")
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	msgs := toolMessages(server.request(1))
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "call_stuck", msgs[0][0])
+	assert.Contains(t, msgs[0][1], "deadline exceeded")
+	assert.Equal(t, [2]string{"call_fast", "quick"}, msgs[1])
+}
+
+func TestRunner_ToolCallTimeoutWaitsForTool(t *testing.T) {
+	var running, finished atomic.Bool
+
+	// slow 响应取消，但需要一段时间清理
+	slow := tool.NewFunctionTool("slow", "Cleans up after cancellation",
+		func(ctx context.Context, _ struct{}) (string, error) {
+			running.Store(true)
+			defer running.Store(false)
+			<-ctx.Done()
+			time.Sleep(100 * time.Millisecond)
+			finished.Store(true)
+			return "", ctx.Err()
+		})
+
+	m := fakemodel.New(
+		fakemodel.Output(fakemodel.ToolCall("call_slow", "slow", `{}`)),
+		fakemodel.Text("ok"),
+	)
+	a := agent.New("worker").WithTools([]tool.FunctionTool{slow})
+
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: m, ToolCallTimeout: 20 * time.Millisecond}}
+	_, err := r.Run(context.Background(), a, "go")
+	require.NoError(t, err)
+
+	// 回合结束时工具已经停止
+	assert.False(t, running.Load())
+	assert.True(t, finished.Load())
+	output, ok := fakemodel.ToolOutput(m.Requests()[1], "call_slow")
+	require.True(t, ok)
+	assert.Contains(t, output, "deadline exceeded")
+}
@@ -0,0 +1,301 @@
+package agentgo
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/config"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/cassette"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner_RetryTransientErrors(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After-Ms": "50"}},
+		scriptedReply{status: http.StatusServiceUnavailable},
+		scriptedReply{text: "ok"},
+	)
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+	r := runner.Runner{Config: runner.RunConfig{RetryPolicy: runner.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}}}
+
+	start := time.Now()
+	result, err := r.Run(context.Background(), a, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.FinalOutput)
+	assert.Equal(t, 3, server.requestCount())
+
+	// 第一次失败按 Retry-After-Ms 等待
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	require.Len(t, result.RawResponses, 1)
+	assert.Equal(t, "test-model", result.RawResponses[0].Model)
+	assert.Equal(t, uint64(1), result.Usage.Requests)
+}
+
+func TestRunner_RetryAfterCappedByMaxBackoff(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "3600"}},
+		scriptedReply{text: "ok"},
+	)
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+	r := runner.Runner{Config: runner.RunConfig{RetryPolicy: runner.RetryPolicy{
+		MaxAttempts: 2,
+		MaxBackoff:  10 * time.Millisecond,
+	}}}
+
+	// 服务端要求等待一小时，实际等待不超过 MaxBackoff
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := r.Run(ctx, a, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result.FinalOutput)
+	assert.Equal(t, 2, server.requestCount())
+}
+
+func TestRunner_RetryStopsOnPermanentError(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{status: http.StatusBadRequest},
+		scriptedReply{text: "unreachable"},
+	)
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+	r := runner.Runner{Config: runner.RunConfig{RetryPolicy: runner.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}}}
+
+	_, err := r.Run(context.Background(), a, "hi")
+	var apiErr *openai.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, 1, server.requestCount())
+}
+
+func TestRunner_FallbackModels(t *testing.T) {
+	paths := []struct {
+		name   string
+		prompt agent.Prompter
+	}{
+		{name: "ChatCompletions"},
+		{name: "Responses", prompt: agent.Prompt{ID: "pmpt_test"}},
+	}
+
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			primary := newFakeLLMServer(t,
+				scriptedReply{status: http.StatusInternalServerError},
+				scriptedReply{status: http.StatusBadGateway},
+			)
+			exhausted := newFakeLLMServer(t,
+				scriptedReply{status: http.StatusTooManyRequests},
+				scriptedReply{status: http.StatusTooManyRequests},
+			)
+			backup := newFakeLLMServer(t, scriptedReply{text: "answered by backup"})
+
+			a := agent.New("assistant").WithModel("primary-model").WithClient(primary.client())
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+			r := runner.Runner{Config: runner.RunConfig{
+				RetryPolicy: runner.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+				FallbackModels: []config.ModelConfig{
+					{Name: "exhausted", BaseURL: exhausted.URL, APIKey: "key", Model: "exhausted-model"},
+					{Name: "backup", BaseURL: backup.URL, APIKey: "key", Model: "backup-model"},
+				},
+			}}
+
+			result, err := r.Run(context.Background(), a, "hi")
+			require.NoError(t, err)
+			assert.Equal(t, "answered by backup", finalText(t, result.FinalOutput))
+
+			// 每个模型用完自己的重试次数后才切换到下一个
+			assert.Equal(t, 2, primary.requestCount())
+			assert.Equal(t, 2, exhausted.requestCount())
+			require.Equal(t, 1, backup.requestCount())
+			assert.Equal(t, "backup-model", backup.request(0)["model"])
+
+			assert.Equal(t, "backup-model", result.RawResponses[0].Model)
+			assert.Contains(t, result.Usage.ByModel, "backup-model")
+			assert.NotContains(t, result.Usage.ByModel, "primary-model")
+		})
+	}
+}
+
+// countingProvider 记录每个模型名被解析的次数
+type countingProvider struct {
+	model.Provider
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (p *countingProvider) GetModel(name string) (model.Model, error) {
+	p.mu.Lock()
+	p.calls[name]++
+	p.mu.Unlock()
+	return p.Provider.GetModel(name)
+}
+
+func TestRunner_FallbackModelsFromProvider(t *testing.T) {
+	unavailable := fmt.Errorf("call API: %w", &openai.Error{StatusCode: http.StatusServiceUnavailable})
+	primary := fakemodel.New(
+		fakemodel.Error(unavailable), fakemodel.Error(unavailable),
+		fakemodel.Error(unavailable), fakemodel.Error(unavailable),
+	)
+	backup := fakemodel.New(
+		fakemodel.Output(fakemodel.ToolCall("call_1", "calculator", `{"operation":"add","a":1,"b":1}`)),
+		fakemodel.Text("answered by backup"),
+	)
+	provider := &countingProvider{
+		Provider: fakemodel.Provider{"primary-model": primary, "backup-model": backup},
+		calls:    map[string]int{},
+	}
+
+	a := agent.New("assistant").WithModel("primary-model").WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	r := runner.Runner{Config: runner.RunConfig{
+		ModelProvider: provider,
+		RetryPolicy:   runner.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		// BaseURL 不可达：使用 ModelProvider 时不会请求它
+		FallbackModels: []config.ModelConfig{{Name: "backup", BaseURL: "http://127.0.0.1:1", Model: "backup-model"}},
+	}}
+
+	result, err := r.Run(context.Background(), a, "1 + 1?")
+	require.NoError(t, err)
+	assert.Equal(t, "answered by backup", result.FinalOutput)
+	assert.Zero(t, primary.Remaining())
+	assert.Zero(t, backup.Remaining())
+
+	// 备用模型每次运行只解析一次
+	assert.Equal(t, 1, provider.calls["backup-model"])
+	assert.Equal(t, 2, provider.calls["primary-model"])
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestRunner_FallbackModelsInheritClientOptions(t *testing.T) {
+	primary := newFakeLLMServer(t, scriptedReply{status: http.StatusInternalServerError})
+	backup := newFakeLLMServer(t, scriptedReply{text: "answered by backup"})
+
+	var transported atomic.Int32
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		transported.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+	client := openai.NewClient(
+		option.WithAPIKey("test-key"),
+		option.WithBaseURL(primary.URL),
+		option.WithMaxRetries(0),
+		option.WithHTTPClient(httpClient),
+		option.WithHeader("OpenAI-Organization", "org-test"),
+	)
+	a := agent.New("assistant").WithModel("primary-model").WithClient(client)
+	r := runner.Runner{Config: runner.RunConfig{
+		FallbackModels: []config.ModelConfig{{Name: "backup", BaseURL: backup.URL, Model: "backup-model"}},
+	}}
+
+	result, err := r.Run(context.Background(), a, "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "answered by backup", result.FinalOutput)
+
+	// 备用模型沿用 Agent 客户端的 HTTP 客户端、请求头和 API key
+	require.Equal(t, 1, backup.requestCount())
+	assert.Equal(t, int32(2), transported.Load())
+	assert.Equal(t, "org-test", backup.headers[0].Get("OpenAI-Organization"))
+	assert.Equal(t, "Bearer test-key", backup.headers[0].Get("Authorization"))
+}
+
+func TestRunner_RetryStreamedRequest(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{status: http.StatusServiceUnavailable},
+		scriptedReply{text: "streamed"},
+	)
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+	r := runner.Runner{Config: runner.RunConfig{RetryPolicy: runner.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	}}}
+
+	stream := r.RunStreamed(context.Background(), a, "hi")
+	for range stream.Events() {
+	}
+	result, err := stream.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", result.FinalOutput)
+	assert.Equal(t, 2, server.requestCount())
+}
+
+func TestIsRetryable(t *testing.T) {
+	statusErr := func(code int) error {
+		return fmt.Errorf("call API: %w", &openai.Error{StatusCode: code})
+	}
+	assert.True(t, runner.IsRetryable(statusErr(http.StatusTooManyRequests)))
+	assert.True(t, runner.IsRetryable(statusErr(http.StatusRequestTimeout)))
+	assert.True(t, runner.IsRetryable(statusErr(http.StatusInternalServerError)))
+	assert.False(t, runner.IsRetryable(statusErr(http.StatusUnauthorized)))
+	assert.False(t, runner.IsRetryable(statusErr(http.StatusNotFound)))
+	assert.False(t, runner.IsRetryable(context.Canceled))
+	assert.False(t, runner.IsRetryable(errors.New("boom")))
+
+	// 网络错误只重试超时、连接重置或拒绝以及响应截断
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://api.example.com/v1/chat/completions", Err: err}
+	}
+	syscallErr := func(errno syscall.Errno) error {
+		return urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)})
+	}
+	assert.True(t, runner.IsRetryable(urlErr(&net.DNSError{Err: "i/o timeout", Name: "api.example.com", IsTimeout: true})))
+	assert.True(t, runner.IsRetryable(syscallErr(syscall.ECONNRESET)))
+	assert.True(t, runner.IsRetryable(syscallErr(syscall.ECONNREFUSED)))
+	assert.True(t, runner.IsRetryable(urlErr(io.ErrUnexpectedEOF)))
+	assert.False(t, runner.IsRetryable(urlErr(&net.DNSError{Err: "no such host", Name: "api.example.com", IsNotFound: true})))
+	assert.False(t, runner.IsRetryable(urlErr(x509.UnknownAuthorityError{})))
+	assert.False(t, runner.IsRetryable(urlErr(errors.New("unsupported protocol scheme"))))
+	assert.False(t, runner.IsRetryable(urlErr(cassette.ErrNoMatch)))
+}
+
+func TestRunner_RetryBackoffCancelled(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{status: http.StatusServiceUnavailable},
+		scriptedReply{text: "unreachable"},
+	)
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+	r := runner.Runner{Config: runner.RunConfig{RetryPolicy: runner.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Minute,
+	}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := r.Run(ctx, a, "hi")
+
+	// 等待重试时 ctx 结束：返回 ctx 的错误，并保留最后一次模型错误
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var apiErr *openai.Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, 1, server.requestCount())
+}
@@ -0,0 +1,214 @@
+package agentgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fullModelSettings 设置了 ModelSettings 的所有请求字段
+func fullModelSettings() agent.ModelSettings {
+	return agent.ModelSettings{
+		Temperature:       param.NewOpt(0.2),
+		TopP:              param.NewOpt(0.9),
+		MaxTokens:         param.NewOpt[int64](256),
+		FrequencyPenalty:  param.NewOpt(0.5),
+		PresencePenalty:   param.NewOpt(0.25),
+		ToolChoice:        agent.ToolChoiceRequired,
+		ParallelToolCalls: param.NewOpt(false),
+		Truncation:        param.NewOpt(agent.TruncationAuto),
+		Reasoning:         shared.ReasoningParam{Effort: shared.ReasoningEffortLow},
+		Metadata:          map[string]string{"team": "search"},
+		Store:             param.NewOpt(true),
+		ResponseInclude:   []responses.ResponseIncludable{responses.ResponseIncludableReasoningEncryptedContent},
+		ExtraQuery:        map[string]string{"api-version": "2025-01-01"},
+		ExtraHeaders:      map[string]string{"X-Trace": "abc"},
+	}
+}
+
+func TestRunner_ModelSettings_ChatCompletions(t *testing.T) {
+	server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+	a := agent.New("settings").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithModelSettings(fullModelSettings()).
+		WithTools([]tool.FunctionTool{newSearchTool()})
+
+	_, err := runner.Run(context.Background(), a, "hi")
+	require.NoError(t, err)
+
+	req := server.request(0)
+	assert.Equal(t, 0.2, req["temperature"])
+	assert.Equal(t, 0.9, req["top_p"])
+	assert.Equal(t, float64(256), req["max_tokens"])
+	assert.Equal(t, 0.5, req["frequency_penalty"])
+	assert.Equal(t, 0.25, req["presence_penalty"])
+	assert.Equal(t, "required", req["tool_choice"])
+	assert.Equal(t, false, req["parallel_tool_calls"])
+	assert.Equal(t, "low", req["reasoning_effort"])
+	assert.Equal(t, map[string]any{"team": "search"}, req["metadata"])
+	assert.Equal(t, true, req["store"])
+	// Chat Completions 没有 truncation 与 include 参数
+	assert.NotContains(t, req, "truncation")
+	assert.NotContains(t, req, "include")
+
+	assert.Equal(t, "abc", server.headers[0].Get("X-Trace"))
+	assert.Equal(t, "2025-01-01", server.queries[0].Get("api-version"))
+}
+
+func TestRunner_ModelSettings_Responses(t *testing.T) {
+	server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+	a := agent.New("settings").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithPrompt(agent.Prompt{ID: "pmpt_test"}).
+		WithModelSettings(fullModelSettings()).
+		WithTools([]tool.FunctionTool{newSearchTool()})
+
+	_, err := runner.Run(context.Background(), a, "hi")
+	require.NoError(t, err)
+
+	req := server.request(0)
+	assert.Equal(t, 0.2, req["temperature"])
+	assert.Equal(t, 0.9, req["top_p"])
+	assert.Equal(t, float64(256), req["max_output_tokens"])
+	assert.Equal(t, "required", req["tool_choice"])
+	assert.Equal(t, false, req["parallel_tool_calls"])
+	assert.Equal(t, "auto", req["truncation"])
+	assert.Equal(t, map[string]any{"effort": "low"}, req["reasoning"])
+	assert.Equal(t, map[string]any{"team": "search"}, req["metadata"])
+	assert.Equal(t, true, req["store"])
+	assert.Equal(t, []any{"reasoning.encrypted_content"}, req["include"])
+	// Responses API 不支持惩罚参数
+	assert.NotContains(t, req, "frequency_penalty")
+	assert.NotContains(t, req, "presence_penalty")
+
+	assert.Equal(t, "abc", server.headers[0].Get("X-Trace"))
+	assert.Equal(t, "2025-01-01", server.queries[0].Get("api-version"))
+}
+
+func TestRunner_ModelSettings_NamedToolChoice(t *testing.T) {
+	for _, p := range []struct {
+		name   string
+		prompt agent.Prompter
+		want   map[string]any
+	}{
+		{name: "ChatCompletions", want: map[string]any{"type": "function", "function": map[string]any{"name": "search"}}},
+		{name: "Responses", prompt: agent.Prompt{ID: "pmpt_test"}, want: map[string]any{"type": "function", "name": "search"}},
+	} {
+		t.Run(p.name, func(t *testing.T) {
+			server := newFakeLLMServer(t, scriptedReply{text: "ok"}, scriptedReply{text: "ok"})
+			a := agent.New("settings").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithModelSettings(agent.ModelSettings{ToolChoice: agent.ToolChoiceString("search")})
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+
+			// 没有工具时不发送工具相关参数
+			_, err := runner.Run(context.Background(), a, "hi")
+			require.NoError(t, err)
+			assert.NotContains(t, server.request(0), "tool_choice")
+
+			a.WithTools([]tool.FunctionTool{newSearchTool()})
+			_, err = runner.Run(context.Background(), a, "hi")
+			require.NoError(t, err)
+			assert.Equal(t, p.want, server.request(1)["tool_choice"])
+		})
+	}
+}
+
+func TestRunner_ModelSettings_RunConfigOverride(t *testing.T) {
+	server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+	a := agent.New("settings").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithModelSettings(agent.ModelSettings{
+			Temperature:  param.NewOpt(0.2),
+			Metadata:     map[string]string{"team": "search"},
+			ExtraHeaders: map[string]string{"X-Trace": "agent"},
+		})
+
+	r := runner.Runner{Config: runner.RunConfig{ModelSettings: agent.ModelSettings{
+		Temperature:  param.NewOpt(0.7),
+		ExtraHeaders: map[string]string{"X-Trace": "run"},
+	}}}
+	_, err := r.Run(context.Background(), a, "hi")
+	require.NoError(t, err)
+
+	req := server.request(0)
+	assert.Equal(t, 0.7, req["temperature"])
+	assert.Equal(t, map[string]any{"team": "search"}, req["metadata"])
+	assert.Equal(t, "run", server.headers[0].Get("X-Trace"))
+}
+
+func TestRunner_ModelSettings_Customize(t *testing.T) {
+	t.Run("ChatCompletions", func(t *testing.T) {
+		server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+		a := agent.New("settings").
+			WithModel("test-model").
+			WithClient(server.client()).
+			WithModelSettings(agent.ModelSettings{
+				ExtraHeaders: map[string]string{"X-Trace": "abc"},
+				CustomizeChatCompletionsRequest: func(_ context.Context, params *openai.ChatCompletionNewParams, opts []option.RequestOption) (*openai.ChatCompletionNewParams, []option.RequestOption, error) {
+					params.Seed = param.NewOpt[int64](42)
+					return params, append(opts, option.WithHeader("X-Custom", "chat")), nil
+				},
+			})
+
+		_, err := runner.Run(context.Background(), a, "hi")
+		require.NoError(t, err)
+		assert.Equal(t, float64(42), server.request(0)["seed"])
+		assert.Equal(t, "chat", server.headers[0].Get("X-Custom"))
+		assert.Equal(t, "abc", server.headers[0].Get("X-Trace"))
+	})
+
+	t.Run("Responses", func(t *testing.T) {
+		server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+		a := agent.New("settings").
+			WithModel("test-model").
+			WithClient(server.client()).
+			WithPrompt(agent.Prompt{ID: "pmpt_test"}).
+			WithModelSettings(agent.ModelSettings{
+				CustomizeResponsesRequest: func(_ context.Context, params *responses.ResponseNewParams, opts []option.RequestOption) (*responses.ResponseNewParams, []option.RequestOption, error) {
+					params.User = param.NewOpt("user-1")
+					return params, append(opts, option.WithQuery("custom", "1")), nil
+				},
+			})
+
+		_, err := runner.Run(context.Background(), a, "hi")
+		require.NoError(t, err)
+		assert.Equal(t, "user-1", server.request(0)["user"])
+		assert.Equal(t, "1", server.queries[0].Get("custom"))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+		hookErr := errors.New("refused")
+		a := agent.New("settings").
+			WithModel("test-model").
+			WithClient(server.client()).
+			WithModelSettings(agent.ModelSettings{
+				CustomizeChatCompletionsRequest: func(context.Context, *openai.ChatCompletionNewParams, []option.RequestOption) (*openai.ChatCompletionNewParams, []option.RequestOption, error) {
+					return nil, nil, hookErr
+				},
+			})
+
+		_, err := runner.Run(context.Background(), a, "hi")
+		assert.ErrorIs(t, err, hookErr)
+		assert.Equal(t, 0, server.requestCount())
+	})
+}
@@ -0,0 +1,186 @@
+package agentgo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSSE 以流式格式返回脚本回复，文本与参数各拆成两段增量
+func writeSSE(w http.ResponseWriter, path string, reply scriptedReply) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	send := func(event string, data any) {
+		raw, _ := json.Marshal(data)
+		if event != "" {
+			fmt.Fprintf(w, "event: %s\n", event)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", raw)
+	}
+
+	if strings.HasSuffix(path, "/chat/completions") {
+		chunk := func(delta map[string]any, finishReason any) map[string]any {
+			return map[string]any{
+				"id":      "chatcmpl-test",
+				"object":  "chat.completion.chunk",
+				"created": 0,
+				"model":   "test-model",
+				"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+			}
+		}
+		for _, part := range splitHalf(reply.text) {
+			send("", chunk(map[string]any{"role": "assistant", "content": part}, nil))
+		}
+		for i, tc := range reply.toolCalls {
+			args := splitHalf(tc.arguments)
+			send("", chunk(map[string]any{"tool_calls": []map[string]any{{
+				"index": i, "id": tc.id, "type": "function",
+				"function": map[string]any{"name": tc.name, "arguments": args[0]},
+			}}}, nil))
+			send("", chunk(map[string]any{"tool_calls": []map[string]any{{
+				"index": i, "function": map[string]any{"arguments": args[1]},
+			}}}, nil))
+		}
+		finishReason := "stop"
+		if len(reply.toolCalls) > 0 {
+			finishReason = "tool_calls"
+		}
+		send("", chunk(map[string]any{}, finishReason))
+		usage := chatCompletionBody(reply)
+		usage["object"] = "chat.completion.chunk"
+		usage["choices"] = []any{}
+		send("", usage)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+
+	seq := 0
+	event := func(typ string, fields map[string]any) {
+		fields["type"] = typ
+		fields["sequence_number"] = seq
+		seq++
+		send(typ, fields)
+	}
+	for _, part := range splitHalf(reply.text) {
+		event("response.output_text.delta", map[string]any{
+			"item_id": "msg_test", "output_index": 0, "content_index": 0, "delta": part, "logprobs": []any{},
+		})
+	}
+	for i, tc := range reply.toolCalls {
+		event("response.output_item.added", map[string]any{
+			"output_index": i,
+			"item": map[string]any{
+				"id": "fc_" + tc.id, "type": "function_call", "call_id": tc.id,
+				"name": tc.name, "arguments": "", "status": "in_progress",
+			},
+		})
+		for _, part := range splitHalf(tc.arguments) {
+			event("response.function_call_arguments.delta", map[string]any{
+				"item_id": "fc_" + tc.id, "output_index": i, "delta": part,
+			})
+		}
+	}
+	event("response.completed", map[string]any{"response": responsesBody(reply)})
+}
+
+// splitHalf 将非空字符串拆为两段
+func splitHalf(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s[:len(s)/2], s[len(s)/2:]}
+}
+
+func TestRunner_RunStreamed(t *testing.T) {
+	paths := []struct {
+		name   string
+		prompt agent.Prompter
+	}{
+		{name: "ChatCompletions"},
+		{name: "Responses", prompt: agent.Prompt{ID: "pmpt_test"}},
+	}
+
+	for _, p := range paths {
+		t.Run(p.name, func(t *testing.T) {
+			server := newFakeLLMServer(t,
+				scriptedReply{toolCalls: []scriptedToolCall{
+					{id: "call_1", name: "calculator", arguments: `{"operation":"add","a":2,"b":3}`},
+				}},
+				scriptedReply{text: "2 + 3 = 5"},
+			)
+
+			a := agent.New("calc").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+
+			stream := runner.RunStreamed(context.Background(), a, "What is 2 + 3?")
+
+			var (
+				kinds []string
+				text  string
+				args  string
+				final *runner.RunResult
+			)
+			for ev := range stream.Events() {
+				switch e := ev.(type) {
+				case runner.AgentSwitchedEvent:
+					kinds = append(kinds, "agent")
+					assert.Nil(t, e.Previous)
+					assert.Equal(t, a, e.Agent)
+				case runner.ToolCallStartedEvent:
+					kinds = append(kinds, "tool_started")
+					assert.Equal(t, "call_1", e.CallID)
+					assert.Equal(t, "calculator", e.Name)
+				case runner.ToolCallArgumentsDeltaEvent:
+					assert.Equal(t, "call_1", e.CallID)
+					args += e.Delta
+				case runner.ToolOutputEvent:
+					kinds = append(kinds, "tool_output")
+					assert.Equal(t, "call_1", e.CallID)
+					assert.Equal(t, "5", e.Output)
+				case runner.TextDeltaEvent:
+					text += e.Delta
+				case runner.TurnFinishedEvent:
+					kinds = append(kinds, fmt.Sprintf("turn_%d", e.Turn))
+				case runner.FinalResultEvent:
+					kinds = append(kinds, "final")
+					final = e.Result
+				}
+			}
+
+			assert.Equal(t, []string{"agent", "tool_started", "tool_output", "turn_1", "turn_2", "final"}, kinds)
+			assert.Equal(t, `{"operation":"add","a":2,"b":3}`, args)
+			assert.Equal(t, "2 + 3 = 5", text)
+
+			result, err := stream.Wait()
+			require.NoError(t, err)
+			assert.Same(t, final, result)
+			assert.Equal(t, "2 + 3 = 5", finalText(t, result.FinalOutput))
+			assert.Len(t, result.RawResponses, 2)
+			assert.Equal(t, true, server.request(0)["stream"])
+		})
+	}
+}
+
+func TestRunner_RunStreamedError(t *testing.T) {
+	server := newFakeLLMServer(t)
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+
+	// 调用方可以不读取事件，直接等待结果
+	result, err := runner.RunStreamed(context.Background(), a, "hi").Wait()
+	assert.Error(t, err)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,110 @@
+package agentgo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/pattern"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// turnUsage 是假服务每次回复报告的用量
+func turnUsage(requests uint64) runner.Usage {
+	var u runner.Usage
+	for range requests {
+		u.Add(runner.Usage{
+			Requests:     1,
+			InputTokens:  10,
+			OutputTokens: 5,
+			TotalTokens:  15,
+		})
+		u.InputTokensDetails.CachedTokens += 4
+		u.OutputTokensDetails.ReasoningTokens += 2
+	}
+	return u
+}
+
+func TestUsage_Add(t *testing.T) {
+	u := turnUsage(1)
+	u.Add(turnUsage(2))
+	assert.Equal(t, uint64(3), u.Requests)
+	assert.Equal(t, uint64(30), u.InputTokens)
+	assert.Equal(t, int64(12), u.InputTokensDetails.CachedTokens)
+	assert.Equal(t, uint64(15), u.OutputTokens)
+	assert.Equal(t, int64(6), u.OutputTokensDetails.ReasoningTokens)
+	assert.Equal(t, uint64(45), u.TotalTokens)
+}
+
+func TestRunner_Usage(t *testing.T) {
+	for _, p := range []struct {
+		name   string
+		prompt agent.Prompter
+	}{
+		{name: "ChatCompletions"},
+		{name: "Responses", prompt: agent.Prompt{ID: "pmpt_test"}},
+	} {
+		t.Run(p.name, func(t *testing.T) {
+			server := newFakeLLMServer(t,
+				scriptedReply{toolCalls: []scriptedToolCall{{id: "call_1", name: "calculator", arguments: `{"expression":"1+1"}`}}},
+				scriptedReply{text: "2"},
+			)
+			a := agent.New("math").
+				WithModel("test-model").
+				WithClient(server.client()).
+				WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+			if p.prompt != nil {
+				a.WithPrompt(p.prompt)
+			}
+
+			result, err := runner.Run(context.Background(), a, "1+1")
+			require.NoError(t, err)
+			assert.Equal(t, turnUsage(2), result.Usage.Usage)
+			assert.Equal(t, map[string]runner.Usage{"math": turnUsage(2)}, result.Usage.ByAgent)
+			assert.Equal(t, map[string]runner.Usage{"test-model": turnUsage(2)}, result.Usage.ByModel)
+		})
+	}
+}
+
+func TestRunner_UsageAcrossHandoffsAndNestedRuns(t *testing.T) {
+	nestedServer := newFakeLLMServer(t, scriptedReply{text: "background facts"})
+	researcher := agent.New("researcher").
+		WithModel("research-model").
+		WithClient(nestedServer.client())
+
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{{id: "call_transfer", name: "transfer_to_writer", arguments: `{}`}}},
+		scriptedReply{toolCalls: []scriptedToolCall{{id: "call_research", name: "call_agent_researcher", arguments: `{"input":"facts"}`}}},
+		scriptedReply{text: "article"},
+	)
+	writer := agent.New("writer").
+		WithModel("writer-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{pattern.WrapAgentAsTool(researcher, 0)})
+	triage := agent.New("triage").
+		WithModel("triage-model").
+		WithClient(server.client()).
+		AddHandoff(agent.HandoffTo(writer))
+
+	result, err := runner.Run(context.Background(), triage, "write an article")
+	require.NoError(t, err)
+	assert.Equal(t, "article", result.FinalOutput)
+
+	// 嵌套运行的用量计入外层运行，但 RawResponses 只包含外层的响应
+	assert.Len(t, result.RawResponses, 3)
+	assert.Equal(t, turnUsage(4), result.Usage.Usage)
+	assert.Equal(t, map[string]runner.Usage{
+		"triage":     turnUsage(1),
+		"writer":     turnUsage(2),
+		"researcher": turnUsage(1),
+	}, result.Usage.ByAgent)
+	assert.Equal(t, map[string]runner.Usage{
+		"triage-model":   turnUsage(1),
+		"writer-model":   turnUsage(2),
+		"research-model": turnUsage(1),
+	}, result.Usage.ByModel)
+}
@@ -0,0 +1,81 @@
+package agentgo
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/memory/sessiontest"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/require"
+)
+
+// uniqueSessionID 为共享存储的会话生成不重复的 ID
+func uniqueSessionID() func() string {
+	var n atomic.Int64
+	return func() string { return fmt.Sprintf("conformance-%d", n.Add(1)) }
+}
+
+func TestSessionConformance_SQLite(t *testing.T) {
+	sessiontest.RunConformance(t, func(t *testing.T) memory.Session {
+		return newTestSQLiteSession(t, "conformance")
+	})
+}
+
+func TestSessionConformance_SQLiteFile(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+	nextID := uniqueSessionID()
+	sessiontest.RunConformance(t, func(t *testing.T) memory.Session {
+		session, err := memory.NewSQLiteSession(context.Background(), memory.SQLiteSessionConfig{SessionID: nextID(), DBPath: dbPath})
+		require.NoError(t, err)
+		t.Cleanup(func() { session.Close() })
+		return session
+	})
+}
+
+func TestSessionConformance_SessionStore(t *testing.T) {
+	store, err := memory.NewSessionStore(context.Background(), memory.SessionStoreConfig{})
+	require.NoError(t, err)
+	defer store.Close()
+	nextID := uniqueSessionID()
+	sessiontest.RunConformance(t, func(t *testing.T) memory.Session {
+		session, err := store.Session(context.Background(), nextID())
+		require.NoError(t, err)
+		return session
+	})
+}
+
+func TestSessionConformance_Redis(t *testing.T) {
+	_, client := newMiniRedis(t)
+	nextID := uniqueSessionID()
+	sessiontest.RunConformance(t, func(t *testing.T) memory.Session {
+		session, err := memory.NewRedisSession(memory.RedisSessionConfig{SessionID: nextID(), Client: client})
+		require.NoError(t, err)
+		return session
+	})
+}
+
+func TestSessionConformance_Postgres(t *testing.T) {
+	_, config := newPostgresTestConfig(t)
+	nextID := uniqueSessionID()
+	sessiontest.RunConformance(t, func(t *testing.T) memory.Session {
+		return newPostgresTestSession(t, config, nextID())
+	})
+}
+
+func TestSessionConformance_Summarizing(t *testing.T) {
+	// 未配置阈值时摘要会话只转发给内部会话
+	sessiontest.RunConformance(t, func(t *testing.T) memory.Session {
+		session, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "conformance"), memory.SummarizingSessionConfig{
+			Summarizer: memory.SummarizerFunc(func(context.Context, []responses.ResponseInputItemUnionParam) (string, error) {
+				return "", fmt.Errorf("unexpected summarization")
+			}),
+		})
+		require.NoError(t, err)
+		return session
+	})
+}
@@ -0,0 +1,176 @@
+package agentgo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMiniRedis 启动进程内的 Redis 替身并返回连接它的客户端
+func newMiniRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return mr, client
+}
+
+func TestRedisSession_Basic(t *testing.T) {
+	ctx := context.Background()
+	_, client := newMiniRedis(t)
+	session, err := memory.NewRedisSession(memory.RedisSessionConfig{SessionID: "basic", Client: client})
+	require.NoError(t, err)
+	assert.Equal(t, memory.DefaultRedisKeyPrefix+"basic", session.Key())
+
+	item, err := session.PopItem(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+
+	require.NoError(t, session.AddItems(ctx, sampleHistory()))
+	items, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, itemLabels(sampleHistory()), itemLabels(items))
+
+	items, err = session.GetItems(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"assistant:answer", "user:second"}, itemLabels(items))
+
+	item, err = session.PopItem(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, item)
+	assert.Equal(t, []string{"user:second"}, itemLabels([]responses.ResponseInputItemUnionParam{*item}))
+
+	require.NoError(t, session.ClearSession(ctx))
+	items, err = session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestRedisSession_TTL(t *testing.T) {
+	ctx := context.Background()
+	mr, client := newMiniRedis(t)
+	session, err := memory.NewRedisSession(memory.RedisSessionConfig{
+		SessionID: "ephemeral",
+		Client:    client,
+		KeyPrefix: "chat:",
+		TTL:       time.Minute,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("hi")}))
+	assert.Equal(t, time.Minute, mr.TTL("chat:ephemeral"))
+
+	// 写入会刷新过期时间，读取不会
+	mr.FastForward(40 * time.Second)
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{assistantItem("hello")}))
+	assert.Equal(t, time.Minute, mr.TTL("chat:ephemeral"))
+	mr.FastForward(40 * time.Second)
+	items, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, 20*time.Second, mr.TTL("chat:ephemeral"))
+
+	mr.FastForward(30 * time.Second)
+	items, err = session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestRedisSession_MaxItems(t *testing.T) {
+	ctx := context.Background()
+	_, client := newMiniRedis(t)
+	session, err := memory.NewRedisSession(memory.RedisSessionConfig{SessionID: "short", Client: client, MaxItems: 3})
+	require.NoError(t, err)
+
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[:2]))
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[6:]))
+	items, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:first", "assistant:answer", "user:second"}, itemLabels(items))
+}
+
+func TestRedisSession_MaxItemsKeepsToolPairs(t *testing.T) {
+	ctx := context.Background()
+	_, client := newMiniRedis(t)
+
+	// 截断点落在两个工具调用之间时，继续丢弃失去调用的输出
+	session, err := memory.NewRedisSession(memory.RedisSessionConfig{SessionID: "tools", Client: client, MaxItems: 5})
+	require.NoError(t, err)
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[1:]))
+	items, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"assistant:answer", "user:second"}, itemLabels(items))
+
+	// 截断点在完整的调用之前时只保留最近的 MaxItems 项
+	session, err = memory.NewRedisSession(memory.RedisSessionConfig{SessionID: "pairs", Client: client, MaxItems: 6})
+	require.NoError(t, err)
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[:4]))
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[4:]))
+	items, err = session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"call:call_a", "call:call_b", "output:call_a=result a", "output:call_b=result b",
+		"assistant:answer", "user:second",
+	}, itemLabels(items))
+
+	// ReplaceItems 同样处理
+	require.NoError(t, session.ReplaceItems(ctx, sampleHistory()[1:]))
+	items, err = session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"call:call_a", "call:call_b", "output:call_a=result a", "output:call_b=result b",
+		"assistant:answer", "user:second",
+	}, itemLabels(items))
+}
+
+func TestRedisSession_ConcurrentPop(t *testing.T) {
+	ctx := context.Background()
+	_, client := newMiniRedis(t)
+	session, err := memory.NewRedisSession(memory.RedisSessionConfig{SessionID: "pop", Client: client})
+	require.NoError(t, err)
+
+	const total = 50
+	for i := range total {
+		require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem(fmt.Sprint(i))}))
+	}
+
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, err := session.PopItem(ctx)
+				if !assert.NoError(t, err) || item == nil {
+					return
+				}
+				label := itemLabels([]responses.ResponseInputItemUnionParam{*item})[0]
+				mu.Lock()
+				assert.False(t, seen[label], "popped twice: %s", label)
+				seen[label] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, seen, total)
+}
+
+func TestRedisSession_Config(t *testing.T) {
+	_, err := memory.NewRedisSession(memory.RedisSessionConfig{Client: redis.NewClient(&redis.Options{})})
+	assert.ErrorIs(t, err, memory.ErrInvalidSessionID)
+	_, err = memory.NewRedisSession(memory.RedisSessionConfig{SessionID: "s"})
+	assert.ErrorIs(t, err, memory.ErrDatabaseOpen)
+}
@@ -0,0 +1,216 @@
+package agentgo
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sessionIDs(sessions []memory.SessionInfo) []string {
+	var ids []string
+	for _, s := range sessions {
+		ids = append(ids, s.SessionID)
+	}
+	return ids
+}
+
+func TestSessionStore_ListForkRename(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewSessionStore(ctx, memory.SessionStoreConfig{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	a, err := store.Session(ctx, "a")
+	require.NoError(t, err)
+	b, err := store.Session(ctx, "b")
+	require.NoError(t, err)
+	require.NoError(t, a.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("a1"), assistantItem("a2"), userItem("a3")}))
+	require.NoError(t, b.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("b1")}))
+
+	// 按最近更新时间倒序
+	sessions, err := store.ListSessions(ctx, memory.ListSessionsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, sessionIDs(sessions))
+	assert.Equal(t, 1, sessions[0].ItemCount)
+	assert.Equal(t, 3, sessions[1].ItemCount)
+	assert.False(t, sessions[1].CreatedAt.IsZero())
+
+	sessions, err = store.ListSessions(ctx, memory.ListSessionsOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, sessionIDs(sessions))
+
+	// 复制前两项及元数据
+	require.NoError(t, store.SetMetadata(ctx, "a", "title", "Trip planning"))
+	require.NoError(t, store.ForkSession(ctx, "a", "c", 2))
+	c, err := store.Session(ctx, "c")
+	require.NoError(t, err)
+	items, err := c.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:a1", "assistant:a2"}, itemLabels(items))
+	metadata, err := store.Metadata(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"title": "Trip planning"}, metadata)
+
+	// 复制不影响原会话
+	require.NoError(t, c.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("c3")}))
+	items, err = a.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+
+	assert.ErrorIs(t, store.ForkSession(ctx, "a", "b", 0), memory.ErrSessionExists)
+	assert.ErrorIs(t, store.ForkSession(ctx, "missing", "d", 0), memory.ErrSessionNotFound)
+
+	require.NoError(t, store.RenameSession(ctx, "c", "d"))
+	d, err := store.Session(ctx, "d")
+	require.NoError(t, err)
+	items, err = d.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:a1", "assistant:a2", "user:c3"}, itemLabels(items))
+	metadata, err = store.Metadata(ctx, "d")
+	require.NoError(t, err)
+	assert.Equal(t, "Trip planning", metadata["title"])
+	assert.ErrorIs(t, store.RenameSession(ctx, "d", "a"), memory.ErrSessionExists)
+
+	sessions, err = store.ListSessions(ctx, memory.ListSessionsOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b", "d"}, sessionIDs(sessions))
+
+	require.NoError(t, store.DeleteSession(ctx, "d"))
+	metadata, err = store.Metadata(ctx, "d")
+	require.NoError(t, err)
+	assert.Empty(t, metadata)
+}
+
+func TestSessionStore_Metadata(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewSessionStore(ctx, memory.SessionStoreConfig{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.Session(ctx, "s")
+	require.NoError(t, err)
+	require.NoError(t, store.SetMetadata(ctx, "s", "user", "alice"))
+	require.NoError(t, store.SetMetadata(ctx, "s", "user", "bob"))
+	require.NoError(t, store.SetMetadata(ctx, "s", "lang", "go"))
+	metadata, err := store.Metadata(ctx, "s")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"user": "bob", "lang": "go"}, metadata)
+
+	require.NoError(t, store.DeleteMetadata(ctx, "s", "user"))
+	metadata, err = store.Metadata(ctx, "s")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"lang": "go"}, metadata)
+
+	assert.ErrorIs(t, store.SetMetadata(ctx, "missing", "k", "v"), memory.ErrSessionNotFound)
+}
+
+func TestSessionStore_ExpireIdleSessions(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+	store, err := memory.NewSessionStore(ctx, memory.SessionStoreConfig{DBPath: dbPath})
+	require.NoError(t, err)
+	defer store.Close()
+
+	for _, id := range []string{"old", "older", "fresh"} {
+		s, err := store.Session(ctx, id)
+		require.NoError(t, err)
+		require.NoError(t, s.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem(id)}))
+	}
+	require.NoError(t, store.SetMetadata(ctx, "old", "k", "v"))
+
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	defer db.Close()
+	backdate := func(id string, age time.Duration) {
+		_, err := db.Exec(`UPDATE agent_sessions SET updated_at = ? WHERE session_id = ?`, time.Now().Add(-age), id)
+		require.NoError(t, err)
+	}
+	backdate("old", 2*time.Hour)
+	backdate("older", 3*time.Hour)
+
+	deleted, err := store.DeleteIdleSessions(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	sessions, err := store.ListSessions(ctx, memory.ListSessionsOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fresh"}, sessionIDs(sessions))
+
+	// 过期会话的对话项与元数据一并删除
+	var remaining int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM agent_messages WHERE session_id <> 'fresh'`).Scan(&remaining))
+	assert.Zero(t, remaining)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM agent_session_metadata`).Scan(&remaining))
+	assert.Zero(t, remaining)
+
+	// 后台清理
+	stop := store.StartSweeper(ctx, memory.SweeperOptions{Interval: 10 * time.Millisecond, TTL: time.Hour})
+	defer stop()
+	backdate("fresh", 2*time.Hour)
+	assert.Eventually(t, func() bool {
+		sessions, err := store.ListSessions(ctx, memory.ListSessionsOptions{})
+		return err == nil && len(sessions) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSessionStore_SweeperReportsErrors(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewSessionStore(ctx, memory.SessionStoreConfig{})
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	// 数据库已关闭，每次清理都失败并交给 OnError
+	errs := make(chan error, 1)
+	stop := store.StartSweeper(ctx, memory.SweeperOptions{
+		Interval: 10 * time.Millisecond,
+		TTL:      time.Hour,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	defer stop()
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, memory.ErrTransactionFailed)
+	case <-time.After(time.Second):
+		t.Fatal("sweeper did not report the failed sweep")
+	}
+}
+
+func TestSessionStore_ClearedSessionIsStillListed(t *testing.T) {
+	ctx := context.Background()
+	store, err := memory.NewSessionStore(ctx, memory.SessionStoreConfig{})
+	require.NoError(t, err)
+	defer store.Close()
+
+	s, err := store.Session(ctx, "s")
+	require.NoError(t, err)
+	require.NoError(t, s.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("one")}))
+	require.NoError(t, s.ClearSession(ctx))
+
+	sessions, err := store.ListSessions(ctx, memory.ListSessionsOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// 清空后继续写入会重新登记会话
+	require.NoError(t, s.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("two")}))
+	sessions, err = store.ListSessions(ctx, memory.ListSessionsOptions{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, 1, sessions[0].ItemCount)
+
+	// 由 store 打开的会话关闭后不影响 store
+	require.NoError(t, s.Close())
+	_, err = store.ListSessions(ctx, memory.ListSessionsOptions{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,230 @@
+package agentgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/memory"
+	"github.com/chuanbosi666/agent_go/pkg/model"
+	"github.com/chuanbosi666/agent_go/pkg/pattern"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/testing/fakemodel"
+
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLiteSession(t *testing.T, id string) *memory.SQLiteSession {
+	t.Helper()
+	session, err := memory.NewSQLiteSession(context.Background(), memory.SQLiteSessionConfig{SessionID: id})
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestSummarizingSession_AgentSummarizerKeepsToolPairs(t *testing.T) {
+	ctx := context.Background()
+	m := fakemodel.New(fakemodel.Text("The user looked things up.").Expecting(func(req model.Request) error {
+		transcript := req.Input[len(req.Input)-1].OfMessage.Content.OfString.Value
+		if !strings.Contains(transcript, "user: first") || strings.Contains(transcript, "lookup") {
+			return fmt.Errorf("unexpected transcript %q", transcript)
+		}
+		return nil
+	}))
+	summarizer := pattern.AgentSummarizer{
+		Agent:  agent.New("summarizer").WithInstructions(pattern.DefaultSummarizerInstructions),
+		Runner: runner.Runner{Config: runner.RunConfig{ModelProvider: m}},
+	}
+	session, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "summarize"), memory.SummarizingSessionConfig{
+		Summarizer: summarizer,
+		MaxItems:   6,
+		KeepItems:  3,
+	})
+	require.NoError(t, err)
+
+	// 未超过阈值时不压缩
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[1:6]))
+	assert.Empty(t, m.Requests())
+
+	require.NoError(t, session.AddItems(ctx, sampleHistory()[6:]))
+	require.Len(t, m.Requests(), 1)
+
+	// 保留最近 3 项时会拆开 call_b 与其结果，因此两组调用都被保留
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"system:" + memory.DefaultSummaryPrefix + "The user looked things up.",
+		"call:call_a", "call:call_b", "output:call_a=result a", "output:call_b=result b",
+		"assistant:answer", "user:second",
+	}, itemLabels(stored))
+}
+
+func TestSummarizingSession_Compact(t *testing.T) {
+	ctx := context.Background()
+	var summarized [][]string
+	summarizer := memory.SummarizerFunc(func(_ context.Context, items []responses.ResponseInputItemUnionParam) (string, error) {
+		summarized = append(summarized, itemLabels(items))
+		return fmt.Sprintf("summary %d", len(summarized)), nil
+	})
+	session, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "compact"), memory.SummarizingSessionConfig{
+		Summarizer: summarizer,
+		KeepItems:  1,
+	})
+	require.NoError(t, err)
+
+	// 没有阈值时只在显式调用 Compact 时压缩
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("a"), assistantItem("b"), userItem("c")}))
+	assert.Empty(t, summarized)
+
+	require.NoError(t, session.Compact(ctx))
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"system:" + memory.DefaultSummaryPrefix + "summary 1", "user:c"}, itemLabels(stored))
+
+	// 仅剩上一次的摘要可压缩时不再调用摘要器
+	require.NoError(t, session.Compact(ctx))
+	assert.Len(t, summarized, 1)
+
+	// 之前的摘要会并入新的摘要
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{assistantItem("d")}))
+	require.NoError(t, session.Compact(ctx))
+	require.Len(t, summarized, 2)
+	assert.Equal(t, []string{"system:" + memory.DefaultSummaryPrefix + "summary 1", "user:c"}, summarized[1])
+	stored, err = session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"system:" + memory.DefaultSummaryPrefix + "summary 2", "assistant:d"}, itemLabels(stored))
+}
+
+func TestSummarizingSession_MaxTokens(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	summarizer := memory.SummarizerFunc(func(context.Context, []responses.ResponseInputItemUnionParam) (string, error) {
+		calls++
+		return "short", nil
+	})
+	session, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "tokens"), memory.SummarizingSessionConfig{
+		Summarizer: summarizer,
+		MaxTokens:  3,
+		Counter:    perItem,
+		KeepItems:  2,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("a"), assistantItem("b"), userItem("c")}))
+	assert.Equal(t, 0, calls)
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{assistantItem("d")}))
+	assert.Equal(t, 1, calls)
+
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"system:" + memory.DefaultSummaryPrefix + "short", "user:c", "assistant:d"}, itemLabels(stored))
+}
+
+func TestSummarizingSession_Errors(t *testing.T) {
+	ctx := context.Background()
+	_, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "none"), memory.SummarizingSessionConfig{})
+	assert.Error(t, err)
+
+	// 被包装的会话必须支持整体替换
+	_, err = memory.NewSummarizingSession(struct{ memory.Session }{newTestSQLiteSession(t, "plain")}, memory.SummarizingSessionConfig{
+		Summarizer: memory.SummarizerFunc(func(context.Context, []responses.ResponseInputItemUnionParam) (string, error) {
+			return "", nil
+		}),
+	})
+	assert.Error(t, err)
+
+	boom := errors.New("boom")
+	var reported []error
+	session, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "failing"), memory.SummarizingSessionConfig{
+		Summarizer: memory.SummarizerFunc(func(context.Context, []responses.ResponseInputItemUnionParam) (string, error) {
+			return "", boom
+		}),
+		MaxItems: 1,
+		OnError:  func(err error) { reported = append(reported, err) },
+	})
+	require.NoError(t, err)
+
+	// 摘要失败不影响写入，错误交给 OnError
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("a"), assistantItem("b")}))
+	require.Len(t, reported, 1)
+	assert.ErrorIs(t, reported[0], boom)
+
+	// 摘要失败时对话项已保存且未被修改
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:a", "assistant:b"}, itemLabels(stored))
+}
+
+// failingReplacer 的整体替换总是失败，模拟写入压缩结果时出错
+type failingReplacer struct {
+	*memory.SQLiteSession
+}
+
+func (failingReplacer) ReplaceItems(context.Context, []responses.ResponseInputItemUnionParam) error {
+	return errors.New("disk full")
+}
+
+func TestSummarizingSession_ReplaceFailureKeepsHistory(t *testing.T) {
+	ctx := context.Background()
+	var reported []error
+	session, err := memory.NewSummarizingSession(failingReplacer{newTestSQLiteSession(t, "replace")}, memory.SummarizingSessionConfig{
+		Summarizer: memory.SummarizerFunc(func(context.Context, []responses.ResponseInputItemUnionParam) (string, error) {
+			return "summary", nil
+		}),
+		MaxItems: 2,
+		OnError:  func(err error) { reported = append(reported, err) },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, session.AddItems(ctx, []responses.ResponseInputItemUnionParam{userItem("a"), assistantItem("b"), userItem("c")}))
+	require.Len(t, reported, 1)
+	assert.ErrorContains(t, reported[0], "disk full")
+
+	// 压缩结果写入失败时原有历史完整保留
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:a", "assistant:b", "user:c"}, itemLabels(stored))
+
+	assert.ErrorContains(t, session.Compact(ctx), "disk full")
+}
+
+func TestRunner_SummarizingSession(t *testing.T) {
+	ctx := context.Background()
+	summaries := fakemodel.New(fakemodel.Text("Greetings were exchanged."))
+	session, err := memory.NewSummarizingSession(newTestSQLiteSession(t, "runner"), memory.SummarizingSessionConfig{
+		Summarizer: pattern.AgentSummarizer{
+			Agent:  agent.New("summarizer"),
+			Runner: runner.Runner{Config: runner.RunConfig{ModelProvider: summaries}},
+		},
+		MaxItems:  3,
+		KeepItems: 2,
+	})
+	require.NoError(t, err)
+
+	m := fakemodel.New(fakemodel.Text("hello"), fakemodel.Text("fine"))
+	r := runner.Runner{Config: runner.RunConfig{ModelProvider: m, Session: session}}
+	_, err = r.Run(ctx, agent.New("assistant"), "hi")
+	require.NoError(t, err)
+	_, err = r.Run(ctx, agent.New("assistant"), "how are you?")
+	require.NoError(t, err)
+
+	requests := m.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, []string{"user:hi", "assistant:hello", "user:how are you?"}, itemLabels(requests[1].Input))
+
+	// 第二轮写入后超过 3 项，最早的对话被摘要
+	require.Len(t, summaries.Requests(), 1)
+	summaryInput := summaries.Requests()[0].Input
+	assert.Contains(t, summaryInput[0].OfMessage.Content.OfString.Value, "user: hi\nassistant: hello\n")
+	stored, err := session.GetItems(ctx, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"system:" + memory.DefaultSummaryPrefix + "Greetings were exchanged.",
+		"user:how are you?", "assistant:fine",
+	}, itemLabels(stored))
+}
@@ -0,0 +1,143 @@
+package agentgo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type searchArgs struct {
+	Query string `json:"query" jsonschema:"description=Search query"`
+	Limit int    `json:"limit,omitempty" jsonschema:"minimum=1"`
+	Sort  string `json:"sort" jsonschema:"enum=relevance|date"`
+}
+
+type searchResult struct {
+	Query string   `json:"query"`
+	Hits  []string `json:"hits"`
+}
+
+func newSearchTool() tool.FunctionTool {
+	return tool.NewFunctionTool("search", "Search documents",
+		func(_ context.Context, args searchArgs) (searchResult, error) {
+			if args.Query == "fail" {
+				return searchResult{}, errors.New("backend unavailable")
+			}
+			hits := []string{args.Query + "-1", args.Query + "-2"}
+			if args.Limit > 0 && args.Limit < len(hits) {
+				hits = hits[:args.Limit]
+			}
+			return searchResult{Query: args.Query, Hits: hits}, nil
+		})
+}
+
+func TestNewFunctionTool_Schema(t *testing.T) {
+	search := newSearchTool()
+	assert.Equal(t, "search", search.Name)
+	assert.True(t, search.StrictJSONSchema.Value)
+
+	schema := search.ParamsJSONSchema
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, false, schema["additionalProperties"])
+	assert.Equal(t, []string{"limit", "query", "sort"}, schema["required"])
+	props := schema["properties"].(map[string]any)
+	assert.Equal(t, "Search query", props["query"].(map[string]any)["description"])
+	assert.Equal(t, []any{"relevance", "date"}, props["sort"].(map[string]any)["enum"])
+	// 可选参数在严格模式下允许为 null
+	assert.Contains(t, props["limit"], "anyOf")
+}
+
+func TestNewFunctionTool_Invoke(t *testing.T) {
+	ctx := context.Background()
+	search := newSearchTool()
+
+	out, err := search.OnInvokeTool(ctx, `{"query":"go","limit":1,"sort":"date"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query":"go","hits":["go-1"]}`, out.(string))
+
+	// 可选参数可以为 null 或省略
+	out, err = search.OnInvokeTool(ctx, `{"query":"go","limit":null,"sort":"date"}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"query":"go","hits":["go-1","go-2"]}`, out.(string))
+	_, err = search.OnInvokeTool(ctx, `{"query":"go","sort":"date"}`)
+	require.NoError(t, err)
+
+	_, err = search.OnInvokeTool(ctx, `{"sort":"date"}`)
+	assert.ErrorContains(t, err, `missing required argument "query"`)
+	_, err = search.OnInvokeTool(ctx, `{"query":"go","sort":"date","extra":1}`)
+	assert.ErrorContains(t, err, "unknown field")
+	_, err = search.OnInvokeTool(ctx, `{"query":`)
+	assert.ErrorContains(t, err, "invalid arguments")
+
+	// 函数返回的错误交由 FailureErrorFunction 处理
+	out, err = search.Invoke(ctx, `{"query":"fail","sort":"date"}`)
+	require.NoError(t, err)
+	assert.Contains(t, out, "backend unavailable")
+}
+
+func TestNewFunctionTool_StringResult(t *testing.T) {
+	echo := tool.NewFunctionTool("echo", "Echo the input",
+		func(_ context.Context, args struct {
+			Text string `json:"text"`
+		}) (string, error) {
+			return strings.ToUpper(args.Text), nil
+		})
+	out, err := echo.OnInvokeTool(context.Background(), `{"text":"hi"}`)
+	require.NoError(t, err)
+	assert.Equal(t, "HI", out)
+
+	assert.Panics(t, func() {
+		tool.NewFunctionTool("bad", "Args must be a struct", func(context.Context, string) (string, error) {
+			return "", nil
+		})
+	})
+}
+
+func TestNewTimeTool_InvalidArguments(t *testing.T) {
+	timeTool := tool.NewTimeTool()
+
+	out, err := timeTool.OnInvokeTool(context.Background(), "")
+	require.NoError(t, err)
+	assert.Contains(t, out, "Current time:")
+
+	out, err = timeTool.OnInvokeTool(context.Background(), `{"timezone":"UTC"}`)
+	require.NoError(t, err)
+	assert.Contains(t, out, "UTC")
+
+	_, err = timeTool.OnInvokeTool(context.Background(), `{"timezone":`)
+	assert.ErrorContains(t, err, "invalid arguments")
+	_, err = timeTool.OnInvokeTool(context.Background(), `{"timezone":"Mars/Base"}`)
+	assert.ErrorContains(t, err, "invalid timezone")
+}
+
+func TestRunner_TypedFunctionTool(t *testing.T) {
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{
+			{id: "call_1", name: "search", arguments: `{"query":"agents","limit":null,"sort":"relevance"}`},
+		}},
+		scriptedReply{text: "found 2"},
+	)
+	a := agent.New("searcher").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{newSearchTool()})
+
+	_, err := runner.Run(context.Background(), a, "search agents")
+	require.NoError(t, err)
+
+	tools := server.request(0)["tools"].([]any)
+	fn := tools[0].(map[string]any)["function"].(map[string]any)
+	assert.Equal(t, true, fn["strict"])
+
+	messages := server.request(1)["messages"].([]any)
+	toolMsg := messages[len(messages)-1].(map[string]any)
+	assert.JSONEq(t, `{"query":"agents","hits":["agents-1","agents-2"]}`, toolMsg["content"].(string))
+}
@@ -0,0 +1,354 @@
+package agentgo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chuanbosi666/agent_go/pkg/agent"
+	"github.com/chuanbosi666/agent_go/pkg/pattern"
+	"github.com/chuanbosi666/agent_go/pkg/runner"
+	"github.com/chuanbosi666/agent_go/pkg/tool"
+	"github.com/chuanbosi666/agent_go/pkg/tracing"
+	"github.com/chuanbosi666/agent_go/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// memoryProcessor 在内存中收集结束的 trace 与 span
+type memoryProcessor struct {
+	mu      sync.Mutex
+	started int
+	traces  []*tracing.Trace
+	spans   []*tracing.Span
+}
+
+func (p *memoryProcessor) OnTraceStart(*tracing.Trace) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started++
+}
+func (p *memoryProcessor) OnTraceEnd(t *tracing.Trace) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.traces = append(p.traces, t)
+}
+func (p *memoryProcessor) OnSpanStart(*tracing.Span) {}
+func (p *memoryProcessor) OnSpanEnd(s *tracing.Span) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans = append(p.spans, s)
+}
+func (p *memoryProcessor) ForceFlush(context.Context) error { return nil }
+func (p *memoryProcessor) Shutdown(context.Context) error   { return nil }
+
+// spanLabel 以 "类型:名称" 描述 span
+func spanLabel(s *tracing.Span) string {
+	switch d := s.Data.(type) {
+	case *tracing.AgentSpanData:
+		return "agent:" + d.Name
+	case *tracing.GenerationSpanData:
+		return "generation:" + d.Model
+	case *tracing.FunctionSpanData:
+		return "function:" + d.Name
+	case *tracing.GuardrailSpanData:
+		return "guardrail:" + d.Name
+	case *tracing.HandoffSpanData:
+		return "handoff:" + d.FromAgent + "->" + d.ToAgent
+	case *tracing.MCPListToolsSpanData:
+		return "mcp_tools:" + d.Server
+	}
+	return s.Data.Type()
+}
+
+// spanTree 返回 父 span 标签 -> 子 span 标签 的映射，根 span 的父标签为空
+func (p *memoryProcessor) spanTree() map[string][]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	labels := map[string]string{}
+	for _, s := range p.spans {
+		labels[s.ID] = spanLabel(s)
+	}
+	tree := map[string][]string{}
+	for _, s := range p.spans {
+		parent := labels[s.ParentID]
+		tree[parent] = append(tree[parent], spanLabel(s))
+	}
+	return tree
+}
+
+func TestRunner_Tracing(t *testing.T) {
+	nestedServer := newFakeLLMServer(t, scriptedReply{text: "background facts"})
+	researcher := agent.New("researcher").
+		WithModel("research-model").
+		WithClient(nestedServer.client())
+
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{{id: "call_transfer", name: "transfer_to_writer", arguments: `{}`}}},
+		scriptedReply{toolCalls: []scriptedToolCall{{id: "call_research", name: "call_agent_researcher", arguments: `{"input":"facts"}`}}},
+		scriptedReply{text: "article"},
+	)
+	writer := agent.New("writer").
+		WithModel("writer-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{pattern.WrapAgentAsTool(researcher, 0)})
+	triage := agent.New("triage").
+		WithModel("triage-model").
+		WithClient(server.client()).
+		AddHandoff(agent.HandoffTo(writer)).
+		WithInputGuardrails([]agent.InputGuardrail{agent.NewInputGuardrail("check_input",
+			func(context.Context, types.AgentLike, types.Input) (agent.GuardrailFunctionOutput, error) {
+				return agent.GuardrailFunctionOutput{}, nil
+			})})
+
+	processor := &memoryProcessor{}
+	r := runner.Runner{Config: runner.RunConfig{
+		WorkflowName:  "writing flow",
+		GroupID:       "thread_1",
+		TraceMetadata: map[string]any{"team": "docs"},
+		TraceProvider: tracing.NewProvider(processor),
+	}}
+	_, err := r.Run(context.Background(), triage, "write an article")
+	require.NoError(t, err)
+
+	// 嵌套运行不会新建 trace
+	assert.Equal(t, 1, processor.started)
+	require.Len(t, processor.traces, 1)
+	trace := processor.traces[0]
+	assert.Equal(t, "writing flow", trace.WorkflowName)
+	assert.Equal(t, "thread_1", trace.GroupID)
+	assert.Equal(t, map[string]any{"team": "docs"}, trace.Metadata)
+	assert.False(t, trace.EndedAt.IsZero())
+	for _, s := range processor.spans {
+		assert.Equal(t, trace.ID, s.TraceID)
+		assert.False(t, s.EndedAt.Before(s.StartedAt))
+	}
+
+	// 同级 span 按结束顺序排列
+	assert.Equal(t, map[string][]string{
+		"":                               {"agent:triage", "agent:writer"},
+		"agent:triage":                   {"guardrail:check_input", "generation:triage-model", "handoff:triage->writer"},
+		"agent:writer":                   {"generation:writer-model", "function:call_agent_researcher", "generation:writer-model"},
+		"function:call_agent_researcher": {"agent:researcher"},
+		"agent:researcher":               {"generation:research-model"},
+	}, processor.spanTree())
+
+	for _, s := range processor.spans {
+		switch d := s.Data.(type) {
+		case *tracing.AgentSpanData:
+			if d.Name == "triage" {
+				assert.Equal(t, []string{"writer"}, d.Handoffs)
+				assert.Equal(t, []string{"transfer_to_writer"}, d.Tools)
+			}
+		case *tracing.FunctionSpanData:
+			assert.Equal(t, `{"input":"facts"}`, d.Input)
+			assert.Equal(t, "background facts", d.Output)
+		case *tracing.GenerationSpanData:
+			assert.Equal(t, &tracing.Usage{InputTokens: 10, OutputTokens: 5}, d.Usage)
+			assert.NotEmpty(t, d.Output)
+		}
+	}
+}
+
+func TestRunner_TracingError(t *testing.T) {
+	server := newFakeLLMServer(t)
+	processor := &memoryProcessor{}
+	a := agent.New("broken").WithModel("test-model").WithClient(server.client())
+
+	r := runner.Runner{Config: runner.RunConfig{TraceProvider: tracing.NewProvider(processor)}}
+	_, err := r.Run(context.Background(), a, "hi")
+	require.Error(t, err)
+
+	require.Len(t, processor.traces, 1)
+	assert.Equal(t, runner.DefaultWorkflowName, processor.traces[0].WorkflowName)
+	require.Len(t, processor.spans, 2)
+	for _, s := range processor.spans {
+		require.NotNil(t, s.Error, spanLabel(s))
+	}
+}
+
+func TestRunner_TracingDisabled(t *testing.T) {
+	server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+	processor := &memoryProcessor{}
+	a := agent.New("quiet").WithModel("test-model").WithClient(server.client())
+
+	provider := tracing.NewProvider(processor)
+	ctx, trace := provider.StartTrace(context.Background(), "outer", tracing.TraceOptions{})
+	r := runner.Runner{Config: runner.RunConfig{TracingDisabled: true, TraceProvider: provider}}
+	_, err := r.Run(ctx, a, "hi")
+	require.NoError(t, err)
+	trace.End()
+
+	assert.Len(t, processor.traces, 1)
+	assert.Empty(t, processor.spans)
+}
+
+func TestTracing_SpanJSON(t *testing.T) {
+	provider := tracing.NewProvider()
+	ctx, trace := provider.StartTrace(context.Background(), "flow", tracing.TraceOptions{GroupID: "g"})
+	ctx, parent := tracing.StartSpan(ctx, &tracing.AgentSpanData{Name: "a"})
+	_, child := tracing.StartSpan(ctx, &tracing.HandoffSpanData{FromAgent: "a", ToAgent: "b"})
+	child.SetError(errors.New("boom"))
+	child.End()
+	parent.End()
+	trace.End()
+
+	var got map[string]any
+	data, err := json.Marshal(child)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "trace.span", got["object"])
+	assert.Equal(t, trace.ID, got["trace_id"])
+	assert.Equal(t, parent.ID, got["parent_id"])
+	assert.Equal(t, map[string]any{"type": "handoff", "from_agent": "a", "to_agent": "b"}, got["span_data"])
+	assert.Equal(t, map[string]any{"message": "boom"}, got["error"])
+
+	data, err = json.Marshal(parent)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Nil(t, got["parent_id"])
+
+	data, err = json.Marshal(trace)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "trace", got["object"])
+	assert.Equal(t, "flow", got["workflow_name"])
+	assert.Equal(t, "g", got["group_id"])
+
+	// 没有 trace 时 span 为 nil，调用其方法是安全的
+	_, noop := tracing.StartSpan(context.Background(), &tracing.AgentSpanData{Name: "a"})
+	assert.Nil(t, noop)
+	noop.SetError(errors.New("ignored"))
+	noop.End()
+}
+
+// countingExporter 记录每批导出的数量
+type countingExporter struct {
+	mu      sync.Mutex
+	batches []int
+	closed  bool
+}
+
+func (e *countingExporter) Export(_ context.Context, records []tracing.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.batches = append(e.batches, len(records))
+	return nil
+}
+
+func (e *countingExporter) Close() error {
+	e.closed = true
+	return nil
+}
+
+func TestBatchProcessor(t *testing.T) {
+	exporter := &countingExporter{}
+	processor := tracing.NewBatchProcessor(exporter, tracing.BatchOptions{
+		MaxQueueSize:  5,
+		MaxBatchSize:  2,
+		ScheduleDelay: time.Hour,
+	})
+	provider := tracing.NewProvider(processor)
+
+	ctx, trace := provider.StartTrace(context.Background(), "flow", tracing.TraceOptions{})
+	for range 6 {
+		_, span := tracing.StartSpan(ctx, &tracing.CustomSpanData{Name: "step"})
+		span.End()
+	}
+	trace.End()
+	require.NoError(t, provider.ForceFlush(context.Background()))
+
+	// 队列上限为 5，多余的两条被丢弃；每批最多 2 条
+	assert.Equal(t, 2, processor.Dropped())
+	exporter.mu.Lock()
+	total := 0
+	for _, n := range exporter.batches {
+		assert.LessOrEqual(t, n, 2)
+		total += n
+	}
+	exporter.mu.Unlock()
+	assert.Equal(t, 5, total)
+
+	require.NoError(t, provider.Shutdown(context.Background()))
+	assert.True(t, exporter.closed)
+}
+
+func TestFileProcessor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	processor, err := tracing.NewFileProcessor(path, tracing.BatchOptions{})
+	require.NoError(t, err)
+
+	server := newFakeLLMServer(t, scriptedReply{text: "ok"})
+	a := agent.New("assistant").WithModel("test-model").WithClient(server.client())
+	r := runner.Runner{Config: runner.RunConfig{TraceProvider: tracing.NewProvider(processor)}}
+	_, err = r.Run(context.Background(), a, "hi")
+	require.NoError(t, err)
+	require.NoError(t, processor.Shutdown(context.Background()))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var objects []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line struct {
+			Object   string `json:"object"`
+			SpanData struct {
+				Type string `json:"type"`
+			} `json:"span_data"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		objects = append(objects, line.Object+":"+line.SpanData.Type)
+	}
+	assert.Equal(t, []string{"trace.span:generation", "trace.span:agent", "trace:"}, objects)
+}
+
+func TestOTelProcessor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	provider := tracing.NewProvider(tracing.NewOTelProcessor(tp.Tracer("agent_go")))
+
+	server := newFakeLLMServer(t,
+		scriptedReply{toolCalls: []scriptedToolCall{{id: "call_1", name: "calculator", arguments: `{"operation":"add","a":1,"b":1}`}}},
+		scriptedReply{text: "2"},
+	)
+	a := agent.New("math").
+		WithModel("test-model").
+		WithClient(server.client()).
+		WithTools([]tool.FunctionTool{tool.NewCalculatorTool()})
+	r := runner.Runner{Config: runner.RunConfig{WorkflowName: "math flow", TraceProvider: provider}}
+	_, err := r.Run(context.Background(), a, "1+1")
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	byName := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byName[s.Name()] = s
+	}
+	require.Contains(t, byName, "math flow")
+	require.Contains(t, byName, "agent math")
+	require.Contains(t, byName, "function calculator")
+	root := byName["math flow"]
+	assert.False(t, root.Parent().IsValid())
+	assert.Equal(t, root.SpanContext().SpanID(), byName["agent math"].Parent().SpanID())
+	assert.Equal(t, byName["agent math"].SpanContext().SpanID(), byName["function calculator"].Parent().SpanID())
+	for _, s := range spans {
+		assert.Equal(t, root.SpanContext().TraceID(), s.SpanContext().TraceID())
+	}
+
+	attrs := map[string]string{}
+	for _, kv := range byName["function calculator"].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	assert.Equal(t, "function", attrs["agent_go.span_type"])
+	assert.Equal(t, "2", attrs["agent_go.function.output"])
+}